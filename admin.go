@@ -0,0 +1,192 @@
+// Admin contains the !admin command, which lets FlaminGo's owners operate the bot from Discord
+
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// startTime is when FlaminGo started, for the uptime in !admin status.
+var startTime = time.Now()
+
+// adminUsage explains the !admin command.
+const adminUsage = "use `!admin reload`, `!admin status`, `!admin guilds`, `!admin broadcast <message>` or `!admin setstatus <text or reset>`"
+
+// runAdmin handles !admin reload, status, guilds, broadcast <message> and setstatus <text|reset>.
+// Only the users listed in Conf.Owners may use it.
+func runAdmin(s Sender, inv *Invocation) error {
+	if !isOwner(inv.UserID) {
+		return usageErrorf("only FlaminGo's owners can use !admin")
+	}
+	if len(inv.Args) == 0 {
+		return usageErrorf("%s", adminUsage)
+	}
+
+	switch inv.Args[0] {
+	case "reload":
+		err := reloadConfig()
+		// Error handling
+		if err != nil {
+			return usageErrorf("reloading failed, nothing was changed: %s", err)
+		}
+		err = loadGenerator(Conf().GeneratorFile)
+		// Error handling
+		if err != nil {
+			return usageErrorf("reloaded the configuration and locations, but not the bird generator: %s", err)
+		}
+		inv.Log.Info("reloaded configuration")
+		words := defaultWords()
+		inv.send(s, fmt.Sprintf("Reloaded the configuration, %s and the bird generator (%s, %s).",
+			pluralize(len(Locations()), "location"), pluralize(len(words.Adjectives), "adjective"), pluralize(len(words.Nouns), "noun")))
+	case "status":
+		guilds, err := allGuilds(s)
+		// Error handling
+		if err != nil {
+			return err
+		}
+		inv.send(s, formatStatus(len(guilds)))
+	case "guilds":
+		guilds, err := allGuilds(s)
+		// Error handling
+		if err != nil {
+			return err
+		}
+		inv.send(s, formatGuilds(guilds))
+	case "broadcast":
+		msg := inv.textAfter("broadcast")
+		if msg == "" {
+			return usageErrorf("%s", adminUsage)
+		}
+		sent, failed, err := broadcast(s, inv, msg)
+		// Error handling
+		if err != nil {
+			return err
+		}
+		inv.send(s, fmt.Sprintf("Sent to %s, %d failed.", pluralize(sent, "announcement channel"), failed))
+	case "setstatus":
+		status := inv.textAfter("setstatus")
+		if status == "" {
+			return usageErrorf("%s", adminUsage)
+		}
+		if strings.EqualFold(status, "reset") {
+			status = defaultStatus
+		}
+		err := s.UpdateGameStatus(0, status)
+		// Error handling
+		if err != nil {
+			return fmt.Errorf("updating game status: %w", err)
+		}
+		inv.Log.Info("changed game status", slog.String("status", status))
+		inv.send(s, fmt.Sprintf("Status is now \"%s\".", status))
+	default:
+		return usageErrorf("%s", adminUsage)
+	}
+	return nil
+}
+
+// isOwner reports whether userID is one of FlaminGo's owners.
+func isOwner(userID string) bool {
+	for _, id := range Conf().Owners {
+		if id == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// allGuilds returns every guild FlaminGo is in, paging through Discord's list 100 at a time.
+func allGuilds(s Sender) ([]*discordgo.UserGuild, error) {
+	var guilds []*discordgo.UserGuild
+	after := ""
+	for {
+		page, err := s.UserGuilds(100, "", after)
+		// Error handling
+		if err != nil {
+			return nil, fmt.Errorf("listing guilds: %w", err)
+		}
+		guilds = append(guilds, page...)
+		if len(page) < 100 {
+			return guilds, nil
+		}
+		after = page[len(page)-1].ID
+	}
+}
+
+// broadcast sends msg to the announcement channel of every guild FlaminGo is in that set one.
+// It returns how many were sent and how many Discord refused.
+func broadcast(s Sender, inv *Invocation, msg string) (sent, failed int, err error) {
+	guilds, err := allGuilds(s)
+	// Error handling
+	if err != nil {
+		return 0, 0, err
+	}
+
+	for _, g := range guilds {
+		channel := store.Guild(g.ID).AnnouncementChannel
+		if channel == "" {
+			continue
+		}
+		_, err := s.ChannelMessageSend(channel, msg)
+		// Error handling
+		if err != nil {
+			inv.Log.Warn("sending announcement failed", slog.String("to_guild", g.ID), slog.Any("err", err))
+			failed++
+			continue
+		}
+		sent++
+	}
+	return sent, failed, nil
+}
+
+// formatStatus returns FlaminGo's uptime, guild count, cache sizes and upstream health for !admin status.
+func formatStatus(guilds int) string {
+	taxaMu.Lock()
+	species, locales := 0, 0
+	for _, t := range taxa {
+		species += len(t)
+		locales++
+	}
+	taxaMu.Unlock()
+	allTaxaMu.Lock()
+	fullTaxonomies := len(allTaxa)
+	allTaxaMu.Unlock()
+	regionNamesMu.Lock()
+	regions := len(regionNames)
+	regionNamesMu.Unlock()
+
+	rString := "**FlaminGo status:**\n"
+	rString += fmt.Sprintf("Uptime: %s\n", now().Sub(startTime).Round(time.Second))
+	rString += fmt.Sprintf("Guilds: %d, %d with saved settings\n", guilds, len(store.GuildIDs()))
+	rString += fmt.Sprintf("Commands waiting: %d of %d, with %s\n", commandPool.queued(), commandQueueSize, pluralize(commandPool.workers(), "worker"))
+	rString += fmt.Sprintf("Caches: %s in %s, the full taxonomy in %s, %s\n", pluralize(species, "species code"),
+		pluralize(locales, "locale"), pluralize(fullTaxonomies, "locale"), pluralize(regions, "region name"))
+	words := defaultWords()
+	rString += fmt.Sprintf("Bird generator: %s, %s\n", pluralize(len(words.Adjectives), "adjective"), pluralize(len(words.Nouns), "noun"))
+	rString += fmt.Sprintf("eBird: %s\n", ebirdUpstream.health())
+	rString += fmt.Sprintf("AllAboutBirds: %s\n", allAboutBirdsUpstream.health())
+	return rString
+}
+
+// formatGuilds lists guilds by name for !admin guilds.
+func formatGuilds(guilds []*discordgo.UserGuild) string {
+	sort.Slice(guilds, func(i, j int) bool {
+		return strings.ToLower(guilds[i].Name) < strings.ToLower(guilds[j].Name)
+	})
+
+	rString := fmt.Sprintf("**FlaminGo is in %s:**\n", pluralize(len(guilds), "guild"))
+	for _, g := range guilds {
+		rString += fmt.Sprintf("%s (%s)", g.Name, g.ID)
+		if channel := store.Guild(g.ID).AnnouncementChannel; channel != "" {
+			rString += fmt.Sprintf(", announcements in <#%s>", channel)
+		}
+		rString += "\n"
+	}
+	//Trimming return string to Discord max message length, minus 5 characters to show max length has been reached
+	return truncateText(rString, 1995)
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// sendAsOwner runs content through handleMessage from one of FlaminGo's owners, in a session with the given guilds.
+func sendAsOwner(t *testing.T, s *fakeSession, content string) []sentMessage {
+	t.Helper()
+	changeConf(t, func(c *Config) { c.Owners = []string{"u1"} })
+	handleMessage(s, newMessage(content))
+	commandPool.wait()
+	return s.messages()
+}
+
+func TestAdminOwnersOnly(t *testing.T) {
+	newFakeEBird(t)
+	useTempStore(t)
+	changeConf(t, func(c *Config) { c.Owners = []string{"someone-else"} })
+
+	sent := send(t, "!admin status")
+	if len(sent) != 1 || sent[0].Content != "Error: only FlaminGo's owners can use !admin" {
+		t.Errorf("unexpected reply %+v", sent)
+	}
+}
+
+func TestAdminStatusAndGuilds(t *testing.T) {
+	newFakeEBird(t)
+	useTempStore(t)
+	s := &fakeSession{}
+	for i := 0; i < 150; i++ {
+		s.guilds = append(s.guilds, &discordgo.UserGuild{ID: fmt.Sprintf("%03d", i), Name: fmt.Sprintf("Club %03d", i)})
+	}
+
+	// The status shows the workers the pool has, whatever the configuration says since
+	changeConf(t, func(c *Config) { c.Workers = 16 })
+	sent := sendAsOwner(t, s, "!admin status")
+	if len(sent) != 1 || !strings.Contains(sent[0].Content, "Guilds: 150, 0 with saved settings\n") || !strings.Contains(sent[0].Content, "eBird: no requests yet\n") ||
+		!strings.Contains(sent[0].Content, "with 4 workers\n") {
+		t.Errorf("unexpected status %+v", sent)
+	}
+
+	// A long guild list is cut to fit in a message
+	sent = sendAsOwner(t, &fakeSession{guilds: s.guilds}, "!admin guilds")
+	if len(sent) != 1 || len(sent[0].Content) > 2000 || !strings.HasSuffix(sent[0].Content, "...") {
+		t.Errorf("unexpected guild list %+v", sent)
+	}
+
+	s = &fakeSession{guilds: []*discordgo.UserGuild{{ID: "2", Name: "Zebra Finches"}, {ID: "1", Name: "audubon"}}}
+	sent = sendAsOwner(t, s, "!admin guilds")
+	if len(sent) != 1 || sent[0].Content != "**FlaminGo is in 2 guilds:**\naudubon (1)\nZebra Finches (2)\n" {
+		t.Errorf("unexpected guild list %+v", sent)
+	}
+}
+
+func TestAdminBroadcast(t *testing.T) {
+	newFakeEBird(t)
+	useTempStore(t)
+	if sent := sendAsAdmin(t, "!config set announcements <#555>"); len(sent) != 1 || sent[0].Content != "Updated: `announcements`: <#555>" {
+		t.Fatalf("unexpected reply %+v", sent)
+	}
+
+	s := &fakeSession{guilds: []*discordgo.UserGuild{{ID: "g1", Name: "RIT Birding"}, {ID: "g2", Name: "Quiet Club"}}}
+	sent := sendAsOwner(t, s, "!admin broadcast  Big Day is on MAY 10!")
+	if len(sent) != 2 || sent[0].ChannelID != "555" || sent[0].Content != "Big Day is on MAY 10!" {
+		t.Fatalf("unexpected announcement %+v", sent)
+	}
+	if sent[1].Content != "Sent to 1 announcement channel, 0 failed." {
+		t.Errorf("unexpected reply %+v", sent[1])
+	}
+}
+
+func TestTextAfter(t *testing.T) {
+	for content, want := range map[string]string{
+		"!admin BROADCAST Meet at 8!": "Meet at 8!",
+		// Ⱥ is longer once lowercased, which must not shift the text
+		"ȺȺ admin broadcast Meet at 8!": "Meet at 8!",
+		"!admin status":                 "",
+	} {
+		inv := &Invocation{Content: content}
+		if got := inv.textAfter("broadcast"); got != want {
+			t.Errorf("%q: got %q, want %q", content, got, want)
+		}
+	}
+}
+
+func TestAdminSetStatus(t *testing.T) {
+	newFakeEBird(t)
+	useTempStore(t)
+
+	s := &fakeSession{}
+	sent := sendAsOwner(t, s, "!admin setstatus Counting Owls")
+	if s.status != "Counting Owls" || len(sent) != 1 {
+		t.Errorf("status %q, replies %+v", s.status, sent)
+	}
+	sendAsOwner(t, s, "!admin setstatus reset")
+	if s.status != defaultStatus {
+		t.Errorf("status %q after reset", s.status)
+	}
+}
+
+func TestAdminReload(t *testing.T) {
+	f := newFakeEBird(t)
+	useTempStore(t)
+	oldLocations, oldArgs := Locations(), configArgs
+	t.Cleanup(func() {
+		confMu.Lock()
+		locations = oldLocations
+		confMu.Unlock()
+		configArgs = oldArgs
+	})
+
+	file := filepath.Join(t.TempDir(), "locations.json")
+	err := os.WriteFile(file, []byte(`[{"key": "Durand", "code": "L1", "lat": 43.2, "long": -77.5, "name": "Durand Eastman Park"}]`), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	configArgs = []string{"-token", "tok", "-key", testKey, "-ebird-url", f.URL, "-locations", file, "-radius", "8", "-env-file", ""}
+
+	sent := sendAsOwner(t, &fakeSession{}, "!admin reload")
+	if len(sent) != 1 || !strings.HasPrefix(sent[0].Content, "Reloaded the configuration, 1 location and the bird generator") {
+		t.Fatalf("unexpected reply %+v", sent)
+	}
+	if _, ok := Locations()["durand"]; !ok || Conf().Radius != 8 {
+		t.Errorf("not reloaded: %v, radius %d", Locations(), Conf().Radius)
+	}
+
+	// A broken file changes nothing
+	err = os.WriteFile(file, []byte(`[{"key": "broken"`), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sent = sendAsOwner(t, &fakeSession{}, "!admin reload")
+	if len(sent) != 1 || !strings.HasPrefix(sent[0].Content, "Error: reloading failed, nothing was changed") {
+		t.Errorf("unexpected reply %+v", sent)
+	}
+	if _, ok := Locations()["durand"]; !ok {
+		t.Error("locations changed by a failed reload")
+	}
+}
+
+func TestAdminReloadDuringCommands(t *testing.T) {
+	f := newFakeEBird(t)
+	useTempStore(t)
+	f.route("/data/obs/geo/recent", "recent.json")
+	oldArgs := configArgs
+	t.Cleanup(func() {
+		configArgs = oldArgs
+		logLevel.Set(slog.LevelInfo)
+	})
+	configArgs = []string{"-token", "tok", "-key", testKey, "-ebird-url", f.URL, "-radius", "8", "-log-level", "debug",
+		"-user-rate", "0", "-channel-rate", "0", "-ebird-rate", "0", "-env-file", ""}
+
+	// Run with -race, commands reading the configuration and locations while it is reloaded show any data race
+	stop, done := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			if err := reloadConfig(); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}()
+	for i := 0; i < 20; i++ {
+		if sent := send(t, "!get rit"); len(sent) != 1 || !strings.Contains(sent[0].Content, "American Crow") {
+			t.Errorf("unexpected reply %+v", sent)
+			break
+		}
+	}
+	close(stop)
+	<-done
+}
@@ -0,0 +1,356 @@
+// Args contains the argument parser shared by FlaminGo's observation commands
+
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/R1V3N/FlaminGo/export"
+)
+
+// ObsQuery holds the options for an observation command such as !get or !rare.
+type ObsQuery struct {
+	// Location is the location to search around. It is unused if Region is set.
+	Location Location
+	// Region is an eBird region code such as US-NY-055 to search instead of a location.
+	Region string
+	// RegionName is the full name of Region, for use in headers.
+	RegionName string
+	// Radius is the number of kilometers to search around Location.
+	Radius int
+	// Days is the number of days back to search.
+	Days int
+	// Reversed reverses the sort order.
+	Reversed bool
+	// Sort is the sort order, one of sortOrders. Empty means by name.
+	Sort string
+	// Group is how to group the list, either groupNone or groupFamily.
+	Group string
+	// Mode is how to query the location, either modeGeo or modeHotspot.
+	Mode string
+	// Export is the file format to send the sightings in instead of a message. Empty means no export.
+	Export export.Format
+	// Locale is the language for species names and headers. Empty means English.
+	Locale string
+}
+
+// UsageError is an error caused by a user's input. Its message is safe to show to the user, and Invocation.fail
+// replies with it instead of the generic error message.
+type UsageError struct {
+	msg string
+	// format and args are kept so the message can be translated for the user.
+	format string
+	args   []interface{}
+}
+
+func (e *UsageError) Error() string {
+	return e.msg
+}
+
+// translate returns the message in a locale. Usage strings passed as arguments, which quote commands in backticks,
+// are translated too. Other arguments are the user's own words and are left alone.
+func (e *UsageError) translate(locale string) string {
+	args := make([]interface{}, len(e.args))
+	for i, a := range e.args {
+		if str, ok := a.(string); ok && strings.Contains(str, "`") {
+			a = tr(locale, str)
+		}
+		args[i] = a
+	}
+	return tr(locale, e.format, args...)
+}
+
+// usageErrorf formats a UsageError.
+func usageErrorf(format string, a ...interface{}) error {
+	return &UsageError{msg: fmt.Sprintf(format, a...), format: format, args: a}
+}
+
+// parseCommand recognizes a message sent to FlaminGo, either starting with the guild's prefix ("!get rit") or with
+// a mention of the bot ("@FlaminGo get rit"). It returns the lowercased command name without its prefix and its
+// arguments, or false if the message is not a command.
+func parseCommand(guildID, content string) (command string, args []string, ok bool) {
+	//Lowercasing message content to standardize commands
+	content = strings.ToLower(strings.TrimSpace(content))
+	prefix := guildPrefix(guildID)
+
+	// Mentions look like <@id>, or <@!id> when the bot has a nickname, and may be followed by the prefix too
+	mentioned := false
+	if BotID != "" {
+		for _, mention := range []string{"<@" + BotID + ">", "<@!" + BotID + ">"} {
+			if strings.HasPrefix(content, mention) {
+				content = strings.TrimPrefix(strings.TrimSpace(strings.TrimPrefix(content, mention)), prefix)
+				mentioned = true
+				break
+			}
+		}
+	}
+	if !mentioned {
+		// The command must follow the prefix directly, so "! hello" is not a command
+		if !strings.HasPrefix(content, prefix) || strings.TrimSpace(content[len(prefix):]) != content[len(prefix):] {
+			return "", nil, false
+		}
+		content = content[len(prefix):]
+	}
+
+	tokens := tokenize(content)
+	if len(tokens) == 0 {
+		return "", nil, false
+	}
+	return tokens[0], tokens[1:], true
+}
+
+// tokenize splits a command into words separated by any run of whitespace. Double quotes, straight or curly, group
+// words into a single argument, e.g. `!nearest "red-tailed hawk" from rit` has three arguments. Quotes can also
+// appear inside a word, as in `region:"us-ny"`. An unclosed quote runs to the end of the message.
+func tokenize(s string) []string {
+	var tokens []string
+	var current strings.Builder
+	inToken, quoted := false, false
+	for _, r := range s {
+		switch {
+		case r == '"' || r == '“' || r == '”':
+			quoted = !quoted
+			inToken = true
+		case unicode.IsSpace(r) && !quoted:
+			if inToken {
+				tokens = append(tokens, current.String())
+				current.Reset()
+				inToken = false
+			}
+		default:
+			current.WriteRune(r)
+			inToken = true
+		}
+	}
+	if inToken {
+		tokens = append(tokens, current.String())
+	}
+	return tokens
+}
+
+// splitOptions splits command arguments into positional arguments and key:value options.
+// Option keys are lowercased, and an option given twice keeps its last value.
+func splitOptions(args []string) (positional []string, options map[string]string) {
+	options = make(map[string]string)
+	for _, a := range args {
+		if a == "" {
+			continue
+		}
+		if k, v, ok := strings.Cut(a, ":"); ok && k != "" {
+			options[strings.ToLower(k)] = v
+			continue
+		}
+		positional = append(positional, a)
+	}
+	return positional, options
+}
+
+// intOption parses the named option as a whole number between min and max, returning def if it was not given.
+func intOption(options map[string]string, name string, min, max, def int) (int, error) {
+	v, ok := options[name]
+	if !ok {
+		return def, nil
+	}
+	i, err := strconv.Atoi(v)
+	// Error handling
+	if err != nil || i < min || i > max {
+		return 0, usageErrorf("'%s' must be a whole number from %d to %d, got '%s'", name, min, max, v)
+	}
+	return i, nil
+}
+
+// checkOptions returns a UsageError naming the first option, in alphabetical order, that is not one of allowed.
+func checkOptions(options map[string]string, command string, allowed ...string) error {
+	known := make(map[string]bool)
+	for _, k := range allowed {
+		known[k] = true
+	}
+	var unknown []string
+	for k := range options {
+		if !known[k] {
+			unknown = append(unknown, k)
+		}
+	}
+	if len(unknown) == 0 {
+		return nil
+	}
+	sort.Strings(unknown)
+	return usageErrorf("'%s' is not a valid option for !%s", unknown[0], command)
+}
+
+// exportOption parses the export: option, returning an empty format if it was not given.
+func exportOption(options map[string]string) (export.Format, error) {
+	v, ok := options["export"]
+	if !ok {
+		return "", nil
+	}
+	f, err := export.ParseFormat(v)
+	// Error handling
+	if err != nil {
+		return "", usageErrorf("'%s' is not an export format, use %s", v, exportFormatNames())
+	}
+	return f, nil
+}
+
+// exportFormatNames returns the export formats separated by slashes, for use in help text.
+func exportFormatNames() string {
+	names := make([]string, len(export.Formats))
+	for i, f := range export.Formats {
+		names[i] = string(f)
+	}
+	return strings.Join(names, "/")
+}
+
+// parseObsQuery parses the arguments of an observation command, e.g. "braddock radius:20 days:3 reversed".
+// guildID is used to find the guild's saved locations, command is the command name used in error messages,
+// and defaultRadius is used when no radius is given.
+func parseObsQuery(guildID, command string, args []string, defaultRadius int) (ObsQuery, error) {
+	q := ObsQuery{}
+	positional, options := splitOptions(args)
+
+	// The location is the first positional argument, unless a region is given instead
+	var loc Location
+	if v, ok := options["region"]; ok {
+		var err error
+		q.Region, err = parseRegionCode(v)
+		// Error handling
+		if err != nil {
+			return q, err
+		}
+		for _, k := range []string{"radius", "mode"} {
+			if _, ok := options[k]; ok {
+				return q, usageErrorf("'%s' cannot be used with 'region'", k)
+			}
+		}
+	} else {
+		// The guild's default location stands in when none is given
+		if len(positional) == 0 {
+			if name := store.Guild(guildID).DefaultLocation; name != "" {
+				positional = []string{name}
+			}
+		}
+		if len(positional) == 0 {
+			return q, usageErrorf("!%s needs a location or a region, e.g. `!%s %s` or `!%s region:US-NY-055`", command, command, firstLocationName(), command)
+		}
+		var ok bool
+		loc, ok = namedLocation(guildID, strings.ToLower(positional[0]))
+		if !ok {
+			return q, usageErrorf("'%s' is not a valid option for !%s", positional[0], command)
+		}
+		q.Location = loc
+		positional = positional[1:]
+	}
+
+	// Remaining positional arguments are flags
+	for _, p := range positional {
+		switch strings.ToLower(p) {
+		case "reversed":
+			q.Reversed = true
+		default:
+			return q, usageErrorf("'%s' is not a valid option for !%s", p, command)
+		}
+	}
+
+	// eBird accepts a dist of at most 50km and a back of 1 to 30 days
+	var err error
+	q.Radius, err = intOption(options, "radius", 1, 50, defaultRadius)
+	// Error handling
+	if err != nil {
+		return q, err
+	}
+	q.Days, err = intOption(options, "days", 1, 30, Conf().BackDays)
+	// Error handling
+	if err != nil {
+		return q, err
+	}
+
+	err = parseSortOptions(&q, options)
+	// Error handling
+	if err != nil {
+		return q, err
+	}
+
+	// The location's own query mode applies unless mode: is given
+	if q.Region == "" {
+		q.Mode = loc.mode
+		if v, ok := options["mode"]; ok {
+			q.Mode = strings.ToLower(v)
+		}
+		if q.Mode == "" {
+			q.Mode = modeGeo
+		}
+		err = validateMode(q.Mode, loc.code)
+		// Error handling
+		if err != nil {
+			return q, err
+		}
+	}
+
+	q.Export, err = exportOption(options)
+	// Error handling
+	if err != nil {
+		return q, err
+	}
+
+	// Any option left over is unknown
+	for k := range options {
+		switch k {
+		case "radius", "days", "sort", "group", "mode", "region", "export":
+		default:
+			return q, usageErrorf("'%s' is not a valid option for !%s", k, command)
+		}
+	}
+
+	return q, nil
+}
+
+// where describes the area the query searches, for use in headers, e.g. "within 5 km of Mendon Ponds Park".
+// It also serves as a short description in error messages.
+func (q ObsQuery) where() string {
+	if q.Region != "" {
+		if q.RegionName != "" {
+			return tr(q.Locale, "in %s", q.RegionName)
+		}
+		return tr(q.Locale, "in %s", q.Region)
+	}
+	if q.Mode == modeHotspot {
+		return tr(q.Locale, "at the %s hotspot", q.Location.name)
+	}
+	return tr(q.Locale, "within %d km of %s", q.Radius, q.Location.name)
+}
+
+// firstLocationName returns the alphabetically first configured location name, for use in usage examples.
+func firstLocationName() string {
+	names := sortedLocationNames()
+	if len(names) == 0 {
+		return "rit"
+	}
+	return names[0]
+}
+
+// locationNames returns the configured location names separated by slashes, for use in help text.
+func locationNames() string {
+	return strings.Join(sortedLocationNames(), "/")
+}
+
+// sortedLocationNames returns the configured location names in alphabetical order.
+func sortedLocationNames() []string {
+	var names []string
+	for name := range Locations() {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// pluralize returns "1 day", "2 days" and so on.
+func pluralize(n int, noun string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, noun)
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
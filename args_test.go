@@ -0,0 +1,119 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParseObsQuery(t *testing.T) {
+	changeConf(t, func(c *Config) { *c = defaultConfig() })
+
+	q, err := parseObsQuery("g1", "get", []string{"braddock", "radius:20", "days:3", "reversed"}, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q.Location != Braddock || q.Radius != 20 || q.Days != 3 || !q.Reversed {
+		t.Errorf("unexpected query %+v", q)
+	}
+
+	q, err = parseObsQuery("g1", "rare", []string{"rit"}, 15)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q.Radius != 15 || q.Days != 14 || q.Reversed {
+		t.Errorf("defaults not applied: %+v", q)
+	}
+}
+
+func TestParseObsQueryErrors(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{nil, "needs a location"},
+		{[]string{"nowhere"}, "'nowhere' is not a valid option"},
+		{[]string{"rit", "radius:51"}, "'radius' must be a whole number from 1 to 50"},
+		{[]string{"rit", "days:0"}, "'days' must be a whole number from 1 to 30"},
+		{[]string{"rit", "days:many"}, "'days' must be"},
+		{[]string{"rit", "color:red"}, "'color' is not a valid option"},
+		{[]string{"rit", "sideways"}, "'sideways' is not a valid option"},
+	}
+	for _, tt := range tests {
+		_, err := parseObsQuery("g1", "get", tt.args, 5)
+		var usageErr *UsageError
+		if !errors.As(err, &usageErr) {
+			t.Errorf("%v: got %v, want a UsageError", tt.args, err)
+			continue
+		}
+		if !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%v: error %q does not contain %q", tt.args, err, tt.want)
+		}
+	}
+}
+
+func TestTokenize(t *testing.T) {
+	tests := map[string][]string{
+		"get  rit\tdays:3":                   {"get", "rit", "days:3"},
+		`nearest "red-tailed hawk" from rit`: {"nearest", "red-tailed hawk", "from", "rit"},
+		"nearest “snowy   owl” 7":            {"nearest", "snowy   owl", "7"},
+		`get region:"us-ny" days:2`:          {"get", "region:us-ny", "days:2"},
+		`bird "unclosed quote`:               {"bird", "unclosed quote"},
+		`config set prefix ""`:               {"config", "set", "prefix", ""},
+		"  \n ":                              nil,
+	}
+	for in, want := range tests {
+		got := tokenize(in)
+		if strings.Join(got, "|") != strings.Join(want, "|") || len(got) != len(want) {
+			t.Errorf("tokenize(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestParseCommand(t *testing.T) {
+	useTempStore(t)
+	old := BotID
+	BotID = "bot1"
+	defer func() { BotID = old }()
+
+	tests := []struct {
+		content string
+		command string
+		args    []string
+	}{
+		{"!GET Rit  days:3", "get", []string{"rit", "days:3"}},
+		{"<@bot1> get rit", "get", []string{"rit"}},
+		{"<@!bot1>   !rare braddock", "rare", []string{"braddock"}},
+		{"<@bot1>", "", nil},
+		{"! get rit", "", nil},
+		{"<@someone> get rit", "", nil},
+		{"get rit", "", nil},
+	}
+	for _, tt := range tests {
+		command, args, ok := parseCommand("g1", tt.content)
+		if ok != (tt.command != "") || command != tt.command || strings.Join(args, "|") != strings.Join(tt.args, "|") {
+			t.Errorf("parseCommand(%q) = %q, %q, %v", tt.content, command, args, ok)
+		}
+	}
+
+	// A guild's own prefix replaces "!", but mentions still work
+	err := store.Update("g1", func(g *GuildSettings) error {
+		g.Prefix = "fg!"
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, ok := parseCommand("g1", "!get rit"); ok {
+		t.Error("'!' still works after changing the prefix")
+	}
+	if command, _, ok := parseCommand("g1", "FG!get rit"); !ok || command != "get" {
+		t.Errorf("prefix not recognized: %q, %v", command, ok)
+	}
+	if command, _, ok := parseCommand("g1", "<@bot1> get rit"); !ok || command != "get" {
+		t.Errorf("mention not recognized: %q, %v", command, ok)
+	}
+	if _, _, ok := parseCommand("g2", "!get rit"); !ok {
+		t.Error("other guilds should keep '!'")
+	}
+}
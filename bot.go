@@ -3,11 +3,10 @@
 package main
 
 import (
-	"encoding/csv"
-	"fmt"
-	"os"
-	"strconv"
+	"context"
+	"log/slog"
 	"strings"
+	"time"
 
 	"github.com/bwmarrin/discordgo"
 	"golang.org/x/text/cases"
@@ -17,17 +16,22 @@ import (
 var (
 	// BotID keeps track of the bot's user ID to make sure it doesn't respond to its own messages.
 	BotID string
-	// adjectives and nouns are slices used when loading and using the bird generator
-	Adjectives []string
-	Nouns      []string
+	// commandPool runs commands, with at most Conf.Workers at once.
+	commandPool *workerPool
 )
 
+// defaultStatus is the game status FlaminGo shows, pointing to the help command, plus a cute little flamingo.
+const defaultStatus = "!flamingo 🦩"
+
+// commandQueueSize is how many commands wait for a free worker before FlaminGo turns new ones away.
+const commandQueueSize = 100
+
 func Start() {
 	// Creating new bot session
-	goBot, err := discordgo.New("Bot " + Token)
+	goBot, err := discordgo.New("Bot " + Conf().Token)
 	// Error handling
 	if err != nil {
-		fmt.Println(err.Error())
+		logger.Error("creating Discord session", slog.Any("err", err))
 		return
 	}
 
@@ -35,129 +39,394 @@ func Start() {
 	u, err := goBot.User("@me")
 	// Error handling
 	if err != nil {
-		fmt.Println(err.Error())
+		logger.Error("looking up bot user", slog.Any("err", err))
 		return
 	}
 
 	// Storing our ID from u to BotID.
 	BotID = u.ID
 
+	// Starting the workers before any command can arrive
+	commandPool = newWorkerPool(Conf().Workers, commandQueueSize)
+
 	// Adding messageHandler function to handle our messages using AddHandler from discordgo package.
 	goBot.AddHandler(messageHandler)
+	// Adding interactionHandler for the buttons on FlaminGo's messages
+	goBot.AddHandler(interactionHandler)
 	err = goBot.Open()
 	// Error handling
 	if err != nil {
-		fmt.Println(err.Error())
+		logger.Error("opening Discord connection", slog.Any("err", err))
 		return
 	}
 
 	// Updates FlaminGo's Discord status to display the help command, plus a cute little flamingo.
-	goBot.UpdateGameStatus(0, "!flamingo 🦩")
+	err = goBot.UpdateGameStatus(0, defaultStatus)
+	// Error handling
+	if err != nil {
+		logger.Warn("updating game status", slog.Any("err", err))
+	}
 
-	// Prints a string to confirm that the bot has successfully started.
-	fmt.Println("Bot is running!")
+	// Logs a line to confirm that the bot has successfully started.
+	logger.Info("Bot is running!", slog.String("bot_id", BotID))
 
 	//Loading bird generator arrays
-	loadGenerator("./birdgen.csv")
+	err = loadGenerator(Conf().GeneratorFile)
+	// Error handling
+	if err != nil {
+		logger.Error("loading bird generator, !generate is turned off", slog.Any("err", err))
+	}
+	// Reloading the lists whenever the generator file is edited
+	go watchGenerator()
+	// Posting trip reminders and recaps as they come due
+	go watchTrips(goBot)
 }
 
-// messageHandler is called whenever a Discord message is created, and will identify if the message is a FlaminGo command.
-// If the message is for FlaminGo, it will call the corresponding command function.
+// Sender is the part of a discordgo.Session that FlaminGo's commands use to reply.
+// It lets tests swap the Discord session for a fake one that records what was sent.
+type Sender interface {
+	ChannelMessageSend(channelID string, content string) (*discordgo.Message, error)
+	ChannelMessageSendEmbed(channelID string, embed *discordgo.MessageEmbed) (*discordgo.Message, error)
+	ChannelMessageSendEmbeds(channelID string, embeds []*discordgo.MessageEmbed) (*discordgo.Message, error)
+	ChannelMessageSendComplex(channelID string, data *discordgo.MessageSend) (*discordgo.Message, error)
+	ChannelTyping(channelID string) error
+	UserGuilds(limit int, beforeID, afterID string) ([]*discordgo.UserGuild, error)
+	UpdateGameStatus(idle int, name string) error
+	UserChannelPermissions(userID, channelID string) (int64, error)
+	InteractionRespond(interaction *discordgo.Interaction, resp *discordgo.InteractionResponse) error
+}
+
+// messageHandler is called whenever a Discord message is created, and passes it on to handleMessage.
 // s is a discordgo.Session
 // m is a discordgo.MessageCreate
 func messageHandler(s *discordgo.Session, m *discordgo.MessageCreate) {
+	handleMessage(s, m)
+}
+
+// interactionHandler is called whenever a user clicks a button on one of FlaminGo's messages, and passes it on to
+// handleInteraction.
+func interactionHandler(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	handleInteraction(s, i)
+}
+
+// handleInteraction answers button clicks through s. Trip RSVPs are the only buttons so far.
+func handleInteraction(s Sender, i *discordgo.InteractionCreate) {
+	if i.Type != discordgo.InteractionMessageComponent {
+		return
+	}
+	if strings.HasPrefix(i.MessageComponentData().CustomID, "trip:") {
+		answerTripButton(s, i)
+	}
+}
+
+// handleMessage will identify if the message is a FlaminGo command.
+// If the message is for FlaminGo, it will queue the command on commandPool, replying through s.
+func handleMessage(s Sender, m *discordgo.MessageCreate) {
 	// Checking to see if the message author is the bot
 	if m.Author.ID == BotID {
 		return
 	}
 
-	m.Content = strings.ToLower(m.Content) //Lowercasing message content to standardize commands
+	// Only messages starting with the guild's prefix or a mention of FlaminGo are commands
+	command, args, ok := parseCommand(m.GuildID, m.Content)
+	if !ok {
+		return
+	}
+
+	// inv ties together every log line and error reply for this command
+	inv := newInvocation(m, command, args)
+	defer inv.recoverPanic(s)
+
+	// Guilds can turn commands off, e.g. when another bot answers them too
+	if !commandEnabled(inv.GuildID, inv.Command) {
+		inv.Log.Debug("command disabled in guild")
+		return
+	}
 
-	messageTokens := strings.Split(m.Content, " ")
+	// Users and channels sending too many commands are asked to slow down, once per cooldown
+	msg, ok := allowCommand(inv)
+	if !ok {
+		if msg != "" {
+			inv.send(s, msg)
+		}
+		return
+	}
+
+	// Commands run on the worker pool, so one slow upstream does not hold up every other command
+	job := func() {
+		defer inv.recoverPanic(s)
+		runCommand(s, inv)
+	}
+	if !commandPool.submit(job) {
+		inv.Log.Warn("command queue full")
+		inv.send(s, tr(inv.Locale, "FlaminGo is very busy right now. Try again in a minute."))
+	}
+}
+
+// runCommand calls the function for inv's command, giving it Conf.CommandTimeout for all of its upstream requests.
+// A typing indicator shows in the channel while it runs.
+func runCommand(s Sender, inv *Invocation) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(Conf().CommandTimeout)*time.Second)
+	defer cancel()
+	inv.ctx = ctx
+	stopTyping := inv.startTyping(s)
+	defer stopTyping()
 
 	// !flamingo Calls DisplayHelp() command
-	if messageTokens[0] == "!flamingo" {
-		_, _ = s.ChannelMessageSendEmbed(m.ChannelID, DisplayHelp())
+	if inv.Command == "flamingo" {
+		inv.Log.Info("handling command")
+		inv.sendEmbed(s, DisplayHelp(inv.GuildID, inv.Locale))
 	}
 
 	// !get Calls GetRecentObservations() command
-	// Separate options for locations relevant to the RIT Birding Club
-	if messageTokens[0] == "!get" {
-		// Adding a third message token if the user did not input an optional argument, so that the bot does not crash
-		if len(messageTokens) < 3 {
-			messageTokens = append(messageTokens, "")
-		}
-
-		switch messageTokens[1] {
-		case "rit":
-			switch messageTokens[2] {
-			case "reversed":
-				_, _ = s.ChannelMessageSend(m.ChannelID, GetRecentObservations(RIT, KM, true))
-			default:
-				_, _ = s.ChannelMessageSend(m.ChannelID, GetRecentObservations(RIT, KM, false))
-			}
-		case "braddock":
-			switch messageTokens[2] {
-			case "reversed":
-				_, _ = s.ChannelMessageSend(m.ChannelID, GetRecentObservations(Braddock, KM, true))
-			default:
-				_, _ = s.ChannelMessageSend(m.ChannelID, GetRecentObservations(Braddock, KM, false))
-			}
-		case "mendon":
-			switch messageTokens[2] {
-			case "reversed":
-				_, _ = s.ChannelMessageSend(m.ChannelID, GetRecentObservations(Mendon, KM, true))
-			default:
-				_, _ = s.ChannelMessageSend(m.ChannelID, GetRecentObservations(Mendon, KM, false))
+	// Separate options for each configured location, by default those relevant to the RIT Birding Club
+	if inv.Command == "get" {
+		inv.Log.Info("handling command")
+		q, err := parseObsQuery(inv.GuildID, "get", inv.Args, guildRadius(inv.GuildID))
+		// Error handling
+		if err != nil {
+			inv.fail(s, err)
+			return
+		}
+		q.Locale = inv.Locale
+
+		// export: sends every sighting as a file instead of the list
+		if q.Export != "" {
+			err = exportObservations(s, inv, q)
+			// Error handling
+			if err != nil {
+				inv.fail(s, err)
 			}
-		default:
-			_, _ = s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("Error: '%s' is not a valid option for !get", messageTokens[1]))
+			return
+		}
+
+		rString, err := GetRecentObservations(ctx, q)
+		// Error handling
+		if err != nil {
+			inv.fail(s, err)
+			return
 		}
+		inv.send(s, rString)
 	}
 
 	// !rare calls GetRareObservations() command
-	// Separate options for locations relevant to the RIT Birding Club
-	// KM value is tripled to grant a larger search radius, due to the low amount of rare sightings.
-	if messageTokens[0] == "!rare" {
-		// Adding a third message token if the user did not input an optional argument, so that the bot does not crash
-		if len(messageTokens) < 3 {
-			messageTokens = append(messageTokens, "")
-		}
-
-		switch messageTokens[1] {
-		case "rit":
-			switch messageTokens[2] {
-			case "reversed":
-				_, _ = s.ChannelMessageSend(m.ChannelID, GetRareObservations(RIT, KM*3, true))
-			default:
-				_, _ = s.ChannelMessageSend(m.ChannelID, GetRareObservations(RIT, KM*3, false))
-			}
-		case "braddock":
-			switch messageTokens[2] {
-			case "reversed":
-				_, _ = s.ChannelMessageSend(m.ChannelID, GetRareObservations(Braddock, KM*3, true))
-			default:
-				_, _ = s.ChannelMessageSend(m.ChannelID, GetRareObservations(Braddock, KM*3, false))
-			}
-		case "mendon":
-			switch messageTokens[2] {
-			case "reversed":
-				_, _ = s.ChannelMessageSend(m.ChannelID, GetRareObservations(Mendon, KM*3, true))
-			default:
-				_, _ = s.ChannelMessageSend(m.ChannelID, GetRareObservations(Mendon, KM*3, false))
+	// Separate options for each configured location, by default those relevant to the RIT Birding Club
+	// Conf.RareRadius is larger than Conf.Radius, due to the low amount of rare sightings.
+	if inv.Command == "rare" {
+		inv.Log.Info("handling command")
+		q, err := parseObsQuery(inv.GuildID, "rare", inv.Args, guildRareRadius(inv.GuildID))
+		// Error handling
+		if err != nil {
+			inv.fail(s, err)
+			return
+		}
+		q.Locale = inv.Locale
+
+		// export: sends every sighting as a file instead of the list
+		if q.Export != "" {
+			err = exportObservations(s, inv, q)
+			// Error handling
+			if err != nil {
+				inv.fail(s, err)
 			}
-		default:
-			_, _ = s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("Error: '%s' is not a valid option for !rare", messageTokens[1]))
+			return
+		}
+
+		rString, points, err := GetRareObservations(ctx, q)
+		// Error handling
+		if err != nil {
+			inv.fail(s, err)
+			return
+		}
+		if len(points) == 0 {
+			inv.send(s, rString)
+			return
+		}
+
+		// Attaching a map of the numbered places. The list is still useful without it.
+		png, err := RenderSightingMap(q, points)
+		if err != nil {
+			inv.Log.Warn("rendering sighting map", slog.Any("err", err))
+			inv.send(s, rString)
+			return
+		}
+		inv.sendFile(s, rString, "rare-map.png", "image/png", png)
+	}
+
+	// !region calls the region code discovery commands
+	if inv.Command == "region" {
+		inv.Log.Info("handling command")
+		err := runRegion(s, inv)
+		// Error handling
+		if err != nil {
+			inv.fail(s, err)
+			return
+		}
+	}
+
+	// !checklists calls the recent checklists feed
+	if inv.Command == "checklists" {
+		inv.Log.Info("handling command")
+		err := runChecklists(s, inv)
+		// Error handling
+		if err != nil {
+			inv.fail(s, err)
+			return
+		}
+	}
+
+	// !checklist calls the checklist detail view
+	if inv.Command == "checklist" {
+		inv.Log.Info("handling command")
+		err := runChecklist(s, inv)
+		// Error handling
+		if err != nil {
+			inv.fail(s, err)
+			return
+		}
+	}
+
+	// !top100 calls the top contributors command
+	if inv.Command == "top100" {
+		inv.Log.Info("handling command")
+		err := runTop100(s, inv)
+		// Error handling
+		if err != nil {
+			inv.fail(s, err)
+			return
+		}
+	}
+
+	// !stats calls the daily stats and recap command
+	if inv.Command == "stats" {
+		inv.Log.Info("handling command")
+		err := runStats(s, inv)
+		// Error handling
+		if err != nil {
+			inv.fail(s, err)
+			return
+		}
+	}
+
+	// !nearest calls the nearest sightings finder
+	if inv.Command == "nearest" {
+		inv.Log.Info("handling command")
+		err := runNearest(s, inv)
+		// Error handling
+		if err != nil {
+			inv.fail(s, err)
+			return
+		}
+	}
+
+	// !season calls the seasonality chart
+	if inv.Command == "season" {
+		inv.Log.Info("handling command")
+		err := runSeason(s, inv)
+		// Error handling
+		if err != nil {
+			inv.fail(s, err)
+			return
+		}
+	}
+
+	// !history calls the historic comparison for a given date
+	if inv.Command == "history" {
+		inv.Log.Info("handling command")
+		err := runHistory(s, inv)
+		// Error handling
+		if err != nil {
+			inv.fail(s, err)
+			return
+		}
+	}
+
+	// !onthisday calls the historic comparison for today
+	if inv.Command == "onthisday" {
+		inv.Log.Info("handling command")
+		err := runOnThisDay(s, inv)
+		// Error handling
+		if err != nil {
+			inv.fail(s, err)
+			return
+		}
+	}
+
+	// !hotspots calls the hotspot discovery commands
+	if inv.Command == "hotspots" {
+		inv.Log.Info("handling command")
+		err := runHotspots(s, inv)
+		// Error handling
+		if err != nil {
+			inv.fail(s, err)
+			return
+		}
+	}
+
+	// !locations calls the location listing and settings commands
+	if inv.Command == "locations" {
+		inv.Log.Info("handling command")
+		err := runLocations(s, inv)
+		// Error handling
+		if err != nil {
+			inv.fail(s, err)
+			return
+		}
+	}
+
+	// !config shows and changes the guild's settings
+	if inv.Command == "config" {
+		inv.Log.Info("handling command")
+		err := runConfig(s, inv)
+		// Error handling
+		if err != nil {
+			inv.fail(s, err)
+			return
+		}
+	}
+
+	// !locale sets the language for a user or a guild
+	if inv.Command == "locale" {
+		inv.Log.Info("handling command")
+		err := runLocale(s, inv)
+		// Error handling
+		if err != nil {
+			inv.fail(s, err)
+			return
+		}
+	}
+
+	// !admin calls the owner-only operating commands
+	if inv.Command == "admin" {
+		inv.Log.Info("handling command")
+		err := runAdmin(s, inv)
+		// Error handling
+		if err != nil {
+			inv.fail(s, err)
+			return
+		}
+	}
+
+	// !trip calls the field trip planner
+	if inv.Command == "trip" {
+		inv.Log.Info("handling command")
+		err := runTrip(s, inv)
+		// Error handling
+		if err != nil {
+			inv.fail(s, err)
+			return
 		}
 	}
 
 	// !bird calls DisplayBird command
-	if messageTokens[0] == "!bird" {
-		// Constructing formatted bird name for use in URL
+	if inv.Command == "bird" {
+		inv.Log.Info("handling command")
+		// Constructing formatted bird name for use in URL. Quoted names are split back into words.
+		words := strings.Fields(strings.Join(inv.Args, " "))
 		formattedName := ""
-		for i := 1; i < len(messageTokens); i++ {
-			formattedName += cases.Title(language.Und).String(messageTokens[i])
-			if i < (len(messageTokens) - 1) {
+		for i, w := range words {
+			formattedName += cases.Title(language.Und).String(w)
+			if i < (len(words) - 1) {
 				formattedName += "_"
 			}
 		}
@@ -166,56 +435,69 @@ func messageHandler(s *discordgo.Session, m *discordgo.MessageCreate) {
 		// the bot will return no bird found. To avoid this, we call ReplaceAll on the URL name string to remove apostrophes.
 		formattedName = strings.ReplaceAll(formattedName, "'", "")
 
-		_, _ = s.ChannelMessageSendEmbed(m.ChannelID, DisplayBird(formattedName))
+		embed, err := DisplayBird(ctx, formattedName, inv.Locale)
+		// Error handling
+		if err != nil {
+			inv.fail(s, err)
+			return
+		}
+		inv.sendEmbed(s, embed)
 	}
 
-	// !generate Calls GenerateBird() command
-	// User can specify
-	if messageTokens[0] == "!generate" {
-		// Adding a second message token if the user did not input an optional argument, so that the bot does not crash
-		if len(messageTokens) < 2 {
-			messageTokens = append(messageTokens, "-1")
-		}
-		//Converting optional argument to integer
-		i, err := strconv.Atoi(messageTokens[1])
+	// !generate calls GenerateBird() command, and the guild's word list commands
+	// User can specify the number of adjectives
+	if inv.Command == "generate" {
+		inv.Log.Info("handling command")
+		err := runGenerate(s, inv)
 		// Error handling
 		if err != nil {
-			fmt.Println(err)
+			inv.fail(s, err)
+			return
 		}
-
-		_, _ = s.ChannelMessageSend(m.ChannelID, GenerateBird(i))
-
 	}
-
 }
 
-//loadGenerator loads the given .csv file path into the bird generator
-func loadGenerator(file string) {
-	//Opening reader with .csv file
-	r, err := os.Open(file)
-	//Error checking
-	if err != nil {
-		fmt.Println(err.Error())
-		return
+// namedLocation returns the location matching the lowercased name a user typed, e.g. after !get or !rare.
+// Configured locations are checked first, then the locations saved by the guild.
+// The guild's query mode for the location, if set, replaces the location's own.
+func namedLocation(guildID, name string) (Location, bool) {
+	return locationIn(store.Guild(guildID), name)
+}
 
+// locationIn is namedLocation for settings already read from the store.
+func locationIn(g GuildSettings, name string) (Location, bool) {
+	loc, ok := Locations()[name]
+	if !ok {
+		e, saved := g.Locations[name]
+		if !saved {
+			return Location{}, false
+		}
+		loc = e.location()
 	}
-	defer r.Close()
-
-	//Creating csv reader
-	reader := csv.NewReader(r)
 
-	records, err := reader.ReadAll()
-	//Error checking
-	if err != nil {
-		fmt.Println(err.Error())
-		return
+	if mode, ok := g.LocationModes[name]; ok {
+		loc.mode = mode
 	}
+	return loc, true
+}
 
-	//Adding fields to respective arrays
-	for i := 0; i < len(records); i++ {
-		Adjectives = append(Adjectives, records[i][0])
-		if len(records[i][1]) > 0 {
-			Nouns = append(Nouns, records[i][1])
+// defaultLocation returns the location used when a command that can take one is given none.
+// This is the guild's default location if it set one, and otherwise builtinDefaultLocationName.
+func defaultLocation(guildID string) Location {
+	if name := store.Guild(guildID).DefaultLocation; name != "" {
+		if loc, ok := namedLocation(guildID, name); ok {
+			return loc
 		}
 	}
+	loc, _ := namedLocation(guildID, builtinDefaultLocationName(guildID))
+	return loc
+}
+
+// builtinDefaultLocationName returns the name of the default location for guilds that did not pick one.
+// This is RIT, or the alphabetically first configured location if a locations file leaves it out.
+func builtinDefaultLocationName(guildID string) string {
+	if _, ok := namedLocation(guildID, "rit"); ok {
+		return "rit"
+	}
+	return firstLocationName()
 }
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHandleMessageGet(t *testing.T) {
+	f := newFakeEBird(t)
+	f.route("/data/obs/geo/recent", "recent.json")
+
+	sent := send(t, "!get RIT")
+	if len(sent) != 1 {
+		t.Fatalf("sent %d messages, want 1", len(sent))
+	}
+	want := "**Verified eBird sightings within 5 km of Rochester Institute of Technology in the past 14 days:**\n" +
+		"American Crow: 40\nAmerican Robin: 12\nBlue Jay: 3\n"
+	if sent[0].Content != want {
+		t.Errorf("content = %q, want %q", sent[0].Content, want)
+	}
+	if sent[0].ChannelID != "c1" {
+		t.Errorf("channel = %q, want c1", sent[0].ChannelID)
+	}
+
+	q := f.lastRequest().URL.Query()
+	if q.Get("lat") != "43.08" || q.Get("lng") != "-77.67" || q.Get("dist") != "5" || q.Get("back") != "14" {
+		t.Errorf("unexpected query %v", q)
+	}
+}
+
+func TestHandleMessageGetReversed(t *testing.T) {
+	f := newFakeEBird(t)
+	f.route("/data/obs/geo/recent", "recent.json")
+
+	sent := send(t, "!get mendon reversed")
+	if len(sent) != 1 {
+		t.Fatalf("sent %d messages, want 1", len(sent))
+	}
+	if !strings.HasSuffix(sent[0].Content, "Blue Jay: 3\nAmerican Robin: 12\nAmerican Crow: 40\n") {
+		t.Errorf("content not reversed: %q", sent[0].Content)
+	}
+}
+
+func TestHandleMessageRare(t *testing.T) {
+	f := newFakeEBird(t)
+	f.route("/data/obs/geo/recent/notable", "notable.json")
+
+	sent := send(t, "!rare braddock")
+	if len(sent) != 1 {
+		t.Fatalf("sent %d messages, want 1", len(sent))
+	}
+	want := "**Notable eBird sightings within 15 km of Braddock Bay Park in the past 14 days:**\n" +
+		"Parasitic Jaeger: 2 [#1 Hamlin Beach State Park: 2022-10-08]\n " +
+		"Snowy Owl: 2 [#2 Braddock Bay Park: 2022-10-11]\n "
+	if sent[0].Content != want {
+		t.Errorf("content = %q, want %q", sent[0].Content, want)
+	}
+	if _, ok := sent[0].Files["rare-map.png"]; !ok {
+		t.Errorf("no map attached, files = %v", sent[0].Files)
+	}
+	if got := f.lastRequest().URL.Query().Get("dist"); got != "15" {
+		t.Errorf("dist = %q, want 15", got)
+	}
+}
+
+func TestHandleMessageUpstreamError(t *testing.T) {
+	f := newFakeEBird(t)
+	changeConf(t, func(c *Config) { c.Key = "wrong-key" })
+	f.route("/data/obs/geo/recent", "recent.json")
+
+	sent := send(t, "!get rit")
+	if len(sent) != 1 {
+		t.Fatalf("sent %d messages, want 1", len(sent))
+	}
+	if strings.Contains(sent[0].Content, f.URL) {
+		t.Errorf("error reply leaks the eBird URL: %q", sent[0].Content)
+	}
+	if !strings.Contains(sent[0].Content, "reference code") {
+		t.Errorf("error reply has no reference code: %q", sent[0].Content)
+	}
+}
+
+func TestHandleMessageUnknownLocation(t *testing.T) {
+	newFakeEBird(t)
+
+	sent := send(t, "!get nowhere")
+	if len(sent) != 1 || sent[0].Content != "Error: 'nowhere' is not a valid option for !get" {
+		t.Errorf("unexpected reply %+v", sent)
+	}
+}
+
+func TestHandleMessageHelp(t *testing.T) {
+	sent := send(t, "!flamingo")
+	if len(sent) != 1 || sent[0].Embed == nil {
+		t.Fatalf("expected one embed, got %+v", sent)
+	}
+	if sent[0].Embed.Title != "FlaminGo Command Help" {
+		t.Errorf("title = %q", sent[0].Embed.Title)
+	}
+}
+
+func TestHandleMessageIgnoresOtherMessages(t *testing.T) {
+	if sent := send(t, "hello flamingo"); len(sent) != 0 {
+		t.Errorf("replied to a non-command: %+v", sent)
+	}
+
+	old := BotID
+	BotID = "u1"
+	defer func() { BotID = old }()
+	if sent := send(t, "!flamingo"); len(sent) != 0 {
+		t.Errorf("replied to itself: %+v", sent)
+	}
+}
+
+func TestHandleMessageGenerate(t *testing.T) {
+	useDefaultWords(t)
+
+	sent := send(t, "!generate 2")
+	if len(sent) != 1 {
+		t.Fatalf("sent %d messages, want 1", len(sent))
+	}
+	if words := strings.Fields(sent[0].Content); len(words) < 3 {
+		t.Errorf("expected two adjectives and a noun, got %q", sent[0].Content)
+	}
+}
+
+func TestHandleMessageGetOptions(t *testing.T) {
+	f := newFakeEBird(t)
+	f.route("/data/obs/geo/recent", "recent.json")
+
+	sent := send(t, "!get braddock radius:20 days:1")
+	if len(sent) != 1 {
+		t.Fatalf("sent %d messages, want 1", len(sent))
+	}
+	if !strings.HasPrefix(sent[0].Content, "**Verified eBird sightings within 20 km of Braddock Bay Park in the past 1 day:**") {
+		t.Errorf("header does not use the options: %q", sent[0].Content)
+	}
+	q := f.lastRequest().URL.Query()
+	if q.Get("dist") != "20" || q.Get("back") != "1" {
+		t.Errorf("unexpected query %v", q)
+	}
+
+	sent = send(t, "!get braddock radius:99")
+	if len(sent) != 1 || !strings.HasPrefix(sent[0].Content, "Error: 'radius' must be") {
+		t.Errorf("unexpected reply %+v", sent)
+	}
+}
+
+func TestHandleMessagePrefixAndMention(t *testing.T) {
+	f := newFakeEBird(t)
+	useTempStore(t)
+	f.route("/ref/taxonomy/ebird", "taxonomy.json")
+	f.route("/data/nearest/geo/recent/snoowl1", "nearest_snoowl1.json")
+	old := BotID
+	BotID = "bot1"
+	defer func() { BotID = old }()
+
+	sendAsAdmin(t, "!config set prefix ?")
+	if sent := send(t, "!nearest snowy owl"); len(sent) != 0 {
+		t.Errorf("answered the old prefix: %+v", sent)
+	}
+
+	// Quoted species names with extra spaces and tabs work with the new prefix and with a mention
+	for _, msg := range []string{"?nearest  \"Snowy Owl\"\tfrom rit", "<@bot1> nearest \"snowy owl\" from rit"} {
+		sent := send(t, msg)
+		if len(sent) != 1 || !strings.HasPrefix(sent[0].Content, "**Nearest Snowy Owl to Rochester Institute of Technology") {
+			t.Errorf("%s: unexpected reply %+v", msg, sent)
+		}
+	}
+
+	sent := send(t, "?flamingo")
+	if len(sent) != 1 || sent[0].Embed == nil || !strings.HasPrefix(sent[0].Embed.Fields[1].Name, "?get") {
+		t.Errorf("help does not show the prefix: %+v", sent)
+	}
+}
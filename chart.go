@@ -0,0 +1,99 @@
+// Chart contains the small drawing helpers FlaminGo uses to render images in pure Go
+
+package main
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"strings"
+)
+
+// Colors used in rendered images.
+var (
+	colorPink       = color.RGBA{0xff, 0x00, 0x99, 0xff} // Same pink as the embeds
+	colorBackground = color.RGBA{0xff, 0xff, 0xff, 0xff}
+	colorAxis       = color.RGBA{0x44, 0x44, 0x44, 0xff}
+	colorGrid       = color.RGBA{0xdd, 0xdd, 0xdd, 0xff}
+	colorNoData     = color.RGBA{0xbb, 0xbb, 0xbb, 0xff}
+)
+
+// glyphs is a tiny 3x5 pixel font covering the characters FlaminGo draws: digits, month initials and a few symbols.
+// Each glyph is five rows of three pixels, with '#' set.
+var glyphs = map[rune][5]string{
+	'0': {"###", "#.#", "#.#", "#.#", "###"},
+	'1': {".#.", "##.", ".#.", ".#.", "###"},
+	'2': {"###", "..#", "###", "#..", "###"},
+	'3': {"###", "..#", "###", "..#", "###"},
+	'4': {"#.#", "#.#", "###", "..#", "..#"},
+	'5': {"###", "#..", "###", "..#", "###"},
+	'6': {"###", "#..", "###", "#.#", "###"},
+	'7': {"###", "..#", "..#", "..#", "..#"},
+	'8': {"###", "#.#", "###", "#.#", "###"},
+	'9': {"###", "#.#", "###", "..#", "###"},
+	'%': {"#.#", "..#", ".#.", "#..", "#.#"},
+	'-': {"...", "...", "###", "...", "..."},
+	'.': {"...", "...", "...", "...", ".#."},
+	'A': {".#.", "#.#", "###", "#.#", "#.#"},
+	'D': {"##.", "#.#", "#.#", "#.#", "##."},
+	'E': {"###", "#..", "##.", "#..", "###"},
+	'F': {"###", "#..", "##.", "#..", "#.."},
+	'J': {"..#", "..#", "..#", "#.#", "###"},
+	'K': {"#.#", "#.#", "##.", "#.#", "#.#"},
+	'M': {"#.#", "###", "###", "#.#", "#.#"},
+	'N': {"##.", "#.#", "#.#", "#.#", "#.#"},
+	'O': {"###", "#.#", "#.#", "#.#", "###"},
+	'S': {"###", "#..", "###", "..#", "###"},
+	'W': {"#.#", "#.#", "###", "###", "#.#"},
+}
+
+// textWidth returns the width in pixels of s drawn at the given scale.
+func textWidth(s string, scale int) int {
+	n := len([]rune(s))
+	if n == 0 {
+		return 0
+	}
+	return (n*4 - 1) * scale
+}
+
+// drawText draws s with its top left corner at x, y, scaling every font pixel to scale x scale.
+// Characters missing from the font are left blank.
+func drawText(img draw.Image, x, y int, s string, scale int, c color.Color) {
+	for _, r := range strings.ToUpper(s) {
+		if g, ok := glyphs[r]; ok {
+			for row, line := range g {
+				for col, px := range line {
+					if px == '#' {
+						fillRect(img, image.Rect(x+col*scale, y+row*scale, x+(col+1)*scale, y+(row+1)*scale), c)
+					}
+				}
+			}
+		}
+		x += 4 * scale
+	}
+}
+
+// fillRect fills r with c.
+func fillRect(img draw.Image, r image.Rectangle, c color.Color) {
+	draw.Draw(img, r, image.NewUniform(c), image.Point{}, draw.Src)
+}
+
+// newCanvas returns a white image of the given size.
+func newCanvas(width, height int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	fillRect(img, img.Bounds(), colorBackground)
+	return img
+}
+
+// encodePNG encodes img as a PNG file.
+func encodePNG(img image.Image) ([]byte, error) {
+	var buf bytes.Buffer
+	err := png.Encode(&buf, img)
+	// Error handling
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,329 @@
+// Checklists contains the !checklists feed and the !checklist detail view
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// ChecklistSummary is an entry in eBird's recent checklists feed.
+type ChecklistSummary struct {
+	LocID           string
+	SubID           string
+	UserDisplayName string
+	NumSpecies      int
+	ObsDt           string
+	ObsTime         string
+	// IsoObsDate is when the checklist started, e.g. "2022-10-10 08:15", or only the date if it has no time.
+	IsoObsDate string
+	Loc        struct {
+		LocName string
+	}
+	// DurationHrs is not part of the feed, and is filled in from the checklist itself.
+	DurationHrs float64
+}
+
+// Checklist is a full checklist from eBird's checklist view endpoint.
+type Checklist struct {
+	SubID           string
+	LocID           string
+	UserDisplayName string
+	ObsDt           string
+	DurationHrs     float64
+	NumObservers    int
+	NumSpecies      int
+	AllObsReported  bool
+	ProtocolID      string
+	Comments        string
+	Obs             []ChecklistObs
+}
+
+// ChecklistObs is a single species entry on a Checklist.
+type ChecklistObs struct {
+	SpeciesCode string
+	HowManyStr  string
+	Comments    string
+}
+
+// subIDPattern matches eBird checklist IDs, e.g. S120000001.
+var subIDPattern = regexp.MustCompile(`^S[0-9]+$`)
+
+// Limits for the number of checklists !checklists shows.
+const (
+	defaultChecklists = 5
+	maxChecklists     = 10
+	// checklistConcurrency is the most checklists !checklists fetches from eBird at once.
+	checklistConcurrency = 4
+)
+
+// Limits for the pages of a !checklist embed. Species past the last page are only counted, with the link to eBird.
+const (
+	// checklistPageSize is the number of species shown on each page.
+	checklistPageSize = 25
+	// maxChecklistPages is the most pages shown, so a long checklist does not flood the channel.
+	maxChecklistPages = 4
+	// maxEmbedDescription is the longest description Discord accepts in an embed.
+	maxEmbedDescription = 4096
+)
+
+// checklistsUsage explains the !checklists command.
+const checklistsUsage = "use `!checklists <location or region:code> [1-10]`"
+
+// runChecklists handles !checklists <location> [n].
+func runChecklists(s Sender, inv *Invocation) error {
+	ctx := inv.Context()
+	positional, options := splitOptions(inv.Args)
+
+	// The feed works for any eBird location code or region code
+	code, name, positional, err := codeArg(ctx, inv.GuildID, "checklists", positional, options)
+	// Error handling
+	if err != nil {
+		return err
+	}
+
+	n := defaultChecklists
+	if len(positional) > 1 {
+		return usageErrorf("%s", checklistsUsage)
+	}
+	if len(positional) == 1 {
+		n, err = intOption(map[string]string{"count": positional[0]}, "count", 1, maxChecklists, n)
+		// Error handling
+		if err != nil {
+			return err
+		}
+	}
+
+	c, err := GetRecentChecklists(ctx, code, n)
+	// Error handling
+	if err != nil {
+		return err
+	}
+	inv.send(s, FormatChecklists(name, c, inv.Locale))
+	return nil
+}
+
+// runChecklist handles !checklist <subId>.
+func runChecklist(s Sender, inv *Invocation) error {
+	ctx := inv.Context()
+	positional, _ := splitOptions(inv.Args)
+	if len(positional) != 1 {
+		return usageErrorf("use `!checklist <checklist ID>`, e.g. `!checklist S120000001`")
+	}
+	// Commands are lowercased, but checklist IDs start with an uppercase S
+	subID := strings.ToUpper(positional[0])
+	if !subIDPattern.MatchString(subID) {
+		return usageErrorf("'%s' is not an eBird checklist ID like S120000001", positional[0])
+	}
+
+	c, err := GetChecklist(ctx, subID)
+	// Error handling
+	if err != nil {
+		return err
+	}
+	embeds, err := ChecklistEmbeds(ctx, c, inv.Locale)
+	// Error handling
+	if err != nil {
+		return err
+	}
+	inv.sendEmbeds(s, embeds)
+	return nil
+}
+
+// GetRecentChecklists returns the n most recent checklists submitted at an eBird location or region,
+// with the duration of each looked up from the checklist itself.
+func GetRecentChecklists(ctx context.Context, code string, n int) ([]ChecklistSummary, error) {
+	c, err := checklistFeed(ctx, code, n)
+	// Error handling
+	if err != nil {
+		return nil, err
+	}
+
+	// The feed has no durations, so each checklist is fetched. A failed lookup only leaves the duration blank.
+	// Every lookup returns nil, so runLimited cannot fail here
+	_ = runLimited(len(c), checklistConcurrency, func(i int) error {
+		full, err := GetChecklist(ctx, c[i].SubID)
+		if err != nil {
+			logger.Debug("leaving checklist duration blank", slog.String("subId", c[i].SubID), slog.Any("err", err))
+			return nil
+		}
+		c[i].DurationHrs = full.DurationHrs
+		return nil
+	})
+	return c, nil
+}
+
+// checklistFeed returns the n most recent checklists submitted at an eBird location or region, newest first.
+func checklistFeed(ctx context.Context, code string, n int) ([]ChecklistSummary, error) {
+	var c []ChecklistSummary
+	err := ebirdGet(ctx, fmt.Sprintf("%s/product/lists/%s?maxResults=%d", Conf().EBirdURL, code, n), &c)
+	// Error handling
+	if err != nil {
+		return nil, fmt.Errorf("getting recent checklists for %s: %w", code, err)
+	}
+	return c, nil
+}
+
+// GetChecklist returns the full checklist with the given ID.
+func GetChecklist(ctx context.Context, subID string) (Checklist, error) {
+	var c Checklist
+	err := ebirdGet(ctx, fmt.Sprintf("%s/product/checklist/view/%s", Conf().EBirdURL, subID), &c)
+	// Error handling
+	if err != nil {
+		return c, fmt.Errorf("getting checklist %s: %w", subID, err)
+	}
+	return c, nil
+}
+
+// FormatChecklists returns a list of recent checklists for a Discord message in locale.
+func FormatChecklists(name string, c []ChecklistSummary, locale string) string {
+	if len(c) == 0 {
+		return tr(locale, "**No recent eBird checklists found at %s.**", name)
+	}
+
+	rString := tr(locale, "**Recent eBird checklists at %s:**\n", name)
+	for _, list := range c {
+		where := ""
+		if list.Loc.LocName != "" && list.Loc.LocName != name {
+			where = " " + tr(locale, "at %s", list.Loc.LocName)
+		}
+		rString += tr(locale, "`%s` %s %s: %s%s, %d species, %s\n", list.SubID, list.ObsDt, list.ObsTime, list.UserDisplayName, where,
+			list.NumSpecies, formatDuration(list.DurationHrs, locale))
+	}
+	rString += tr(locale, "See one with `!checklist <checklist ID>`\n")
+
+	//Trimming return string to Discord max message length, minus 5 characters to show max length has been reached
+	return truncateText(rString, 1995)
+}
+
+// ChecklistEmbeds renders a checklist as one or more embeds, with checklistPageSize species on each named in locale.
+func ChecklistEmbeds(ctx context.Context, c Checklist, locale string) ([]*discordgo.MessageEmbed, error) {
+	// Species codes are turned into names through the taxonomy
+	codes := make([]string, len(c.Obs))
+	for i, o := range c.Obs {
+		codes[i] = o.SpeciesCode
+	}
+	tax, err := lookupTaxa(ctx, codes, locale)
+	// Error handling
+	if err != nil {
+		return nil, err
+	}
+
+	var lines []string
+	for _, o := range c.Obs {
+		name := o.SpeciesCode
+		if t, ok := tax[o.SpeciesCode]; ok {
+			name = t.ComName
+		}
+		line := fmt.Sprintf("%s: %s", name, o.HowMany(locale))
+		if o.Comments != "" {
+			line += fmt.Sprintf(" *(%s)*", truncateRunes(o.Comments, 150))
+		}
+		lines = append(lines, line)
+	}
+
+	// Splitting the species into pages
+	pages := (len(lines) + checklistPageSize - 1) / checklistPageSize
+	if pages == 0 {
+		pages = 1
+	}
+	hidden := 0
+	if pages > maxChecklistPages {
+		pages = maxChecklistPages
+		hidden = len(lines) - pages*checklistPageSize
+	}
+	url := "https://ebird.org/checklist/" + c.SubID
+	var embeds []*discordgo.MessageEmbed
+	for p := 0; p < pages; p++ {
+		end := (p + 1) * checklistPageSize
+		if end > len(lines) {
+			end = len(lines)
+		}
+
+		embed := &discordgo.MessageEmbed{
+			Color:       defaultEmbedColor,
+			Title:       tr(locale, "Checklist %s", c.SubID),
+			URL:         url,
+			Description: truncateRunes(strings.Join(lines[p*checklistPageSize:end], "\n"), maxEmbedDescription),
+			Footer: &discordgo.MessageEmbedFooter{
+				Text: tr(locale, "Page %d/%d", p+1, pages),
+			},
+		}
+		if p == pages-1 && hidden > 0 {
+			embed.Footer.Text += tr(locale, ", %d more species on eBird", hidden)
+		}
+
+		// The checklist's details only go on the first page
+		if p == 0 {
+			embed.Fields = []*discordgo.MessageEmbedField{
+				{Name: tr(locale, "Observer"), Value: orNone(c.UserDisplayName, locale), Inline: true},
+				{Name: tr(locale, "Date"), Value: orNone(c.ObsDt, locale), Inline: true},
+				{Name: tr(locale, "Duration"), Value: formatDuration(c.DurationHrs, locale), Inline: true},
+				{Name: tr(locale, "Species"), Value: strconv.Itoa(len(c.Obs)), Inline: true},
+				{Name: tr(locale, "Observers"), Value: strconv.Itoa(c.NumObservers), Inline: true},
+				{Name: tr(locale, "Complete"), Value: yesNo(c.AllObsReported, locale), Inline: true},
+			}
+			if c.Comments != "" {
+				embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{Name: tr(locale, "Comments"), Value: truncateRunes(c.Comments, 1024)})
+			}
+		}
+		if embed.Description == "" {
+			embed.Description = tr(locale, "No species reported.")
+		}
+		embeds = append(embeds, embed)
+	}
+	return embeds, nil
+}
+
+// HowMany returns the count of an observation, with "X" (present, not counted) spelled out in locale.
+func (o ChecklistObs) HowMany(locale string) string {
+	if o.HowManyStr == "" || o.HowManyStr == "X" {
+		return tr(locale, "present")
+	}
+	return o.HowManyStr
+}
+
+// formatDuration formats a duration in hours as e.g. "1h 30m", or "no duration" in locale for incidental checklists.
+func formatDuration(hours float64, locale string) string {
+	minutes := int(hours*60 + 0.5)
+	switch {
+	case minutes <= 0:
+		return tr(locale, "no duration")
+	case minutes < 60:
+		return fmt.Sprintf("%dm", minutes)
+	case minutes%60 == 0:
+		return fmt.Sprintf("%dh", minutes/60)
+	}
+	return fmt.Sprintf("%dh %dm", minutes/60, minutes%60)
+}
+
+// truncateRunes shortens s to at most max characters, adding an ellipsis if anything was cut.
+func truncateRunes(s string, max int) string {
+	r := []rune(s)
+	if len(r) <= max {
+		return s
+	}
+	return string(r[:max-1]) + "…"
+}
+
+// orNone returns s, or "None" in locale if it is empty, since Discord rejects empty embed fields.
+func orNone(s, locale string) string {
+	if s == "" {
+		return tr(locale, "None")
+	}
+	return s
+}
+
+// yesNo formats a bool for an embed field in locale.
+func yesNo(b bool, locale string) string {
+	if b {
+		return tr(locale, "Yes")
+	}
+	return tr(locale, "No")
+}
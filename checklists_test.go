@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestChecklists(t *testing.T) {
+	f := newFakeEBird(t)
+	f.route("/product/lists/L976278", "checklists.json")
+	f.route("/product/checklist/view/S120000001", "checklist_S120000001.json")
+
+	sent := send(t, "!checklists rit 2")
+	want := "**Recent eBird checklists at Rochester Institute of Technology:**\n" +
+		"`S120000001` 10 Oct 2022 08:15: Alex Birder, 2 species, 1h 30m\n" +
+		"`S120000003` 9 Oct 2022 17:40: Sam Lister, 9 species, no duration\n" +
+		"See one with `!checklist <checklist ID>`\n"
+	if len(sent) != 1 || sent[0].Content != want {
+		t.Fatalf("got %+v, want %q", sent, want)
+	}
+
+	sent = send(t, "!checklists rit 11")
+	if len(sent) != 1 || !strings.HasPrefix(sent[0].Content, "Error: 'count' must be a whole number from 1 to 10") {
+		t.Errorf("unexpected reply %+v", sent)
+	}
+}
+
+func TestChecklistView(t *testing.T) {
+	f := newFakeEBird(t)
+	f.route("/product/checklist/view/S120000001", "checklist_S120000001.json")
+	f.route("/ref/taxonomy/ebird", "taxonomy.json")
+
+	sent := send(t, "!checklist s120000001")
+	if len(sent) != 1 || sent[0].Embed == nil {
+		t.Fatalf("expected one embed, got %+v", sent)
+	}
+	e := sent[0].Embed
+	wantDesc := "American Robin: 12\nBlue Jay: 3 *(Calling from the pines)*\nDowny Woodpecker: present"
+	if e.Description != wantDesc {
+		t.Errorf("description = %q, want %q", e.Description, wantDesc)
+	}
+	if e.Fields[0].Value != "Alex Birder" || e.Fields[2].Value != "1h 30m" {
+		t.Errorf("unexpected fields %+v %+v", e.Fields[0], e.Fields[2])
+	}
+	if e.Footer.Text != "Page 1/1" {
+		t.Errorf("footer = %q", e.Footer.Text)
+	}
+
+	sent = send(t, "!checklist 12345")
+	if len(sent) != 1 || !strings.HasPrefix(sent[0].Content, "Error: '12345' is not an eBird checklist ID") {
+		t.Errorf("unexpected reply %+v", sent)
+	}
+}
+
+func TestChecklistEmbedsPaging(t *testing.T) {
+	f := newFakeEBird(t)
+	f.route("/ref/taxonomy/ebird", "taxonomy.json")
+
+	c := Checklist{SubID: "S1"}
+	for i := 0; i < checklistPageSize*2+1; i++ {
+		c.Obs = append(c.Obs, ChecklistObs{SpeciesCode: fmt.Sprintf("sp%d", i), HowManyStr: "1"})
+	}
+	embeds, err := ChecklistEmbeds(context.Background(), c, "en")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(embeds) != 3 {
+		t.Fatalf("got %d pages, want 3", len(embeds))
+	}
+	if embeds[2].Footer.Text != "Page 3/3" || embeds[2].Fields != nil {
+		t.Errorf("unexpected last page %+v", embeds[2])
+	}
+	// Species missing from the taxonomy are shown by code
+	if !strings.HasPrefix(embeds[0].Description, "sp0: 1\n") {
+		t.Errorf("description = %q", embeds[0].Description)
+	}
+
+	// Long checklists stop after maxChecklistPages, counting the species left out
+	for i := len(c.Obs); i < checklistPageSize*maxChecklistPages+30; i++ {
+		c.Obs = append(c.Obs, ChecklistObs{SpeciesCode: fmt.Sprintf("sp%d", i), HowManyStr: "1"})
+	}
+	embeds, err = ChecklistEmbeds(context.Background(), c, "en")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(embeds) != maxChecklistPages || embeds[maxChecklistPages-1].Footer.Text != "Page 4/4, 30 more species on eBird" {
+		t.Errorf("got %d pages, last footer %q", len(embeds), embeds[len(embeds)-1].Footer.Text)
+	}
+}
+
+func TestSendEmbedsBatches(t *testing.T) {
+	inv := newInvocation(newMessage("!checklist S1"), "checklist", []string{"s1"})
+	var small, large []*discordgo.MessageEmbed
+	for i := 0; i < 12; i++ {
+		small = append(small, &discordgo.MessageEmbed{Title: fmt.Sprint(i)})
+	}
+	for i := 0; i < 3; i++ {
+		large = append(large, &discordgo.MessageEmbed{Description: strings.Repeat("a", 2500)})
+	}
+
+	// At most 10 embeds, and 6000 characters, go in one message
+	for _, tt := range []struct {
+		embeds []*discordgo.MessageEmbed
+		want   []int
+	}{{small, []int{10, 2}}, {large, []int{2, 1}}} {
+		s := &fakeSession{}
+		inv.sendEmbeds(s, tt.embeds)
+		var got []int
+		for _, m := range s.messages() {
+			got = append(got, len(m.Embeds))
+		}
+		if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+			t.Errorf("sent messages with %v embeds, want %v", got, tt.want)
+		}
+	}
+}
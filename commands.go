@@ -3,12 +3,12 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"math/rand"
 	"net/http"
-	"sort"
 	"strings"
 	"time"
 
@@ -22,10 +22,19 @@ import (
 
 // BirdSighting holds information related to a specific bird sighting in GetRecentObservations and GetRareObservations commands.
 type BirdSighting struct {
-	ComName string
-	HowMany int
-	LocName string
-	ObsDt   string
+	SpeciesCode     string
+	ComName         string `export:"name"`
+	SciName         string
+	LocID           string
+	LocName         string
+	ObsDt           string `export:"time"`
+	HowMany         int
+	Lat             float64 `export:"lat"`
+	Lng             float64 `export:"lng"`
+	ObsValid        bool
+	ObsReviewed     bool
+	LocationPrivate bool
+	SubID           string
 }
 
 // EmbedInfo holds information retrieved from AllAboutBirds for a bird info embed, for use in the DisplayBird() command
@@ -46,71 +55,154 @@ type EmbedInfo struct {
 
 // Defining Commands
 
-// DisplayHelp() returns a DiscordGo embed message listing FlaminGo's commands and usage
-func DisplayHelp() *discordgo.MessageEmbed {
+// DisplayHelp() returns a DiscordGo embed message listing FlaminGo's commands and usage, with the guild's settings
+// and in the given locale
+func DisplayHelp(guildID, locale string) *discordgo.MessageEmbed {
 	//from: https://github.com/bwmarrin/discordgo/wiki/FAQ#sending-embeds
-	return &discordgo.MessageEmbed{
-		Color: 16711833, // Pink
+	help := &discordgo.MessageEmbed{
+		Color: defaultEmbedColor,
 		Fields: []*discordgo.MessageEmbedField{
 			// !flamingo
 			{
 				Name:   "!flamingo",
-				Value:  "Displays this list of commands",
+				Value:  tr(locale, "Displays this list of commands"),
 				Inline: false,
 			},
 			// !get
 			{
-				Name:   "!get (RIT/Mendon/Braddock) {reversed}",
-				Value:  "Returns a list of birds seen within 5km of the specified location in the past 2 weeks. Optionally, include 'reversed' to reverse the alphabetical order.",
+				Name:   fmt.Sprintf("!get (%s/region:code) {radius:1-50} {days:1-30} {sort:name/count/date/taxonomic} {group:family} {reversed} {export:%s}", locationNames(), exportFormatNames()),
+				Value:  tr(locale, "Returns a list of birds seen within %dkm of the specified location in the past %s. Optionally, include 'radius:' and 'days:' to change the search, 'sort:' and 'group:family' to change the order, or 'reversed' to reverse it. Add 'export:' to get every sighting as a file instead.", guildRadius(guildID), pluralizeIn(locale, Conf().BackDays, "day")),
 				Inline: false,
 			},
 			// !rare
 			{
-				Name:   "!rare (RIT/Mendon/Braddock) {reversed}",
-				Value:  "Returns a list of notable bird sightings (rare, out of season, etc.) within 15km of the specified location. Optionally, include 'reversed' to reverse the alphabetical order.",
+				Name:   fmt.Sprintf("!rare (%s/region:code) {radius:1-50} {days:1-30} {sort:name/count/date/taxonomic} {group:family} {reversed} {export:%s}", locationNames(), exportFormatNames()),
+				Value:  tr(locale, "Returns a list of notable bird sightings (rare, out of season, etc.) within %dkm of the specified location in the past %s, with a map of where they were seen. Optionally, include 'radius:' and 'days:' to change the search, 'sort:' and 'group:family' to change the order, or 'reversed' to reverse it. Add 'export:' to get every sighting as a file instead.", guildRareRadius(guildID), pluralizeIn(locale, Conf().BackDays, "day")),
+				Inline: false,
+			},
+			// !locations
+			{
+				Name:   "!locations {mode (location) (geo/hotspot)}",
+				Value:  tr(locale, "Lists the locations you can use in commands. Server managers can set a location's mode to 'hotspot' to only see sightings reported at that exact eBird hotspot, or 'geo' to search the area around it. Commands also take 'mode:' for a single search."),
+				Inline: false,
+			},
+			// !checklists
+			{
+				Name:   "!checklists (location/region:code) {1-10}",
+				Value:  tr(locale, "Lists the most recent eBird checklists at a location, with who birded, when, how many species and for how long. See a whole checklist with '!checklist (checklist ID)'."),
+				Inline: false,
+			},
+			// !nearest
+			{
+				Name:   fmt.Sprintf("!nearest (species) {from location} {1-30} {export:%s}", exportFormatNames()),
+				Value:  tr(locale, "Finds the closest recent reports of a species, with distance, direction, date, count and location. Species names can be common names, scientific names or banding codes. Add 'export:' to get the reports as a file."),
+				Inline: false,
+			},
+			// !season
+			{
+				Name:   "!season (species) (location/region:code) {years:1-5}",
+				Value:  tr(locale, "Charts how often a species was reported at a place in each week of the year, to show the best time to see it."),
+				Inline: false,
+			},
+			// !history and !onthisday
+			{
+				Name:   "!history (location/region:code) (YYYY-MM-DD) {years:2-10} | !onthisday (location/region:code) {2-10}",
+				Value:  tr(locale, "Compares the eBird sightings on a date (or today) with the same date in earlier years: species per year, species new this year and species missing this year."),
+				Inline: false,
+			},
+			// !top100
+			{
+				Name:   "!top100 (region code) {YYYY-MM-DD} {by:species/checklists}",
+				Value:  tr(locale, "Shows the top eBirders in a region on a day, by species or by checklists. Defaults to today."),
+				Inline: false,
+			},
+			// !stats
+			{
+				Name:   "!stats (region code) {YYYY-MM-DD/YYYY-MM-DD..YYYY-MM-DD/month:YYYY-MM}",
+				Value:  tr(locale, "Shows how many checklists, contributors and species a region had on a day, or a day-by-day recap over a range of up to 31 days."),
+				Inline: false,
+			},
+			// !region
+			{
+				Name:   "!region search (name) {in:region code}",
+				Value:  tr(locale, "Finds eBird region codes for countries, states and counties, for use with '!get region:US-NY-055' or '!rare region:US-NY'. Search inside a country or state with 'in:', e.g. 'in:US-NY'."),
+				Inline: false,
+			},
+			// !hotspots
+			{
+				Name:   fmt.Sprintf("!hotspots near (lat,long/location) {radius} {export:%s}", exportFormatNames()),
+				Value:  tr(locale, "Lists eBird hotspots near a location, with their all-time species counts and latest sightings. Add 'export:' to get the list as a file. Server managers can save one as a location for this server with '!hotspots save (number/code) (name)', adding 'replace' to overwrite a saved one."),
 				Inline: false,
 			},
 			// !bird
 			{
 				Name:   "!bird (Full Bird Name)",
-				Value:  "Displays info for the specified bird. Uses information and names from AllAboutBirds.org.",
+				Value:  tr(locale, "Displays info for the specified bird. Uses information and names from AllAboutBirds.org."),
+				Inline: false,
+			},
+			// !config
+			{
+				Name:   "!config {list/get (setting)/set (setting) (value/reset)}",
+				Value:  tr(locale, "Shows and changes FlaminGo's settings for this server: prefix, default location, radius, rare radius multiplier, embed color, language, enabled commands and announcement channel. Needs the Manage Server permission."),
+				Inline: false,
+			},
+			// !locale
+			{
+				Name:   fmt.Sprintf("!locale {me/server (%s/reset)}", localeCodes()),
+				Value:  tr(locale, "Sets the language of replies and species names, for you or for the whole server. Speaks %s.", localeNamesList()),
+				Inline: false,
+			},
+			// !trip
+			{
+				Name:   "!trip create (location) (YYYY-MM-DD HH:MM) {title} {hours:1-12} | !trip list | !trip cancel (id)",
+				Value:  tr(locale, "Plans a field trip: posts it with buttons to RSVP, reminds everyone going an hour before it starts, and afterwards posts a recap of the eBird sightings at the location during the trip, tagging everyone who went. Times are YYYY-MM-DD HH:MM."),
 				Inline: false,
 			},
 			// !generate
 			{
 				Name:   "!generate {0-3}",
-				Value:  "Randomly generates a bird name using a list of every bird species. Optionally, include 0-3 to specify the number of adjectives. Credit to Aidan Mahar for the lists and original idea!",
+				Value:  tr(locale, "Randomly generates a bird name using a list of every bird species. Optionally, include 0-3 to specify the number of adjectives. Credit to Aidan Mahar for the lists and original idea! Server managers can attach a .csv of adjectives and nouns to `!generate upload merge` to add their own words, or `!generate upload replace` to use only theirs, and `!generate reset` goes back to the defaults."),
 				Inline: false,
 			},
 		},
-		Title: "FlaminGo Command Help",
+		Title: tr(locale, "FlaminGo Command Help"),
 	}
+
+	// Showing the commands with the prefix the guild actually uses
+	if prefix := guildPrefix(guildID); prefix != defaultPrefix {
+		for _, f := range help.Fields {
+			f.Name = strings.ReplaceAll(f.Name, defaultPrefix, prefix)
+		}
+	}
+	return help
 }
 
-// GetRecentObservations returns a list of nearby observations in the specified radius (km) from the specified location.
-func GetRecentObservations(loc Location, radius int, reverseSort bool) string {
-	// Creating URL
-	url := fmt.Sprintf("https://api.ebird.org/v2/data/obs/geo/recent?lat=%v&lng=%v&sort=species&dist=%d", loc.lat, loc.long, radius)
-	method := "GET"
+// ebirdGet sends a GET request with FlaminGo's API key to the given eBird API URL and decodes the JSON response into v.
+// Every command shares FlaminGo's eBird rate limit, and backs off together when eBird answers 429.
+func ebirdGet(ctx context.Context, url string, v interface{}) error {
+	// Waiting for FlaminGo's turn
+	err := ebirdUpstream.wait(ctx)
+	// Error handling
+	if err != nil {
+		return err
+	}
 
-	//Creating HTTP request
+	//Creating HTTP request, cancelled with ctx
 	client := &http.Client{}
-	req, err := http.NewRequest(method, url, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	// Error handling
 	if err != nil {
-		fmt.Println(err)
-		return err.Error()
+		return fmt.Errorf("creating eBird request: %w", err)
 	}
 
 	// Adding API token to header
-	req.Header.Add("X-eBirdApiToken", Key)
+	req.Header.Add("X-eBirdApiToken", Conf().Key)
 
 	// Sends the request
 	res, err := client.Do(req)
 	//Error handling
 	if err != nil {
-		fmt.Println(err)
-		return err.Error()
+		return fmt.Errorf("sending eBird request: %w", err)
 	}
 	defer res.Body.Close()
 
@@ -118,154 +210,189 @@ func GetRecentObservations(loc Location, radius int, reverseSort bool) string {
 	body, err := ioutil.ReadAll(res.Body)
 	// Error handling
 	if err != nil {
-		fmt.Println(err)
-		return err.Error()
+		return fmt.Errorf("reading eBird response: %w", err)
 	}
 
-	// Creates an array of BirdSighting and Unmarshals the contents of body into this array
-	var b []BirdSighting
-	err = json.Unmarshal(body, &b)
+	// A 429 means eBird wants every command to slow down
+	err = ebirdUpstream.checkResponse(res.StatusCode, res.Header)
 	// Error handling
 	if err != nil {
-		fmt.Println(err)
-		return err.Error()
+		return err
 	}
 
-	// Sorting list of birds alphabetically, depending on whether reverseSort is true or false
-	if reverseSort {
-		sort.Slice(b, func(i, j int) bool {
-			return b[i].ComName > b[j].ComName
-		})
-	} else {
-		sort.Slice(b, func(i, j int) bool {
-			return b[i].ComName < b[j].ComName
-		})
+	// eBird reports errors such as a bad API key through the status code
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("eBird responded %s: %.200s", res.Status, body)
 	}
 
-	// Formatting return string
-	rString := fmt.Sprintf("**Verified eBird sightings within %d km of %v in the past 2 weeks:**\n", radius, loc.name)
-	for i := 0; i < len(b); i++ {
-		if b[i].HowMany > 0 {
-			rString += fmt.Sprintf("%v: %d\n", b[i].ComName, b[i].HowMany)
-		}
+	// Unmarshals the contents of body into v
+	err = json.Unmarshal(body, v)
+	// Error handling
+	if err != nil {
+		return fmt.Errorf("decoding eBird response: %w", err)
 	}
 
-	//Trimming return string to Discord max message length, minus 5 characters to show max length has been reached
-	rString = truncateText(rString, 1995)
-
-	return rString
+	return nil
 }
 
-// GetRareObservations returns a list of nearby notable observations in the specified radius (km) from the specified location.
-// A notable observation may be a rare bird or a bird out of season.
-func GetRareObservations(loc Location, radius int, reverseSort bool) string {
-	// Creating URL
-	url := fmt.Sprintf("https://api.ebird.org/v2/data/obs/geo/recent/notable?lat=%v&lng=%v&dist=%d&sort=species&hotspot=true", loc.lat, loc.long, radius)
-	method := "GET"
-
-	//Creating HTTP request
-	client := &http.Client{}
-	req, err := http.NewRequest(method, url, nil)
+// GetRecentObservations returns a list of observations in the query's days, either within its radius (km) of its location,
+// or at the location's hotspot only.
+func GetRecentObservations(ctx context.Context, q ObsQuery) (string, error) {
+	b, err := RecentSightings(ctx, &q)
 	// Error handling
 	if err != nil {
-		fmt.Println(err)
-		return err.Error()
+		return "", err
 	}
 
-	// Adding API token to header
-	req.Header.Add("X-eBirdApiToken", Key)
+	// Formatting return string
+	header := fmt.Sprintf("**%s:**\n", tr(q.Locale, "Verified eBird sightings %s in the past %s", q.where(), pluralizeIn(q.Locale, q.Days, "day")))
+	return formatSightings(ctx, header, b, q, func(s BirdSighting) string {
+		return fmt.Sprintf("%v: %d\n", s.ComName, s.HowMany)
+	})
+}
 
-	// Sends the request
-	res, err := client.Do(req)
-	// Error handling
-	if err != nil {
-		fmt.Println(err)
-		return err.Error()
+// RecentSightings returns the observations in the query's days as eBird lists them, filling in q.RegionName for
+// region queries.
+func RecentSightings(ctx context.Context, q *ObsQuery) ([]BirdSighting, error) {
+	loc := q.Location
+
+	// Creating URL
+	url := fmt.Sprintf("%s/data/obs/geo/recent?lat=%v&lng=%v&sort=species&dist=%d&back=%d", Conf().EBirdURL, loc.lat, loc.long, q.Radius, q.Days)
+	if q.Mode == modeHotspot {
+		url = fmt.Sprintf("%s/data/obs/%s/recent?back=%d", Conf().EBirdURL, loc.code, q.Days)
 	}
-	defer res.Body.Close()
+	if q.Region != "" {
+		url = fmt.Sprintf("%s/data/obs/%s/recent?back=%d", Conf().EBirdURL, q.Region, q.Days)
+		q.RegionName = regionNameOrCode(ctx, q.Region)
+	}
+	url += sppLocaleParam(q.Locale)
 
-	// Body is a JSON response
-	body, err := ioutil.ReadAll(res.Body)
+	// Creates an array of BirdSighting and fills it from the eBird response
+	var b []BirdSighting
+	err := ebirdGet(ctx, url, &b)
 	// Error handling
 	if err != nil {
-		fmt.Println(err)
-		return err.Error()
+		return nil, fmt.Errorf("getting recent observations %s: %w", q.where(), err)
 	}
+	return b, nil
+}
 
-	// Creates an array of BirdSighting and Unmarshals the contents of body into this array
-	var b []BirdSighting
-	err = json.Unmarshal(body, &b)
+// GetRareObservations returns a list of notable observations in the query's days, either within its radius (km)
+// of its location, or at the location's hotspot only.
+// A notable observation may be a rare bird or a bird out of season.
+// Each distinct place in the list is numbered, and returned as a MapPoint with the same number for the sighting map.
+func GetRareObservations(ctx context.Context, q ObsQuery) (string, []MapPoint, error) {
+	b, err := NotableSightings(ctx, &q)
 	// Error handling
 	if err != nil {
-		fmt.Println(err)
-		return err.Error()
+		return "", nil, err
 	}
 
-	// In order to remove birds found at the same location/date, we create a map to combine these entries
-	dupeMap := make(map[string]int)
-
 	// Formatting return string
-	rString := ""
 	if len(b) == 0 {
-		rString = "**No notable eBird sightings found.**"
-	} else {
-		rString = fmt.Sprintf("**Notable eBird sightings within %d km of %v in the past 2 weeks:**\n", radius, loc.name)
-		for i := 0; i < len(b); i++ {
-			if b[i].HowMany > 0 {
-				strings := strings.Split(b[i].ObsDt, " ")
-				b[i].ObsDt = strings[0] //Removing the hours/minutes from observation
-
-				// Combining observations with same date and location
-				key := (b[i].ComName + "|" + b[i].LocName + "|" + b[i].ObsDt + "|")
-				if value, ok := dupeMap[key]; ok {
-					dupeMap[key] = (value + b[i].HowMany)
-				} else {
-					dupeMap[key] = b[i].HowMany
-				}
+		return tr(q.Locale, "**No notable eBird sightings found.**"), nil, nil
+	}
+
+	// In order to remove birds found at the same location/date, we create a map to combine these entries
+	dupeMap := make(map[string]*BirdSighting)
+	var combined []BirdSighting
+	for i := 0; i < len(b); i++ {
+		if b[i].HowMany > 0 {
+			strings := strings.Split(b[i].ObsDt, " ")
+			b[i].ObsDt = strings[0] //Removing the hours/minutes from observation
+
+			// Combining observations with same date and location
+			key := (b[i].ComName + "|" + b[i].LocName + "|" + b[i].ObsDt + "|")
+			if value, ok := dupeMap[key]; ok {
+				value.HowMany += b[i].HowMany
+			} else {
+				dupeMap[key] = &b[i]
 			}
 		}
+	}
+	for _, v := range dupeMap {
+		combined = append(combined, *v)
+	}
 
-		// Reformatting each line after duplicate removal and adding it to an array
-		var a []string
-		for k, v := range dupeMap {
-			t := strings.Split(k, "|")
-			a = append(a, fmt.Sprintf("%v: %d [%s: %s]\n ", t[0], v, t[1], t[2]))
-		}
-		// Sorting list of birds alphabetically, depending on whether reverseSort is true or false
-		if reverseSort {
-			sort.Slice(a, func(i, j int) bool {
-				return a[i] > a[j]
-			})
-		} else {
-			sort.Slice(a, func(i, j int) bool {
-				return a[i] < a[j]
-			})
+	// Numbering places in the order they are listed, so the list doubles as the map's legend
+	var points []MapPoint
+	numbers := make(map[string]int)
+	header := fmt.Sprintf("**%s:**\n", tr(q.Locale, "Notable eBird sightings %s in the past %s", q.where(), pluralizeIn(q.Locale, q.Days, "day")))
+	rString, err := formatSightings(ctx, header, combined, q, func(s BirdSighting) string {
+		n, ok := numbers[s.LocName]
+		if !ok {
+			n = len(points) + 1
+			numbers[s.LocName] = n
+			points = append(points, MapPoint{Number: n, Lat: s.Lat, Lng: s.Lng})
 		}
+		return fmt.Sprintf("%v: %d [#%d %s: %s]\n ", s.ComName, s.HowMany, n, s.LocName, s.ObsDt)
+	})
+	// Error handling
+	if err != nil {
+		return "", nil, err
+	}
+	return rString, points, nil
+}
 
-		// Adding to return string from array
-		for i := 0; i < len(a); i++ {
-			rString += a[i]
-		}
+// NotableSightings returns the notable observations in the query's days as eBird lists them, filling in q.RegionName
+// for region queries.
+func NotableSightings(ctx context.Context, q *ObsQuery) ([]BirdSighting, error) {
+	loc := q.Location
 
-		//Trimming return string to Discord max message length, minus 5 characters to show max length has been reached
-		rString = truncateText(rString, 1995)
+	// Creating URL
+	url := fmt.Sprintf("%s/data/obs/geo/recent/notable?lat=%v&lng=%v&dist=%d&back=%d&sort=species&hotspot=true", Conf().EBirdURL, loc.lat, loc.long, q.Radius, q.Days)
+	if q.Mode == modeHotspot {
+		url = fmt.Sprintf("%s/data/obs/%s/recent/notable?back=%d", Conf().EBirdURL, loc.code, q.Days)
 	}
+	if q.Region != "" {
+		url = fmt.Sprintf("%s/data/obs/%s/recent/notable?back=%d", Conf().EBirdURL, q.Region, q.Days)
+		q.RegionName = regionNameOrCode(ctx, q.Region)
+	}
+	url += sppLocaleParam(q.Locale)
 
-	return rString
+	// Creates an array of BirdSighting and fills it from the eBird response
+	var b []BirdSighting
+	err := ebirdGet(ctx, url, &b)
+	// Error handling
+	if err != nil {
+		return nil, fmt.Errorf("getting notable observations %s: %w", q.where(), err)
+	}
+	return b, nil
 }
 
 // scrapeEmbedInfo attempts to gather information about a bird from AllAboutBirds.org.
 // formattedName is a string created by messageHandler() that is given to DisplayBird() to be added to the end of the URL
-func scrapeEmbedInfo(formattedName string) EmbedInfo {
+func scrapeEmbedInfo(ctx context.Context, formattedName string) (EmbedInfo, error) {
 
 	var embed EmbedInfo
 
 	// Resolving URL
 	embed.URL = fmt.Sprintf("https://www.allaboutbirds.org/guide/%s", formattedName)
 
+	// Waiting for FlaminGo's turn, since every !bird shares one limit for AllAboutBirds
+	err := allAboutBirdsUpstream.wait(ctx)
+	// Error handling
+	if err != nil {
+		return embed, err
+	}
+
 	c := colly.NewCollector(
 		colly.AllowedDomains("www.allaboutbirds.org"),
 	)
+	// colly has no context of its own, so its requests are given ctx by the transport
+	c.WithTransport(contextTransport{ctx: ctx, base: http.DefaultTransport})
+
+	// Keeping the status and headers, so a 429 can back off every !bird
+	status, header := 0, http.Header{}
+	c.OnResponse(func(r *colly.Response) {
+		status = r.StatusCode
+	})
+	c.OnError(func(r *colly.Response, _ error) {
+		status = r.StatusCode
+		if r.Headers != nil {
+			header = *r.Headers
+		}
+	})
 
 	// Since putting in a nonexistent bird returns a search page and doesn't give an error, this acts as a form of
 	// error checking by identifying an element unique to the AllAboutBirds search page.
@@ -281,6 +408,9 @@ func scrapeEmbedInfo(formattedName string) EmbedInfo {
 		// Grabbing order and family information
 		e.ForEach("li", func(_ int, ch *colly.HTMLElement) {
 			strings := strings.Split(ch.Text, " ")
+			if len(strings) < 2 {
+				return
+			}
 			switch strings[0] {
 			case "ORDER:":
 				embed.Order = strings[1]
@@ -343,10 +473,7 @@ func scrapeEmbedInfo(formattedName string) EmbedInfo {
 		e.ForEachWithBreak("img", func(_ int, ch *colly.HTMLElement) bool {
 			if embed.ImageURL == "" {
 				// Janky, but it works until I figure out a solution for Attr("src") not working
-				interchange := ch.Attr("data-interchange")
-				images := strings.Split(interchange, "[")
-				image := strings.Split(images[3], ",")
-				embed.ImageURL = image[0]
+				embed.ImageURL = interchangeImage(ch.Attr("data-interchange"))
 				return false
 			} else {
 				return false
@@ -355,82 +482,119 @@ func scrapeEmbedInfo(formattedName string) EmbedInfo {
 	})
 
 	// Visits the URL, beginning the search for applicable HTML elements.
-	c.Visit(embed.URL)
+	err = c.Visit(embed.URL)
+	// A 429 means AllAboutBirds wants every !bird to slow down
+	if status != 0 {
+		busyErr := allAboutBirdsUpstream.checkResponse(status, header)
+		if busyErr != nil {
+			return embed, busyErr
+		}
+	}
+	// Error handling
+	if err != nil {
+		return embed, fmt.Errorf("scraping %s: %w", embed.URL, err)
+	}
 
-	return embed
+	return embed, nil
+}
+
+// contextTransport sends every request with ctx, so that cancelling ctx cancels requests made by libraries
+// such as colly that do not take a context.
+type contextTransport struct {
+	ctx  context.Context
+	base http.RoundTripper
+}
+
+func (t contextTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return t.base.RoundTrip(req.WithContext(t.ctx))
 }
 
 // DisplayBird() creates and returns a Discord embed containing information about the bird.
 // formattedName is a URL compatible string that is fed to scrapeEmbedInfo
-func DisplayBird(formattedName string) *discordgo.MessageEmbed {
-	embed := scrapeEmbedInfo(formattedName)
+func DisplayBird(ctx context.Context, formattedName, locale string) (*discordgo.MessageEmbed, error) {
+	embed, err := scrapeEmbedInfo(ctx, formattedName)
+	// Error handling
+	if err != nil {
+		return nil, err
+	}
+	return BirdEmbed(embed, locale), nil
+}
 
+// BirdEmbed turns the information scraped for a bird into its embed. The not found message is in locale, the rest
+// comes from AllAboutBirds in English.
+// Sections missing from the page are left out, since Discord rejects empty embed fields.
+func BirdEmbed(embed EmbedInfo, locale string) *discordgo.MessageEmbed {
 	// If the URL does not return a bird, the bot will return this error embed.
 	if embed.Name == "Bird not found!" {
 		return &discordgo.MessageEmbed{
-			Color:       16711833, // Pink
-			Title:       "Bird not found!",
-			Description: "Make sure you spelled it right and have the name properly punctuated. Also make sure you have the full name (e.g. \"American Robin\" instead of just \"Robin\"). Birds outside of North America are unavailable.",
+			Color:       defaultEmbedColor,
+			Title:       tr(locale, "Bird not found!"),
+			Description: tr(locale, "Make sure you spelled it right and have the name properly punctuated. Also make sure you have the full name (e.g. \"American Robin\" instead of just \"Robin\"). Birds outside of North America are unavailable."),
 		}
-	} else {
-		// from: https://github.com/bwmarrin/discordgo/wiki/FAQ#sending-embeds
-		return &discordgo.MessageEmbed{
-			Color:       16711833,
-			Description: embed.ScientificName,
-			Fields: []*discordgo.MessageEmbedField{
-				{
-					Name:   "Order",
-					Value:  embed.Order,
-					Inline: false,
-				},
-				{
-					Name:   "Family",
-					Value:  embed.Family,
-					Inline: false,
-				},
-				{
-					Name:   "Habitat",
-					Value:  embed.Habitat,
-					Inline: false,
-				},
-				{
-					Name:   "Food",
-					Value:  embed.Food,
-					Inline: false,
-				},
-				{
-					Name:   "Nesting",
-					Value:  embed.Nesting,
-					Inline: false,
-				},
-				{
-					Name:   "Behavior",
-					Value:  embed.Behavior,
-					Inline: false,
-				},
-				{
-					Name:   "Description",
-					Value:  embed.Description,
-					Inline: false,
-				},
-				{
-					Name:   "Cool Fact",
-					Value:  embed.Facts[rand.Intn(len(embed.Facts))],
-					Inline: false,
-				},
-			},
-			Image: &discordgo.MessageEmbedImage{
-				URL: embed.ImageURL,
-			},
-			URL:   embed.URL,
-			Title: embed.Name,
+	}
+
+	// A random fact, if the page had any
+	fact := ""
+	if len(embed.Facts) > 0 {
+		fact = embed.Facts[rand.Intn(len(embed.Facts))]
+	}
+
+	sections := []struct{ name, value string }{
+		{"Order", embed.Order},
+		{"Family", embed.Family},
+		{"Habitat", embed.Habitat},
+		{"Food", embed.Food},
+		{"Nesting", embed.Nesting},
+		{"Behavior", embed.Behavior},
+		{"Description", embed.Description},
+		{"Cool Fact", fact},
+	}
+	var fields []*discordgo.MessageEmbedField
+	for _, section := range sections {
+		if section.value == "" {
+			continue
 		}
+		fields = append(fields, &discordgo.MessageEmbedField{
+			Name:   section.name,
+			Value:  section.value,
+			Inline: false,
+		})
 	}
 
+	// from: https://github.com/bwmarrin/discordgo/wiki/FAQ#sending-embeds
+	bird := &discordgo.MessageEmbed{
+		Color:       defaultEmbedColor,
+		Description: embed.ScientificName,
+		Fields:      fields,
+		URL:         embed.URL,
+		Title:       embed.Name,
+	}
+	if embed.ImageURL != "" {
+		bird.Image = &discordgo.MessageEmbedImage{
+			URL: embed.ImageURL,
+		}
+	}
+	return bird
+}
+
+// interchangeImage returns the image URL to use from an AllAboutBirds data-interchange attribute, which lists
+// sizes of the same image as "[url, size], [url, size], ...". The third size is used when there is one, since
+// it is large enough for an embed, and otherwise the largest one. It returns "" if the attribute lists no images.
+func interchangeImage(interchange string) string {
+	images := strings.Split(interchange, "[")
+	if len(images) < 2 {
+		return ""
+	}
+	i := len(images) - 1
+	if i > 3 {
+		i = 3
+	}
+	image := strings.Split(images[i], ",")
+	return strings.TrimSpace(image[0])
 }
 
-// GenerateBird returns a randomly generated bird name from adjectives and a noun. User can specify 0-3 adjectives, otherwise it is randomly chosen.
-func GenerateBird(adjectives int) string {
+// GenerateBird returns a randomly generated bird name from adjectives and a noun in words. User can specify 0-3 adjectives, otherwise it is randomly chosen.
+func GenerateBird(words WordLists, adjectives int) string {
 	rand.Seed(time.Now().UnixNano())
 
 	if adjectives >= 4 || adjectives < 0 {
@@ -448,11 +612,11 @@ func GenerateBird(adjectives int) string {
 	}
 
 	//Generating noun
-	bird := Nouns[rand.Intn(len(Nouns))]
+	bird := words.Nouns[rand.Intn(len(words.Nouns))]
 
 	//Adding adjectives
 	for i := 0; i < adjectives; i++ {
-		bird = Adjectives[rand.Intn(len(Adjectives))] + " " + bird
+		bird = words.Adjectives[rand.Intn(len(words.Adjectives))] + " " + bird
 	}
 
 	//Capitalizing words
@@ -0,0 +1,80 @@
+// Concurrency contains helpers for running several upstream requests at once, and the pool commands run on
+
+package main
+
+import "sync"
+
+// runLimited calls fn for every index from 0 to n-1, with at most limit calls running at once.
+// It waits for every call to finish and returns the error of the lowest failing index, if any.
+func runLimited(n, limit int, fn func(i int) error) error {
+	errs := make([]error, n)
+	sem := make(chan struct{}, limit)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			errs[i] = fn(i)
+		}(i)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// workerPool runs jobs on a fixed number of goroutines, queueing the jobs submitted while they are all busy.
+type workerPool struct {
+	jobs chan func()
+	// size is how many goroutines take jobs, fixed when the pool starts.
+	size int
+	// running counts the jobs submitted and not finished yet, for wait.
+	running sync.WaitGroup
+}
+
+// newWorkerPool starts workers goroutines taking jobs from a queue of up to queue jobs.
+func newWorkerPool(workers, queue int) *workerPool {
+	p := &workerPool{jobs: make(chan func(), queue), size: workers}
+	for i := 0; i < workers; i++ {
+		go func() {
+			for job := range p.jobs {
+				job()
+				p.running.Done()
+			}
+		}()
+	}
+	return p
+}
+
+// submit queues job to run on the pool. It returns false without queueing it if the queue is full.
+func (p *workerPool) submit(job func()) bool {
+	p.running.Add(1)
+	select {
+	case p.jobs <- job:
+		return true
+	default:
+		p.running.Done()
+		return false
+	}
+}
+
+// queued returns how many jobs are waiting for a free worker.
+func (p *workerPool) queued() int {
+	return len(p.jobs)
+}
+
+// workers returns how many jobs the pool runs at once.
+func (p *workerPool) workers() int {
+	return p.size
+}
+
+// wait blocks until every submitted job has finished.
+func (p *workerPool) wait() {
+	p.running.Wait()
+}
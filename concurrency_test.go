@@ -0,0 +1,62 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWorkerPoolQueue(t *testing.T) {
+	p := newWorkerPool(1, 1)
+	release := make(chan struct{})
+	started := make(chan struct{})
+
+	// One job runs, one waits in the queue, and the next is turned away
+	if !p.submit(func() { close(started); <-release }) {
+		t.Fatal("first job refused")
+	}
+	<-started
+	ran := false
+	if !p.submit(func() { ran = true }) {
+		t.Fatal("queued job refused")
+	}
+	if p.submit(func() {}) {
+		t.Error("job accepted with a full queue")
+	}
+
+	close(release)
+	p.wait()
+	if !ran {
+		t.Error("queued job did not run")
+	}
+}
+
+func TestCommandTimeout(t *testing.T) {
+	f := newFakeEBird(t)
+	useTempStore(t)
+	changeConf(t, func(c *Config) { c.CommandTimeout = 1 })
+	f.handle("/data/obs/geo/recent", func(w http.ResponseWriter, r *http.Request) {
+		// eBird hangs until FlaminGo gives up
+		select {
+		case <-r.Context().Done():
+		case <-time.After(10 * time.Second):
+		}
+	})
+
+	s := &fakeSession{}
+	start := time.Now()
+	handleMessage(s, newMessage("!get rit"))
+	commandPool.wait()
+	if d := time.Since(start); d > 5*time.Second {
+		t.Errorf("command took %s", d)
+	}
+
+	sent := s.messages()
+	if len(sent) != 1 || !strings.HasPrefix(sent[0].Content, "That took longer than 1 second, so FlaminGo gave up.") {
+		t.Errorf("unexpected reply %+v", sent)
+	}
+	if s.typing == 0 {
+		t.Error("no typing indicator was sent")
+	}
+}
@@ -1,25 +1,112 @@
-// Conf defines and initalizes several variables used throughout the program
+// Conf defines and loads FlaminGo's configuration, and the locations used throughout the program
 
 package main
 
 import (
-	"os"
-
+	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	// Time zones load even where the system has no time zone database
+	_ "time/tzdata"
 
 	"github.com/joho/godotenv"
 )
 
-// The bot is currently being hosted via Heroku. To run it locally, change this to false.
-const herokuUsed bool = true
+// Config holds FlaminGo's effective configuration.
+// Values are layered, with each source overriding the previous one:
+// defaults, the optional JSON config file, the .env file, environment variables, and finally command-line flags.
+type Config struct {
+	// Token is the bot's Discord token.
+	Token string `json:"token"`
+	// Key is the eBird API key.
+	Key string `json:"ebird_key"`
+	// Radius is the default number of kilometers to search around a location, for use in GetRecentObservations.
+	Radius int `json:"radius"`
+	// RareRadius is the default number of kilometers to search around a location, for use in GetRareObservations.
+	// It is larger than Radius due to the low amount of rare sightings.
+	RareRadius int `json:"rare_radius"`
+	// BackDays is the default number of days back to look for observations.
+	BackDays int `json:"back_days"`
+	// LocationsFile is an optional JSON file replacing the built-in locations.
+	LocationsFile string `json:"locations_file"`
+	// DataDir is the directory FlaminGo keeps its persistent data in.
+	DataDir string `json:"data_dir"`
+	// LogLevel is one of debug, info, warn or error.
+	LogLevel string `json:"log_level"`
+	// MapBasemap is an optional GeoJSON file whose lines and polygons are drawn under sighting maps.
+	MapBasemap string `json:"map_basemap"`
+	// GeneratorFile is an optional .csv file replacing the built-in bird generator lists. It is reloaded when it changes.
+	GeneratorFile string `json:"generator_file"`
+	// TaxonomyFile is an optional JSON snapshot of the eBird taxonomy, used instead of downloading it.
+	TaxonomyFile string `json:"taxonomy_file"`
+	// TimeZone is the IANA time zone trips are planned in unless a guild picks its own, e.g. America/New_York.
+	// Empty means the system's time zone.
+	TimeZone string `json:"time_zone"`
+	// UserRate and ChannelRate are how many commands a minute each user and each channel may send. 0 turns them off.
+	UserRate    int `json:"user_rate"`
+	ChannelRate int `json:"channel_rate"`
+	// EBirdRate is how many eBird API requests a second FlaminGo sends at most, across every command. 0 turns it off.
+	EBirdRate int `json:"ebird_rate"`
+	// Workers is how many commands run at once. Commands sent while they are all busy wait in a queue.
+	Workers int `json:"workers"`
+	// CommandTimeout is how many seconds a command may take before FlaminGo gives up on it.
+	CommandTimeout int `json:"command_timeout"`
+	// Owners are the Discord user IDs allowed to use !admin.
+	Owners []string `json:"owners"`
+	// CrashChannel is an optional Discord channel ID that panics and failed commands are reported to.
+	CrashChannel string `json:"crash_channel"`
+	// EBirdURL is the base URL of eBird's API, without a trailing slash. Tests point it at a fake server.
+	EBirdURL string `json:"ebird_url"`
+	// EnvFile is the .env file loaded into the environment before environment variables are read. It may be missing.
+	EnvFile string `json:"-"`
+	// ConfigFile is the JSON config file that was loaded, if any.
+	ConfigFile string `json:"-"`
+}
 
 var (
-	Token string
-	Key   string
+	// confMu guards conf and locations, which reloadConfig replaces while commands read them.
+	confMu sync.RWMutex
+	// conf is the configuration FlaminGo is running with. It is set by loadConfig, and replaced by reloadConfig.
+	conf = defaultConfig()
+	// locations maps the lowercased names users type in commands (e.g. "rit") to their locations.
+	locations map[string]Location
+)
 
-	// KM is the number of kilometers around a location to search, for use in GetRecentObservations.
-	KM int
+// Conf returns the configuration FlaminGo is running with.
+func Conf() Config {
+	confMu.RLock()
+	defer confMu.RUnlock()
+	return conf
+}
+
+// setConf replaces the configuration FlaminGo is running with.
+func setConf(c Config) {
+	confMu.Lock()
+	conf = c
+	confMu.Unlock()
+}
 
+// Locations returns the locations users can name in commands, keyed by their lowercased names. The map is replaced,
+// never changed, so callers may keep it but must not change it.
+func Locations() map[string]Location {
+	confMu.RLock()
+	defer confMu.RUnlock()
+	return locations
+}
+
+// configArgs are the command-line arguments loadConfig was given, for reloadConfig.
+var configArgs []string
+
+var (
 	//RIT is a location representing Rochester Institute of Technology in eBird's API.
 	RIT Location
 	//Braddock is a location representing Braddock Bay in eBird's API.
@@ -38,30 +125,63 @@ type Location struct {
 	long float64
 	// Name stores the actual name of the location, for use in printing strings.
 	name string
+	// Mode chooses how observations are queried, either modeGeo (the default) or modeHotspot.
+	mode string
 }
 
-func init() {
+// Query modes for a Location.
+const (
+	// modeGeo searches a circle around the location's lat/long, which includes nearby places.
+	modeGeo = "geo"
+	// modeHotspot only returns sightings reported at the location's eBird code.
+	modeHotspot = "hotspot"
+)
 
-	// Since Heroku uses its own config vars, this section will cause an error if not being run locally.
-	// To fix this, we put it in a conditional statement that checks to see if herokuUsed is false.
-	if !herokuUsed {
-		// Load .env file
-		err := godotenv.Load("config.env")
-		if err != nil {
-			fmt.Println(err.Error())
-			return
-		}
-	}
+// locationEntry is the JSON form of a Location in the locations file.
+type locationEntry struct {
+	Key  string  `json:"key"`
+	Code string  `json:"code"`
+	Lat  float64 `json:"lat"`
+	Long float64 `json:"long"`
+	Name string  `json:"name"`
+	Mode string  `json:"mode,omitempty"`
+}
 
-	// Token stores the bot's Discord token
-	Token = os.Getenv("FLAMINGO_TOK")
+// location converts a locations file entry to a Location.
+func (e locationEntry) location() Location {
+	return Location{code: e.Code, lat: e.Lat, long: e.Long, name: e.Name, mode: e.Mode}
+}
 
-	// Key stores the eBird API key
-	Key = os.Getenv("EBIRD_KEY")
+// entry converts a Location to its JSON form, saved under key.
+func (l Location) entry(key string) locationEntry {
+	return locationEntry{Key: key, Code: l.code, Lat: l.lat, Long: l.long, Name: l.name, Mode: l.mode}
+}
 
-	// Number of kilometers to search around a location
-	KM = 5
+// envVars maps each environment variable FlaminGo reads to the function applying it to a Config.
+var envVars = map[string]func(c *Config, v string) error{
+	"FLAMINGO_TOK":             func(c *Config, v string) error { c.Token = v; return nil },
+	"EBIRD_KEY":                func(c *Config, v string) error { c.Key = v; return nil },
+	"FLAMINGO_RADIUS":          func(c *Config, v string) error { return setInt(&c.Radius, v) },
+	"FLAMINGO_RARE_RADIUS":     func(c *Config, v string) error { return setInt(&c.RareRadius, v) },
+	"FLAMINGO_BACK_DAYS":       func(c *Config, v string) error { return setInt(&c.BackDays, v) },
+	"FLAMINGO_LOCATIONS_FILE":  func(c *Config, v string) error { c.LocationsFile = v; return nil },
+	"FLAMINGO_DATA_DIR":        func(c *Config, v string) error { c.DataDir = v; return nil },
+	"FLAMINGO_LOG_LEVEL":       func(c *Config, v string) error { c.LogLevel = v; return nil },
+	"FLAMINGO_EBIRD_URL":       func(c *Config, v string) error { c.EBirdURL = v; return nil },
+	"FLAMINGO_MAP_BASEMAP":     func(c *Config, v string) error { c.MapBasemap = v; return nil },
+	"FLAMINGO_GENERATOR_FILE":  func(c *Config, v string) error { c.GeneratorFile = v; return nil },
+	"FLAMINGO_TAXONOMY_FILE":   func(c *Config, v string) error { c.TaxonomyFile = v; return nil },
+	"FLAMINGO_TIME_ZONE":       func(c *Config, v string) error { c.TimeZone = v; return nil },
+	"FLAMINGO_USER_RATE":       func(c *Config, v string) error { return setInt(&c.UserRate, v) },
+	"FLAMINGO_CHANNEL_RATE":    func(c *Config, v string) error { return setInt(&c.ChannelRate, v) },
+	"FLAMINGO_EBIRD_RATE":      func(c *Config, v string) error { return setInt(&c.EBirdRate, v) },
+	"FLAMINGO_WORKERS":         func(c *Config, v string) error { return setInt(&c.Workers, v) },
+	"FLAMINGO_COMMAND_TIMEOUT": func(c *Config, v string) error { return setInt(&c.CommandTimeout, v) },
+	"FLAMINGO_CRASH_CHANNEL":   func(c *Config, v string) error { c.CrashChannel = v; return nil },
+	"FLAMINGO_OWNERS":          func(c *Config, v string) error { c.Owners = splitList(v); return nil },
+}
 
+func init() {
 	// Create RIT Location
 	RIT.code = "L976278" //eBird location code
 	RIT.lat = 43.08
@@ -80,4 +200,446 @@ func init() {
 	Mendon.long = -77.57
 	Mendon.name = "Mendon Ponds Park"
 
+	locations = builtinLocations()
+}
+
+// builtinLocations returns the locations used when no locations file is given.
+func builtinLocations() map[string]Location {
+	return map[string]Location{
+		"rit":      RIT,
+		"braddock": Braddock,
+		"mendon":   Mendon,
+	}
+}
+
+// defaultConfig returns the configuration used when nothing else is specified.
+func defaultConfig() Config {
+	return Config{
+		Radius:         5,
+		RareRadius:     15,
+		BackDays:       14,
+		DataDir:        "data",
+		LogLevel:       "info",
+		UserRate:       6,
+		ChannelRate:    20,
+		EBirdRate:      5,
+		Workers:        8,
+		CommandTimeout: 30,
+		EBirdURL:       "https://api.ebird.org/v2",
+		EnvFile:        "config.env",
+	}
+}
+
+// loadConfig builds the configuration from every source, validates it, and sets the configuration, the log level and the locations.
+// args are the command-line arguments without the program name.
+func loadConfig(args []string) error {
+	c, err := buildConfig(args, os.LookupEnv)
+	// Error handling
+	if err != nil {
+		return err
+	}
+
+	err = c.validate()
+	// Error handling
+	if err != nil {
+		return err
+	}
+
+	// Creating the data directory so commands can rely on it existing
+	err = os.MkdirAll(c.DataDir, 0o755)
+	// Error handling
+	if err != nil {
+		return fmt.Errorf("creating data dir: %w", err)
+	}
+
+	// Loading the locations and basemap
+	err = loadConfigFiles(c)
+	// Error handling
+	if err != nil {
+		return err
+	}
+
+	// Opening the per-guild store
+	st, err := openStore(filepath.Join(c.DataDir, "guilds.json"))
+	// Error handling
+	if err != nil {
+		return err
+	}
+	store = st
+
+	setConf(c)
+	configArgs = args
+	setupLogger(c.LogLevel)
+	logger.Info("effective configuration", slog.Any("config", c))
+
+	return nil
+}
+
+// reloadConfig builds the configuration again from the same sources as loadConfig, and applies it along with the
+// locations file and basemap it names. The Discord token, the data directory and the number of workers are kept,
+// since changing them needs a restart.
+func reloadConfig() error {
+	c, err := buildConfig(configArgs, os.LookupEnv)
+	// Error handling
+	if err != nil {
+		return err
+	}
+
+	err = c.validate()
+	// Error handling
+	if err != nil {
+		return err
+	}
+
+	// Nothing changes unless every file loads
+	err = loadConfigFiles(c)
+	// Error handling
+	if err != nil {
+		return err
+	}
+
+	old := Conf()
+	if c.Token != old.Token || c.DataDir != old.DataDir || c.Workers != old.Workers {
+		logger.Warn("the Discord token, data dir and workers only change on restart")
+	}
+	c.Token, c.DataDir, c.Workers = old.Token, old.DataDir, old.Workers
+	if c.LogLevel != old.LogLevel {
+		setupLogger(c.LogLevel)
+	}
+	setConf(c)
+	logger.Info("reloaded configuration", slog.Any("config", c))
+
+	return nil
+}
+
+// loadConfigFiles replaces the locations, the sighting map basemap and the taxonomy snapshot with the files named in c.
+// Without a locations file, the built-in locations are used.
+func loadConfigFiles(c Config) error {
+	locs := builtinLocations()
+	if c.LocationsFile != "" {
+		var err error
+		locs, err = loadLocations(c.LocationsFile)
+		// Error handling
+		if err != nil {
+			return err
+		}
+	}
+
+	var bm *Basemap
+	if c.MapBasemap != "" {
+		var err error
+		bm, err = loadBasemap(c.MapBasemap)
+		// Error handling
+		if err != nil {
+			return err
+		}
+	}
+
+	snapshot, err := loadTaxonomySnapshot(c.TaxonomyFile)
+	// Error handling
+	if err != nil {
+		return err
+	}
+
+	confMu.Lock()
+	locations = locs
+	confMu.Unlock()
+	basemapMu.Lock()
+	basemap = bm
+	basemapMu.Unlock()
+	useTaxonomySnapshot(snapshot)
+	return nil
+}
+
+// buildConfig layers defaults, the config file, the .env file, environment variables and flags into a Config.
+// lookupEnv is os.LookupEnv outside of tests.
+func buildConfig(args []string, lookupEnv func(string) (string, bool)) (Config, error) {
+	c := defaultConfig()
+
+	// Flags are parsed first so that -config and -env-file can choose the files to load,
+	// but they are only applied after every other source.
+	fl := flag.NewFlagSet("flamingo", flag.ContinueOnError)
+	flagConfig := defaultConfig()
+	fl.StringVar(&flagConfig.ConfigFile, "config", "", "JSON config file to load")
+	fl.StringVar(&flagConfig.EnvFile, "env-file", flagConfig.EnvFile, ".env file to load into the environment")
+	fl.StringVar(&flagConfig.Token, "token", "", "Discord bot token")
+	fl.StringVar(&flagConfig.Key, "key", "", "eBird API key")
+	fl.IntVar(&flagConfig.Radius, "radius", flagConfig.Radius, "default search radius in km for !get")
+	fl.IntVar(&flagConfig.RareRadius, "rare-radius", flagConfig.RareRadius, "default search radius in km for !rare")
+	fl.IntVar(&flagConfig.BackDays, "back", flagConfig.BackDays, "default number of days back to search (1-30)")
+	fl.StringVar(&flagConfig.LocationsFile, "locations", "", "JSON file replacing the built-in locations")
+	fl.StringVar(&flagConfig.DataDir, "data-dir", flagConfig.DataDir, "directory for persistent data")
+	fl.StringVar(&flagConfig.LogLevel, "log-level", flagConfig.LogLevel, "log level (debug, info, warn, error)")
+	fl.StringVar(&flagConfig.EBirdURL, "ebird-url", flagConfig.EBirdURL, "base URL of the eBird API")
+	fl.StringVar(&flagConfig.MapBasemap, "map-basemap", "", "GeoJSON file drawn under sighting maps")
+	fl.StringVar(&flagConfig.GeneratorFile, "generator-file", "", ".csv file replacing the built-in bird generator lists")
+	fl.StringVar(&flagConfig.TaxonomyFile, "taxonomy-file", "", "JSON eBird taxonomy snapshot used instead of downloading it")
+	fl.StringVar(&flagConfig.TimeZone, "time-zone", "", "IANA time zone trips are planned in, e.g. America/New_York")
+	fl.IntVar(&flagConfig.UserRate, "user-rate", flagConfig.UserRate, "commands a minute each user may send (0 for no limit)")
+	fl.IntVar(&flagConfig.ChannelRate, "channel-rate", flagConfig.ChannelRate, "commands a minute each channel may send (0 for no limit)")
+	fl.IntVar(&flagConfig.EBirdRate, "ebird-rate", flagConfig.EBirdRate, "eBird API requests a second across every command (0 for no limit)")
+	fl.IntVar(&flagConfig.Workers, "workers", flagConfig.Workers, "number of commands run at once")
+	fl.IntVar(&flagConfig.CommandTimeout, "command-timeout", flagConfig.CommandTimeout, "seconds a command may take before giving up")
+	fl.StringVar(&flagConfig.CrashChannel, "crash-channel", "", "Discord channel ID to report crashes and failed commands to")
+	owners := fl.String("owners", "", "comma-separated Discord user IDs allowed to use !admin")
+	err := fl.Parse(args)
+	// Error handling
+	if err != nil {
+		return c, err
+	}
+	setFlags := make(map[string]bool)
+	fl.Visit(func(f *flag.Flag) {
+		setFlags[f.Name] = true
+	})
+
+	// Config file, chosen by flag or environment variable
+	c.ConfigFile, _ = lookupEnv("FLAMINGO_CONFIG")
+	if setFlags["config"] {
+		c.ConfigFile = flagConfig.ConfigFile
+	}
+	if c.ConfigFile != "" {
+		data, err := os.ReadFile(c.ConfigFile)
+		// Error handling
+		if err != nil {
+			return c, fmt.Errorf("reading config file: %w", err)
+		}
+		err = json.Unmarshal(data, &c)
+		// Error handling
+		if err != nil {
+			return c, fmt.Errorf("parsing config file %s: %w", c.ConfigFile, err)
+		}
+	}
+
+	// .env file. Heroku uses its own config vars, so a missing file is not an error.
+	if setFlags["env-file"] {
+		c.EnvFile = flagConfig.EnvFile
+	}
+	var dotEnv map[string]string
+	if c.EnvFile != "" {
+		dotEnv, err = godotenv.Read(c.EnvFile)
+		// Error handling
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return c, fmt.Errorf("loading %s: %w", c.EnvFile, err)
+		}
+	}
+
+	// .env values, then real environment variables, which take precedence
+	for name, apply := range envVars {
+		v, ok := lookupEnv(name)
+		if !ok {
+			v, ok = dotEnv[name]
+		}
+		if !ok {
+			continue
+		}
+		err := apply(&c, v)
+		// Error handling
+		if err != nil {
+			return c, fmt.Errorf("%s: %w", name, err)
+		}
+	}
+
+	// Command-line flags
+	fl.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "token":
+			c.Token = flagConfig.Token
+		case "key":
+			c.Key = flagConfig.Key
+		case "radius":
+			c.Radius = flagConfig.Radius
+		case "rare-radius":
+			c.RareRadius = flagConfig.RareRadius
+		case "back":
+			c.BackDays = flagConfig.BackDays
+		case "locations":
+			c.LocationsFile = flagConfig.LocationsFile
+		case "data-dir":
+			c.DataDir = flagConfig.DataDir
+		case "log-level":
+			c.LogLevel = flagConfig.LogLevel
+		case "ebird-url":
+			c.EBirdURL = flagConfig.EBirdURL
+		case "map-basemap":
+			c.MapBasemap = flagConfig.MapBasemap
+		case "generator-file":
+			c.GeneratorFile = flagConfig.GeneratorFile
+		case "taxonomy-file":
+			c.TaxonomyFile = flagConfig.TaxonomyFile
+		case "time-zone":
+			c.TimeZone = flagConfig.TimeZone
+		case "user-rate":
+			c.UserRate = flagConfig.UserRate
+		case "channel-rate":
+			c.ChannelRate = flagConfig.ChannelRate
+		case "ebird-rate":
+			c.EBirdRate = flagConfig.EBirdRate
+		case "workers":
+			c.Workers = flagConfig.Workers
+		case "command-timeout":
+			c.CommandTimeout = flagConfig.CommandTimeout
+		case "crash-channel":
+			c.CrashChannel = flagConfig.CrashChannel
+		case "owners":
+			c.Owners = splitList(*owners)
+		}
+	})
+
+	// Trailing slashes are dropped from the eBird URL so paths can be appended directly
+	c.EBirdURL = strings.TrimRight(c.EBirdURL, "/")
+
+	return c, nil
+}
+
+// validate checks that the configuration is complete and within the limits of eBird's API.
+func (c Config) validate() error {
+	var errs []error
+	if c.Token == "" {
+		errs = append(errs, errors.New("a Discord token is required (FLAMINGO_TOK or -token)"))
+	}
+	if c.Key == "" {
+		errs = append(errs, errors.New("an eBird API key is required (EBIRD_KEY or -key)"))
+	}
+	// eBird accepts a dist of at most 50km
+	if c.Radius < 1 || c.Radius > 50 {
+		errs = append(errs, fmt.Errorf("radius must be between 1 and 50 km, got %d", c.Radius))
+	}
+	if c.RareRadius < 1 || c.RareRadius > 50 {
+		errs = append(errs, fmt.Errorf("rare radius must be between 1 and 50 km, got %d", c.RareRadius))
+	}
+	// eBird accepts a back of 1 to 30 days
+	if c.BackDays < 1 || c.BackDays > 30 {
+		errs = append(errs, fmt.Errorf("back days must be between 1 and 30, got %d", c.BackDays))
+	}
+	if c.UserRate < 0 || c.ChannelRate < 0 || c.EBirdRate < 0 {
+		errs = append(errs, errors.New("rate limits must not be negative, use 0 to turn one off"))
+	}
+	if c.Workers < 1 {
+		errs = append(errs, fmt.Errorf("workers must be at least 1, got %d", c.Workers))
+	}
+	if c.CommandTimeout < 1 {
+		errs = append(errs, fmt.Errorf("command timeout must be at least 1 second, got %d", c.CommandTimeout))
+	}
+	if !strings.HasPrefix(c.EBirdURL, "http://") && !strings.HasPrefix(c.EBirdURL, "https://") {
+		errs = append(errs, fmt.Errorf("eBird URL must be an http(s) URL, got %q", c.EBirdURL))
+	}
+	if c.DataDir == "" {
+		errs = append(errs, errors.New("data dir must not be empty"))
+	}
+	if _, err := time.LoadLocation(c.TimeZone); err != nil {
+		errs = append(errs, fmt.Errorf("time zone must be an IANA name such as America/New_York, got %q", c.TimeZone))
+	}
+	switch strings.ToLower(c.LogLevel) {
+	case "debug", "info", "warn", "error":
+	default:
+		errs = append(errs, fmt.Errorf("log level must be debug, info, warn or error, got %q", c.LogLevel))
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid configuration: %w", errors.Join(errs...))
+	}
+	return nil
+}
+
+// LogValue lets Config be logged with slog, redacting the token and API key.
+func (c Config) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("token", redact(c.Token)),
+		slog.String("ebird_key", redact(c.Key)),
+		slog.Int("radius", c.Radius),
+		slog.Int("rare_radius", c.RareRadius),
+		slog.Int("back_days", c.BackDays),
+		slog.String("locations_file", c.LocationsFile),
+		slog.String("data_dir", c.DataDir),
+		slog.String("log_level", c.LogLevel),
+		slog.String("ebird_url", c.EBirdURL),
+		slog.String("map_basemap", c.MapBasemap),
+		slog.String("generator_file", c.GeneratorFile),
+		slog.String("taxonomy_file", c.TaxonomyFile),
+		slog.String("time_zone", c.TimeZone),
+		slog.Int("user_rate", c.UserRate),
+		slog.Int("channel_rate", c.ChannelRate),
+		slog.Int("ebird_rate", c.EBirdRate),
+		slog.Int("workers", c.Workers),
+		slog.Int("command_timeout", c.CommandTimeout),
+		slog.String("crash_channel", c.CrashChannel),
+		slog.Any("owners", c.Owners),
+		slog.String("config_file", c.ConfigFile),
+		slog.String("env_file", c.EnvFile),
+	)
+}
+
+// redact hides a secret, only showing whether it is set.
+func redact(secret string) string {
+	if secret == "" {
+		return "(unset)"
+	}
+	return "(redacted)"
+}
+
+// validateMode checks that mode is a valid query mode for a location with the given eBird code.
+func validateMode(mode, code string) error {
+	switch mode {
+	case "", modeGeo:
+		return nil
+	case modeHotspot:
+		if code == "" {
+			return usageErrorf("hotspot mode needs the location's eBird code")
+		}
+		return nil
+	}
+	return usageErrorf("mode must be '%s' or '%s', got '%s'", modeGeo, modeHotspot, mode)
+}
+
+// splitList splits a comma-separated list, dropping spaces and empty items.
+func splitList(v string) []string {
+	var items []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// setInt parses v into dst.
+func setInt(dst *int, v string) error {
+	i, err := strconv.Atoi(strings.TrimSpace(v))
+	// Error handling
+	if err != nil {
+		return fmt.Errorf("%q is not a whole number", v)
+	}
+	*dst = i
+	return nil
+}
+
+// loadLocations reads a JSON array of locations from file, keyed by their lowercased key.
+func loadLocations(file string) (map[string]Location, error) {
+	data, err := os.ReadFile(file)
+	// Error handling
+	if err != nil {
+		return nil, fmt.Errorf("reading locations file: %w", err)
+	}
+
+	var entries []locationEntry
+	err = json.Unmarshal(data, &entries)
+	// Error handling
+	if err != nil {
+		return nil, fmt.Errorf("parsing locations file %s: %w", file, err)
+	}
+
+	locs := make(map[string]Location)
+	for i, e := range entries {
+		key := strings.ToLower(e.Key)
+		if key == "" || e.Name == "" {
+			return nil, fmt.Errorf("locations file %s: entry %d needs a key and a name", file, i+1)
+		}
+		err := validateMode(e.Mode, e.Code)
+		// Error handling
+		if err != nil {
+			return nil, fmt.Errorf("locations file %s: entry %d: %w", file, i+1, err)
+		}
+		locs[key] = e.location()
+	}
+	return locs, nil
 }
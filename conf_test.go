@@ -0,0 +1,81 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBuildConfigLayers(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "flamingo.json")
+	envFile := filepath.Join(dir, "test.env")
+	if err := os.WriteFile(file, []byte(`{"radius": 7, "rare_radius": 20, "back_days": 3, "token": "file-token"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(envFile, []byte("FLAMINGO_RARE_RADIUS=25\nEBIRD_KEY=dotenv-key\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	env := map[string]string{
+		"FLAMINGO_BACK_DAYS": "10",
+		"EBIRD_KEY":          "env-key",
+	}
+	lookup := func(k string) (string, bool) {
+		v, ok := env[k]
+		return v, ok
+	}
+
+	c, err := buildConfig([]string{"-config", file, "-env-file", envFile, "-back", "2", "-ebird-url", "http://localhost:1/"}, lookup)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if c.Radius != 7 {
+		t.Errorf("Radius = %d, want 7 from the config file", c.Radius)
+	}
+	if c.RareRadius != 25 {
+		t.Errorf("RareRadius = %d, want 25 from the .env file", c.RareRadius)
+	}
+	if c.Key != "env-key" {
+		t.Errorf("Key = %q, want env-key from the environment", c.Key)
+	}
+	if c.BackDays != 2 {
+		t.Errorf("BackDays = %d, want 2 from flags", c.BackDays)
+	}
+	if c.Token != "file-token" {
+		t.Errorf("Token = %q, want file-token", c.Token)
+	}
+	if c.EBirdURL != "http://localhost:1" {
+		t.Errorf("EBirdURL = %q, want trailing slash trimmed", c.EBirdURL)
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	c := defaultConfig()
+	c.Radius = 80
+	c.BackDays = 0
+	c.TimeZone = "Mars/Olympus"
+
+	err := c.validate()
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	for _, want := range []string{"Discord token", "eBird API key", "radius must be", "back days", "time zone"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %q", err, want)
+		}
+	}
+}
+
+func TestConfigLogValueRedactsSecrets(t *testing.T) {
+	c := defaultConfig()
+	c.Token = "secret-token"
+	c.Key = "secret-key"
+
+	s := c.LogValue().String()
+	if strings.Contains(s, "secret") {
+		t.Errorf("log value leaks secrets: %s", s)
+	}
+}
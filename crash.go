@@ -0,0 +1,89 @@
+// Crash recovers from panics in command handlers, and reports crashes and failed commands to an admin channel
+
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"regexp"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// crashReportInterval is how long FlaminGo waits before reporting the same kind of problem to the admin channel again.
+const crashReportInterval = time.Hour
+
+// maxStackLength is the most characters of a stack trace put in a crash report, keeping it within Discord's limit.
+const maxStackLength = 1400
+
+// recoverPanic is deferred around command handlers. If the handler panicked, it logs the stack, sends the invocation's
+// friendly error message, and reports the crash to the admin channel, instead of letting the panic stop FlaminGo.
+func (inv *Invocation) recoverPanic(s Sender) {
+	r := recover()
+	if r == nil {
+		return
+	}
+
+	stack := string(debug.Stack())
+	inv.Log.Error("command panicked", slog.Any("panic", r), slog.String("stack", stack))
+	inv.send(s, inv.userError())
+	reportProblem(s, inv, "panic", fmt.Sprint(r), stack)
+}
+
+// crashReports remembers when each kind of problem was last reported, so a command failing over and over
+// only reports it once per crashReportInterval.
+var crashReports = struct {
+	sync.Mutex
+	last map[string]time.Time
+	// skipped counts the problems of each kind that were not reported since the last report.
+	skipped map[string]int
+}{last: make(map[string]time.Time), skipped: make(map[string]int)}
+
+// digits matches the numbers in error messages, such as indexes and lengths, which vary between problems of one kind.
+var digits = regexp.MustCompile(`[0-9]+`)
+
+// reportProblem sends a report of a panic or a failed command to Conf.CrashChannel, if it is set.
+// kind is "panic" or "error", msg is the panic value or error, and stack is included for panics.
+func reportProblem(s Sender, inv *Invocation, kind, msg, stack string) {
+	if Conf().CrashChannel == "" {
+		return
+	}
+
+	// Problems of the same kind in the same command count as one, whatever their numbers
+	key := kind + "|" + inv.Command + "|" + digits.ReplaceAllString(msg, "N")
+	crashReports.Lock()
+	t := now()
+	if last, ok := crashReports.last[key]; ok && t.Sub(last) < crashReportInterval {
+		crashReports.skipped[key]++
+		crashReports.Unlock()
+		return
+	}
+	skipped := crashReports.skipped[key]
+	crashReports.last[key] = t
+	delete(crashReports.skipped, key)
+	crashReports.Unlock()
+
+	report := fmt.Sprintf("**FlaminGo %s** in `!%s`, reference `%s` (guild %s, channel %s, user %s)\n```\n%s\n```",
+		kind, inv.Command, inv.ID, inv.GuildID, inv.ChannelID, inv.UserID, truncateRunes(msg, 300))
+	if skipped > 0 {
+		report += fmt.Sprintf("Happened %s more since the last report.\n", pluralize(skipped, "time"))
+	}
+	if stack != "" {
+		report += fmt.Sprintf("```\n%s\n```", truncateRunes(stack, maxStackLength))
+	}
+
+	_, err := s.ChannelMessageSend(Conf().CrashChannel, report)
+	// Error handling
+	if err != nil {
+		inv.Log.Warn("sending crash report failed", slog.Any("err", err))
+	}
+}
+
+// resetCrashReports forgets every reported problem, e.g. between tests.
+func resetCrashReports() {
+	crashReports.Lock()
+	defer crashReports.Unlock()
+	crashReports.last = make(map[string]time.Time)
+	crashReports.skipped = make(map[string]int)
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// useCrashChannel sends crash reports to the "admin" channel for the duration of the test.
+func useCrashChannel(t *testing.T) {
+	t.Helper()
+	changeConf(t, func(c *Config) { c.CrashChannel = "admin" })
+	resetCrashReports()
+	t.Cleanup(resetCrashReports)
+}
+
+// sentTo returns the messages sent to channelID.
+func sentTo(sent []sentMessage, channelID string) []sentMessage {
+	var to []sentMessage
+	for _, m := range sent {
+		if m.ChannelID == channelID {
+			to = append(to, m)
+		}
+	}
+	return to
+}
+
+func TestRecoverPanic(t *testing.T) {
+	newFakeEBird(t)
+	useTempStore(t)
+	useCrashChannel(t)
+	advance := stepNow(t)
+
+	crash := func(index int) []sentMessage {
+		s := &fakeSession{}
+		inv := newInvocation(newMessage("!bird robin"), "bird", []string{"robin"})
+		func() {
+			defer inv.recoverPanic(s)
+			var images []string
+			_ = images[index]
+		}()
+		return s.messages()
+	}
+
+	sent := crash(3)
+	if reply := sentTo(sent, "c1"); len(reply) != 1 || !strings.Contains(reply[0].Content, "reference code") {
+		t.Errorf("unexpected reply %+v", reply)
+	}
+	report := sentTo(sent, "admin")
+	if len(report) != 1 || !strings.Contains(report[0].Content, "**FlaminGo panic** in `!bird`") || !strings.Contains(report[0].Content, "index out of range [3]") {
+		t.Fatalf("unexpected report %+v", report)
+	}
+
+	// The same kind of panic is only reported once an hour
+	if report := sentTo(crash(4), "admin"); len(report) != 0 {
+		t.Errorf("repeated panic reported: %+v", report)
+	}
+	advance(time.Hour)
+	report = sentTo(crash(5), "admin")
+	if len(report) != 1 || !strings.Contains(report[0].Content, "Happened 1 time more since the last report.") {
+		t.Errorf("unexpected report %+v", report)
+	}
+}
+
+func TestHandleMessagePanic(t *testing.T) {
+	newFakeEBird(t)
+	useTempStore(t)
+	useCrashChannel(t)
+
+	s := &fakeSession{panicOnTyping: true}
+	handleMessage(s, newMessage("!flamingo"))
+	commandPool.wait()
+
+	sent := s.messages()
+	if reply := sentTo(sent, "c1"); len(reply) != 1 || !strings.Contains(reply[0].Content, "reference code") {
+		t.Errorf("unexpected reply %+v", reply)
+	}
+	if report := sentTo(sent, "admin"); len(report) != 1 || !strings.Contains(report[0].Content, "typing failed") {
+		t.Errorf("unexpected report %+v", report)
+	}
+}
+
+func TestFailedCommandReported(t *testing.T) {
+	f := newFakeEBird(t)
+	useTempStore(t)
+	useCrashChannel(t)
+	changeConf(t, func(c *Config) { c.Key = "wrong-key" })
+	f.route("/data/obs/geo/recent", "recent.json")
+
+	sent := send(t, "!get rit")
+	report := sentTo(sent, "admin")
+	if len(report) != 1 || !strings.Contains(report[0].Content, "**FlaminGo error** in `!get`") || !strings.Contains(report[0].Content, "403") {
+		t.Errorf("unexpected report %+v", report)
+	}
+
+	// Mistakes in the command are not reported
+	if report := sentTo(send(t, "!get nowhere"), "admin"); len(report) != 0 {
+		t.Errorf("usage error reported: %+v", report)
+	}
+}
+
+func TestInterchangeImage(t *testing.T) {
+	tests := map[string]string{
+		"[https://a/s.jpg, small], [https://a/m.jpg, medium], [https://a/l.jpg, large]":                             "https://a/l.jpg",
+		"[https://a/s.jpg, small], [https://a/m.jpg, medium], [https://a/l.jpg, large], [https://a/xl.jpg, xlarge]": "https://a/l.jpg",
+		"[https://a/s.jpg, small]": "https://a/s.jpg",
+		"":                         "",
+	}
+	for in, want := range tests {
+		if got := interchangeImage(in); got != want {
+			t.Errorf("interchangeImage(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestBirdEmbedMissingSections(t *testing.T) {
+	embed := BirdEmbed(EmbedInfo{Name: "Snowy Owl", ScientificName: "Bubo scandiacus", Order: "Strigiformes"}, "en")
+	if len(embed.Fields) != 1 || embed.Fields[0].Name != "Order" {
+		t.Errorf("unexpected fields %+v", embed.Fields)
+	}
+	if embed.Image != nil {
+		t.Errorf("image without a URL: %+v", embed.Image)
+	}
+
+	embed = BirdEmbed(EmbedInfo{Name: "Snowy Owl", Facts: []string{"They hunt by day."}}, "en")
+	if len(embed.Fields) != 1 || embed.Fields[0].Value != "They hunt by day." {
+		t.Errorf("unexpected fields %+v", embed.Fields)
+	}
+}
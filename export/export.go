@@ -0,0 +1,197 @@
+// Package export writes lists of places, such as eBird sightings and hotspots, as files for spreadsheets and map tools.
+//
+// Rows are built from structs with FromStructs. Every exported field becomes a column, and fields tagged
+// `export:"name"`, `export:"lat"`, `export:"lng"` and `export:"time"` give the placemark name, position and time
+// used by the map formats.
+package export
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Format is a file format to export to.
+type Format string
+
+// Supported formats.
+const (
+	CSV     Format = "csv"
+	GeoJSON Format = "geojson"
+	KML     Format = "kml"
+	GPX     Format = "gpx"
+)
+
+// Formats lists the supported formats, for use in help text.
+var Formats = []Format{CSV, GeoJSON, KML, GPX}
+
+// ParseFormat returns the format with the given name, ignoring case.
+func ParseFormat(name string) (Format, error) {
+	for _, f := range Formats {
+		if strings.EqualFold(name, string(f)) {
+			return f, nil
+		}
+	}
+	return "", fmt.Errorf("unknown export format '%s'", name)
+}
+
+// ContentType returns the MIME type of files in the format.
+func (f Format) ContentType() string {
+	switch f {
+	case CSV:
+		return "text/csv"
+	case GeoJSON:
+		return "application/geo+json"
+	case KML:
+		return "application/vnd.google-earth.kml+xml"
+	case GPX:
+		return "application/gpx+xml"
+	}
+	return "application/octet-stream"
+}
+
+// FileName returns base with the format's extension.
+func (f Format) FileName(base string) string {
+	return base + "." + string(f)
+}
+
+// Table is a list of places with the same columns.
+type Table struct {
+	// Title names the list in map formats.
+	Title string
+	// Columns are the names of the values of each row.
+	Columns []string
+	Rows    []Row
+}
+
+// Row is a single place.
+type Row struct {
+	Name string
+	Lat  float64
+	Lng  float64
+	// Time is when the place was visited or reported, the zero time if unknown.
+	Time time.Time
+	// Values are the row's values in the order of the table's columns. Each is a string, bool, int or float64.
+	Values []interface{}
+}
+
+// timeLayouts are the layouts accepted in fields tagged `export:"time"`, as used by eBird.
+var timeLayouts = []string{"2006-01-02 15:04", "2006-01-02"}
+
+// FromStructs builds a table from a slice of structs. Embedded structs are flattened into the outer struct's columns.
+func FromStructs(title string, items interface{}) (Table, error) {
+	t := Table{Title: title}
+	v := reflect.ValueOf(items)
+	if v.Kind() != reflect.Slice || v.Type().Elem().Kind() != reflect.Struct {
+		return t, fmt.Errorf("exporting %T: not a slice of structs", items)
+	}
+
+	fields := columns(v.Type().Elem(), nil)
+	for _, f := range fields {
+		t.Columns = append(t.Columns, f.name)
+	}
+
+	for i := 0; i < v.Len(); i++ {
+		item := v.Index(i)
+		row := Row{}
+		for _, f := range fields {
+			fv := item.FieldByIndex(f.index)
+			var value interface{}
+			switch fv.Kind() {
+			case reflect.String:
+				value = fv.String()
+			case reflect.Bool:
+				value = fv.Bool()
+			case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+				value = int(fv.Int())
+			case reflect.Float32, reflect.Float64:
+				value = fv.Float()
+			default:
+				value = fmt.Sprint(fv.Interface())
+			}
+			row.Values = append(row.Values, value)
+
+			switch f.role {
+			case "name":
+				row.Name = fmt.Sprint(value)
+			case "lat":
+				row.Lat = fv.Float()
+			case "lng":
+				row.Lng = fv.Float()
+			case "time":
+				for _, layout := range timeLayouts {
+					if tm, err := time.Parse(layout, fv.String()); err == nil {
+						row.Time = tm
+						break
+					}
+				}
+			}
+		}
+		t.Rows = append(t.Rows, row)
+	}
+	return t, nil
+}
+
+// column is an exported struct field that becomes a column.
+type column struct {
+	name  string
+	index []int
+	role  string
+}
+
+// columns lists the exported fields of a struct type, flattening embedded structs.
+func columns(t reflect.Type, parent []int) []column {
+	var cols []column
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		index := append(append([]int{}, parent...), i)
+		if f.Anonymous && f.Type.Kind() == reflect.Struct {
+			cols = append(cols, columns(f.Type, index)...)
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+		cols = append(cols, column{name: f.Name, index: index, role: f.Tag.Get("export")})
+	}
+	return cols
+}
+
+// Write encodes the table in the given format.
+func Write(f Format, t Table) ([]byte, error) {
+	var buf bytes.Buffer
+	var err error
+	switch f {
+	case CSV:
+		err = writeCSV(&buf, t)
+	case GeoJSON:
+		err = writeGeoJSON(&buf, t)
+	case KML:
+		err = writeKML(&buf, t)
+	case GPX:
+		err = writeGPX(&buf, t)
+	default:
+		err = fmt.Errorf("unknown export format '%s'", f)
+	}
+	// Error handling
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// formatValue formats a row value as text.
+func formatValue(v interface{}) string {
+	switch v := v.(type) {
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// timeLayout is how times are written. eBird times are local to the place, so no time zone is given.
+const timeLayout = "2006-01-02T15:04:05"
@@ -0,0 +1,118 @@
+package export
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// update rewrites the golden files with the current output, e.g. go test ./export -update
+var update = flag.Bool("update", false, "rewrite golden files")
+
+type place struct {
+	Code  string
+	Place string  `export:"name"`
+	Lat   float64 `export:"lat"`
+	Lng   float64 `export:"lng"`
+	When  string  `export:"time"`
+}
+
+type sighting struct {
+	place
+	Count    int
+	Reviewed bool
+	// private fields are not exported
+	private string
+}
+
+var sightings = []sighting{
+	{place: place{Code: "L772198", Place: "Braddock Bay Park", Lat: 43.3043, Lng: -77.7107, When: "2022-10-11 07:30"}, Count: 1},
+	{place: place{Code: "L1000001", Place: `Hamlin Beach "West" & Lake`, Lat: 43.3607, Lng: -77.9492, When: "2022-10-08"}, Count: 2, Reviewed: true},
+	{place: place{Code: "L2000001", Place: "Quiet Pond, Pittsford", Lat: 43.05, Lng: -77.62}, Count: 12},
+}
+
+func TestFromStructs(t *testing.T) {
+	table, err := FromStructs("Sightings", sightings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"Code", "Place", "Lat", "Lng", "When", "Count", "Reviewed"}
+	if len(table.Columns) != len(want) {
+		t.Fatalf("columns = %v, want %v", table.Columns, want)
+	}
+	for i := range want {
+		if table.Columns[i] != want[i] {
+			t.Errorf("columns = %v, want %v", table.Columns, want)
+			break
+		}
+	}
+
+	row := table.Rows[0]
+	if row.Name != "Braddock Bay Park" || row.Lat != 43.3043 || row.Lng != -77.7107 {
+		t.Errorf("unexpected row %+v", row)
+	}
+	if !row.Time.Equal(time.Date(2022, 10, 11, 7, 30, 0, 0, time.UTC)) {
+		t.Errorf("time = %v", row.Time)
+	}
+	if !table.Rows[2].Time.IsZero() {
+		t.Errorf("time = %v, want zero for a missing date", table.Rows[2].Time)
+	}
+
+	if _, err := FromStructs("Nothing", []string{"a"}); err == nil {
+		t.Error("want an error for a slice of strings")
+	}
+}
+
+func TestWriteGolden(t *testing.T) {
+	table, err := FromStructs("Sightings", sightings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range Formats {
+		t.Run(string(f), func(t *testing.T) {
+			got, err := Write(f, table)
+			if err != nil {
+				t.Fatal(err)
+			}
+			golden := filepath.Join("testdata", f.FileName("sightings"))
+			if *update {
+				if err := os.WriteFile(golden, got, 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("output differs from %s:\n%s", golden, got)
+			}
+		})
+	}
+}
+
+func TestWriteEmpty(t *testing.T) {
+	table, err := FromStructs("Nothing", []sighting{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := Write(GeoJSON, table)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(got, []byte(`"features": []`)) {
+		t.Errorf("want an empty feature list, got %s", got)
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	if f, err := ParseFormat("KML"); err != nil || f != KML {
+		t.Errorf("ParseFormat(KML) = %q, %v", f, err)
+	}
+	if _, err := ParseFormat("xlsx"); err == nil {
+		t.Error("want an error for xlsx")
+	}
+}
@@ -0,0 +1,167 @@
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"strings"
+)
+
+// writeCSV writes a header row with the column names, then one line per row.
+func writeCSV(w io.Writer, t Table) error {
+	cw := csv.NewWriter(w)
+	err := cw.Write(t.Columns)
+	// Error handling
+	if err != nil {
+		return err
+	}
+	for _, row := range t.Rows {
+		record := make([]string, len(row.Values))
+		for i, v := range row.Values {
+			record[i] = formatValue(v)
+		}
+		err = cw.Write(record)
+		// Error handling
+		if err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// geoJSONFeature is a point feature with the row's values as properties.
+type geoJSONFeature struct {
+	Type       string                 `json:"type"`
+	Geometry   geoJSONPoint           `json:"geometry"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+type geoJSONPoint struct {
+	Type        string     `json:"type"`
+	Coordinates [2]float64 `json:"coordinates"`
+}
+
+// writeGeoJSON writes a FeatureCollection of points.
+func writeGeoJSON(w io.Writer, t Table) error {
+	doc := struct {
+		Type     string           `json:"type"`
+		Name     string           `json:"name,omitempty"`
+		Features []geoJSONFeature `json:"features"`
+	}{Type: "FeatureCollection", Name: t.Title, Features: []geoJSONFeature{}}
+
+	for _, row := range t.Rows {
+		props := make(map[string]interface{}, len(row.Values))
+		for i, v := range row.Values {
+			props[t.Columns[i]] = v
+		}
+		doc.Features = append(doc.Features, geoJSONFeature{
+			Type:       "Feature",
+			Geometry:   geoJSONPoint{Type: "Point", Coordinates: [2]float64{row.Lng, row.Lat}},
+			Properties: props,
+		})
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(doc)
+}
+
+// kmlDocument is a KML file with one placemark per row.
+type kmlDocument struct {
+	XMLName  xml.Name `xml:"kml"`
+	XMLNS    string   `xml:"xmlns,attr"`
+	Document struct {
+		Name       string         `xml:"name"`
+		Placemarks []kmlPlacemark `xml:"Placemark"`
+	}
+}
+
+type kmlPlacemark struct {
+	Name        string    `xml:"name"`
+	TimeStamp   *string   `xml:"TimeStamp>when"`
+	Data        []kmlData `xml:"ExtendedData>Data"`
+	Coordinates string    `xml:"Point>coordinates"`
+}
+
+type kmlData struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value"`
+}
+
+// writeKML writes a KML document with the row's values as extended data, which Google My Maps imports as columns.
+func writeKML(w io.Writer, t Table) error {
+	doc := kmlDocument{XMLNS: "http://www.opengis.net/kml/2.2"}
+	doc.Document.Name = t.Title
+	for _, row := range t.Rows {
+		p := kmlPlacemark{Name: row.Name, Coordinates: formatValue(row.Lng) + "," + formatValue(row.Lat)}
+		if !row.Time.IsZero() {
+			when := row.Time.Format(timeLayout)
+			p.TimeStamp = &when
+		}
+		for i, v := range row.Values {
+			p.Data = append(p.Data, kmlData{Name: t.Columns[i], Value: formatValue(v)})
+		}
+		doc.Document.Placemarks = append(doc.Document.Placemarks, p)
+	}
+	return writeXML(w, doc)
+}
+
+// gpxDocument is a GPX 1.1 file with one waypoint per row.
+type gpxDocument struct {
+	XMLName  xml.Name `xml:"gpx"`
+	XMLNS    string   `xml:"xmlns,attr"`
+	Version  string   `xml:"version,attr"`
+	Creator  string   `xml:"creator,attr"`
+	Metadata struct {
+		Name string `xml:"name"`
+	} `xml:"metadata"`
+	Waypoints []gpxWaypoint `xml:"wpt"`
+}
+
+type gpxWaypoint struct {
+	Lat  string  `xml:"lat,attr"`
+	Lon  string  `xml:"lon,attr"`
+	Time *string `xml:"time"`
+	Name string  `xml:"name"`
+	Desc string  `xml:"desc"`
+}
+
+// writeGPX writes GPX waypoints. GPX has no free-form fields, so the row's values go in the description.
+func writeGPX(w io.Writer, t Table) error {
+	doc := gpxDocument{XMLNS: "http://www.topografix.com/GPX/1/1", Version: "1.1", Creator: "FlaminGo"}
+	doc.Metadata.Name = t.Title
+	for _, row := range t.Rows {
+		wpt := gpxWaypoint{Lat: formatValue(row.Lat), Lon: formatValue(row.Lng), Name: row.Name}
+		if !row.Time.IsZero() {
+			tm := row.Time.Format(timeLayout)
+			wpt.Time = &tm
+		}
+		desc := make([]string, len(row.Values))
+		for i, v := range row.Values {
+			desc[i] = t.Columns[i] + ": " + formatValue(v)
+		}
+		wpt.Desc = strings.Join(desc, "\n")
+		doc.Waypoints = append(doc.Waypoints, wpt)
+	}
+	return writeXML(w, doc)
+}
+
+// writeXML writes an indented XML document with its declaration.
+func writeXML(w io.Writer, doc interface{}) error {
+	_, err := io.WriteString(w, xml.Header)
+	// Error handling
+	if err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	err = enc.Encode(doc)
+	// Error handling
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, "\n")
+	return err
+}
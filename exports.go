@@ -0,0 +1,49 @@
+// Exports sends command results as CSV, GeoJSON, KML or GPX attachments for spreadsheets and map tools
+
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/R1V3N/FlaminGo/export"
+)
+
+// sendExport replies with items, a slice of structs, as a file in format f named base plus the format's extension.
+// title describes the list, e.g. "Notable eBird sightings within 15 km of Braddock Bay Park in the past 14 days".
+func (inv *Invocation) sendExport(s Sender, f export.Format, base, title string, items interface{}) error {
+	table, err := export.FromStructs(title, items)
+	// Error handling
+	if err != nil {
+		return err
+	}
+	data, err := export.Write(f, table)
+	// Error handling
+	if err != nil {
+		return fmt.Errorf("exporting %s as %s: %w", base, f, err)
+	}
+
+	inv.sendFile(s, tr(inv.Locale, "**%s** (%s as %s)", title, pluralizeIn(inv.Locale, len(table.Rows), "row"), strings.ToUpper(string(f))),
+		f.FileName(base), f.ContentType(), data)
+	return nil
+}
+
+// exportObservations sends every sighting of a !get or !rare query as a file in the query's export format.
+// Unlike the message, the file has every field eBird returns and nothing is combined or cut off.
+func exportObservations(s Sender, inv *Invocation, q ObsQuery) error {
+	ctx := inv.Context()
+	var b []BirdSighting
+	var err error
+	title := "Verified eBird sightings %s in the past %s"
+	if inv.Command == "rare" {
+		title = "Notable eBird sightings %s in the past %s"
+		b, err = NotableSightings(ctx, &q)
+	} else {
+		b, err = RecentSightings(ctx, &q)
+	}
+	// Error handling
+	if err != nil {
+		return err
+	}
+	return inv.sendExport(s, q.Export, inv.Command, tr(q.Locale, title, q.where(), pluralizeIn(q.Locale, q.Days, "day")), b)
+}
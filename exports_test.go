@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+func TestExportRareCSV(t *testing.T) {
+	f := newFakeEBird(t)
+	f.route("/data/obs/geo/recent/notable", "notable.json")
+
+	sent := send(t, "!rare braddock export:csv")
+	if len(sent) != 1 {
+		t.Fatalf("sent %d messages, want 1", len(sent))
+	}
+	if want := "**Notable eBird sightings within 15 km of Braddock Bay Park in the past 14 days** (3 rows as CSV)"; sent[0].Content != want {
+		t.Errorf("content = %q, want %q", sent[0].Content, want)
+	}
+
+	// Every sighting is exported with every field, without combining the two owl reports
+	records, err := csv.NewReader(bytes.NewReader(sent[0].Files["rare.csv"])).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 4 {
+		t.Fatalf("got %d records, want a header and 3 rows", len(records))
+	}
+	if got := strings.Join(records[0], ","); got != "SpeciesCode,ComName,SciName,LocID,LocName,ObsDt,HowMany,Lat,Lng,ObsValid,ObsReviewed,LocationPrivate,SubID" {
+		t.Errorf("header = %s", got)
+	}
+	if got := strings.Join(records[3], ","); got != "parjae,Parasitic Jaeger,Stercorarius parasiticus,L1000001,Hamlin Beach State Park,2022-10-08 09:00,2,43.3607,-77.9492,true,true,false,S120000012" {
+		t.Errorf("row = %s", got)
+	}
+}
+
+func TestExportGetGeoJSON(t *testing.T) {
+	f := newFakeEBird(t)
+	f.route("/data/obs/geo/recent", "recent.json")
+
+	sent := send(t, "!get mendon export:geojson")
+	if len(sent) != 1 {
+		t.Fatalf("sent %d messages, want 1", len(sent))
+	}
+	var doc struct {
+		Type     string
+		Features []struct {
+			Geometry struct {
+				Coordinates []float64
+			}
+			Properties map[string]interface{}
+		}
+	}
+	if err := json.Unmarshal(sent[0].Files["get.geojson"], &doc); err != nil {
+		t.Fatalf("attachment is not JSON: %v", err)
+	}
+	if doc.Type != "FeatureCollection" || len(doc.Features) == 0 {
+		t.Fatalf("unexpected document %+v", doc)
+	}
+	if _, ok := doc.Features[0].Properties["ComName"]; !ok {
+		t.Errorf("properties missing ComName: %v", doc.Features[0].Properties)
+	}
+}
+
+func TestExportHotspotsKML(t *testing.T) {
+	f := newFakeEBird(t)
+	f.route("/ref/hotspot/geo", "hotspots.json")
+
+	sent := send(t, "!hotspots near rit export:kml")
+	if len(sent) != 1 {
+		t.Fatalf("sent %d messages, want 1", len(sent))
+	}
+	var doc struct {
+		Document struct {
+			Placemarks []struct {
+				Name string `xml:"name"`
+			} `xml:"Placemark"`
+		}
+	}
+	if err := xml.Unmarshal(sent[0].Files["hotspots.kml"], &doc); err != nil {
+		t.Fatalf("attachment is not XML: %v", err)
+	}
+	// Hotspots are exported in the same order as the list, most species first
+	if len(doc.Document.Placemarks) != 3 || doc.Document.Placemarks[0].Name != "Mendon Ponds Park" {
+		t.Errorf("unexpected placemarks %+v", doc.Document.Placemarks)
+	}
+}
+
+func TestExportNearestGPX(t *testing.T) {
+	f := newFakeEBird(t)
+	f.route("/ref/taxonomy/ebird", "taxonomy.json")
+	f.route("/data/nearest/geo/recent/snoowl1", "nearest_snoowl1.json")
+
+	sent := send(t, "!nearest snowy owl from rit 7 export:gpx")
+	if len(sent) != 1 {
+		t.Fatalf("sent %d messages, want 1", len(sent))
+	}
+	data := sent[0].Files["nearest-snoowl1.gpx"]
+	if !bytes.Contains(data, []byte("<gpx ")) || !bytes.Contains(data, []byte("DistanceKm: ")) {
+		t.Errorf("unexpected GPX:\n%s", data)
+	}
+}
+
+func TestExportUnknownFormat(t *testing.T) {
+	newFakeEBird(t)
+
+	sent := send(t, "!get rit export:xlsx")
+	if len(sent) != 1 || sent[0].Content != "Error: 'xlsx' is not an export format, use csv/geojson/kml/gpx" {
+		t.Errorf("unexpected reply %+v", sent)
+	}
+}
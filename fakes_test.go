@@ -0,0 +1,342 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestMain(m *testing.M) {
+	// Keeping test output readable, logs are only shown with -v
+	flag.Parse()
+	if !testing.Verbose() {
+		logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	}
+	commandPool = newWorkerPool(4, commandQueueSize)
+	os.Exit(m.Run())
+}
+
+// testKey is the eBird API key the fake eBird server expects.
+const testKey = "test-ebird-key"
+
+// fakeEBird is an httptest server that answers eBird API paths with recorded JSON fixtures from testdata/ebird.
+type fakeEBird struct {
+	*httptest.Server
+
+	mu sync.Mutex
+	// routes maps request paths to fixture file names.
+	routes map[string]string
+	// handlers maps request paths to handlers, for tests that need more than a fixture.
+	handlers map[string]http.HandlerFunc
+	// requests records every request the server received.
+	requests []*http.Request
+	// prefixes maps request path prefixes to handlers, for endpoints with dates or codes in the path.
+	prefixes map[string]http.HandlerFunc
+}
+
+// changeConf changes the configuration for the duration of the test.
+func changeConf(t *testing.T, change func(c *Config)) {
+	t.Helper()
+	old := Conf()
+	c := old
+	change(&c)
+	setConf(c)
+	t.Cleanup(func() { setConf(old) })
+}
+
+// newFakeEBird starts a fake eBird server, points Conf at it for the duration of the test, and returns it.
+func newFakeEBird(t *testing.T) *fakeEBird {
+	t.Helper()
+
+	f := &fakeEBird{routes: make(map[string]string), handlers: make(map[string]http.HandlerFunc), prefixes: make(map[string]http.HandlerFunc)}
+	f.Server = httptest.NewServer(http.HandlerFunc(f.serve))
+	t.Cleanup(f.Close)
+
+	changeConf(t, func(c *Config) {
+		*c = defaultConfig()
+		c.Key = testKey
+		c.EBirdURL = f.URL
+		c.DataDir = t.TempDir()
+		// Tests send many commands in a row, so only the rate limit tests turn the limits on
+		c.UserRate, c.ChannelRate, c.EBirdRate = 0, 0, 0
+	})
+	resetRateLimits()
+
+	// Emptying the caches, so every test sees its own fixtures
+	taxaMu.Lock()
+	taxa = make(map[string]map[string]Taxon)
+	taxaMu.Unlock()
+	allTaxaMu.Lock()
+	allTaxa = make(map[string][]Taxon)
+	allTaxaMu.Unlock()
+	regionNamesMu.Lock()
+	regionNames = make(map[string]string)
+	regionNamesMu.Unlock()
+
+	return f
+}
+
+// route makes the server answer requests for path with the given fixture file from testdata/ebird.
+func (f *fakeEBird) route(path, fixture string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.routes[path] = fixture
+}
+
+// handle makes the server answer requests for path with h, after checking the API key.
+func (f *fakeEBird) handle(path string, h http.HandlerFunc) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.handlers[path] = h
+}
+
+// handlePrefix makes the server answer requests for every path starting with prefix with h,
+// unless a route or handler for the exact path exists.
+func (f *fakeEBird) handlePrefix(prefix string, h http.HandlerFunc) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.prefixes[prefix] = h
+}
+
+// requestCount returns the number of requests the server received.
+func (f *fakeEBird) requestCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.requests)
+}
+
+// lastRequestTo returns the most recent request the server received for path, or nil.
+func (f *fakeEBird) lastRequestTo(path string) *http.Request {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i := len(f.requests) - 1; i >= 0; i-- {
+		if f.requests[i].URL.Path == path {
+			return f.requests[i]
+		}
+	}
+	return nil
+}
+
+// lastRequest returns the most recent request the server received, or nil.
+func (f *fakeEBird) lastRequest() *http.Request {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.requests) == 0 {
+		return nil
+	}
+	return f.requests[len(f.requests)-1]
+}
+
+func (f *fakeEBird) serve(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	f.requests = append(f.requests, r)
+	fixture, ok := f.routes[r.URL.Path]
+	h := f.handlers[r.URL.Path]
+	if h == nil && !ok {
+		for prefix, ph := range f.prefixes {
+			if strings.HasPrefix(r.URL.Path, prefix) {
+				h = ph
+			}
+		}
+	}
+	f.mu.Unlock()
+
+	// eBird rejects requests without a valid key
+	if r.Header.Get("X-eBirdApiToken") != testKey {
+		http.Error(w, `{"errors":[{"status":"403 FORBIDDEN","title":"Forbidden"}]}`, http.StatusForbidden)
+		return
+	}
+	if h != nil {
+		h(w, r)
+		return
+	}
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	data, err := os.ReadFile(filepath.Join("testdata", "ebird", fixture))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(data)
+}
+
+// useTempStore replaces store with an empty one saved in a temporary directory for the duration of the test.
+func useTempStore(t *testing.T) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "guilds.json")
+	st, err := openStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	old := store
+	store = st
+	t.Cleanup(func() {
+		store = old
+	})
+	return path
+}
+
+// sentMessage is a message recorded by fakeSession.
+type sentMessage struct {
+	ChannelID string
+	Content   string
+	Embed     *discordgo.MessageEmbed
+	// Embeds are every embed sent in one message by ChannelMessageSendEmbeds. Embed is the first of them.
+	Embeds []*discordgo.MessageEmbed
+	// Files maps the names of attached files to their contents.
+	Files map[string][]byte
+	// Components are the buttons sent with the message.
+	Components []discordgo.MessageComponent
+}
+
+// fakeSession implements Sender by recording every message instead of sending it to Discord.
+type fakeSession struct {
+	mu   sync.Mutex
+	sent []sentMessage
+	// permissions is what UserChannelPermissions reports for every user.
+	permissions int64
+	// typing counts the typing indicators sent.
+	typing int
+	// panicOnTyping makes ChannelTyping panic, to test recovering from panics in commands.
+	panicOnTyping bool
+	// guilds is what UserGuilds lists, and status the last game status set.
+	guilds []*discordgo.UserGuild
+	status string
+	// responses are the answers to button clicks.
+	responses []*discordgo.InteractionResponse
+}
+
+func (f *fakeSession) ChannelMessageSend(channelID string, content string) (*discordgo.Message, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.sent = append(f.sent, sentMessage{ChannelID: channelID, Content: content})
+	return &discordgo.Message{ChannelID: channelID, Content: content}, nil
+}
+
+func (f *fakeSession) ChannelMessageSendEmbed(channelID string, embed *discordgo.MessageEmbed) (*discordgo.Message, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.sent = append(f.sent, sentMessage{ChannelID: channelID, Embed: embed})
+	return &discordgo.Message{ChannelID: channelID, Embeds: []*discordgo.MessageEmbed{embed}}, nil
+}
+
+func (f *fakeSession) ChannelMessageSendEmbeds(channelID string, embeds []*discordgo.MessageEmbed) (*discordgo.Message, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.sent = append(f.sent, sentMessage{ChannelID: channelID, Embed: embeds[0], Embeds: embeds})
+	return &discordgo.Message{ChannelID: channelID, Embeds: embeds}, nil
+}
+
+func (f *fakeSession) ChannelMessageSendComplex(channelID string, data *discordgo.MessageSend) (*discordgo.Message, error) {
+	msg := sentMessage{ChannelID: channelID, Content: data.Content, Embed: data.Embed, Files: make(map[string][]byte),
+		Components: data.Components}
+	if len(data.Embeds) > 0 {
+		msg.Embed = data.Embeds[0]
+	}
+	for _, file := range data.Files {
+		b, err := io.ReadAll(file.Reader)
+		if err != nil {
+			return nil, err
+		}
+		msg.Files[file.Name] = b
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.sent = append(f.sent, msg)
+	return &discordgo.Message{ChannelID: channelID, Content: data.Content}, nil
+}
+
+// ChannelTyping records a typing indicator.
+func (f *fakeSession) ChannelTyping(channelID string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.typing++
+	if f.panicOnTyping {
+		panic("typing failed")
+	}
+	return nil
+}
+
+// UserGuilds pages through the test's guilds, ordered by ID like Discord's.
+func (f *fakeSession) UserGuilds(limit int, beforeID, afterID string) ([]*discordgo.UserGuild, error) {
+	var page []*discordgo.UserGuild
+	for _, g := range f.guilds {
+		if g.ID > afterID && len(page) < limit {
+			page = append(page, g)
+		}
+	}
+	return page, nil
+}
+
+// UpdateGameStatus records the status.
+func (f *fakeSession) UpdateGameStatus(idle int, name string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.status = name
+	return nil
+}
+
+// UserChannelPermissions returns the permissions the test gave the fake session.
+func (f *fakeSession) UserChannelPermissions(userID, channelID string) (int64, error) {
+	return f.permissions, nil
+}
+
+// InteractionRespond records the answer to a button click.
+func (f *fakeSession) InteractionRespond(interaction *discordgo.Interaction, resp *discordgo.InteractionResponse) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.responses = append(f.responses, resp)
+	return nil
+}
+
+// messages returns a copy of everything sent so far.
+func (f *fakeSession) messages() []sentMessage {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]sentMessage(nil), f.sent...)
+}
+
+// newMessage builds a MessageCreate event as if a user typed content in a test channel.
+func newMessage(content string) *discordgo.MessageCreate {
+	return &discordgo.MessageCreate{Message: &discordgo.Message{
+		ID:        "m1",
+		ChannelID: "c1",
+		GuildID:   "g1",
+		Content:   content,
+		Author:    &discordgo.User{ID: "u1", Username: "birder"},
+	}}
+}
+
+// send runs content through handleMessage, waits for the command to finish, and returns what the bot sent back.
+func send(t *testing.T, content string) []sentMessage {
+	t.Helper()
+	s := &fakeSession{}
+	handleMessage(s, newMessage(content))
+	commandPool.wait()
+	return s.messages()
+}
+
+// sendAsAdmin is send from a member with the Manage Server permission.
+func sendAsAdmin(t *testing.T, content string) []sentMessage {
+	t.Helper()
+	s := &fakeSession{permissions: discordgo.PermissionManageServer}
+	handleMessage(s, newMessage(content))
+	commandPool.wait()
+	return s.messages()
+}
@@ -0,0 +1,199 @@
+// Format contains the sorting, grouping and formatting shared by the observation list commands
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Sort orders accepted by the sort: option.
+const (
+	sortName      = "name"
+	sortCount     = "count"
+	sortDate      = "date"
+	sortTaxonomic = "taxonomic"
+)
+
+// Groupings accepted by the group: option.
+const (
+	groupNone   = ""
+	groupFamily = "family"
+)
+
+// sortOrders lists the valid sort: values, for use in error messages and help text.
+var sortOrders = []string{sortName, sortCount, sortDate, sortTaxonomic}
+
+// formatSightings sorts and optionally groups sightings according to q, and renders them below header with line.
+// Sightings with no count are left out. The result is trimmed to fit in a Discord message.
+func formatSightings(ctx context.Context, header string, b []BirdSighting, q ObsQuery, line func(BirdSighting) string) (string, error) {
+	// Dropping sightings without a count, since eBird reports "X" (present) as a missing howMany
+	var list []BirdSighting
+	for _, s := range b {
+		if s.HowMany > 0 {
+			list = append(list, s)
+		}
+	}
+
+	// The taxonomy is only needed for taxonomic order and family groups
+	var tax map[string]Taxon
+	if q.Sort == sortTaxonomic || q.Group == groupFamily {
+		codes := make([]string, len(list))
+		for i, s := range list {
+			codes[i] = s.SpeciesCode
+		}
+		var err error
+		tax, err = lookupTaxa(ctx, codes, q.Locale)
+		// Error handling
+		if err != nil {
+			return "", err
+		}
+	}
+
+	sortSightings(list, q, tax)
+
+	rString := header
+	if q.Group == groupFamily {
+		for _, g := range groupByFamily(list, tax) {
+			rString += fmt.Sprintf("__%s__\n", g.name)
+			for _, s := range g.sightings {
+				rString += line(s)
+			}
+		}
+	} else {
+		for _, s := range list {
+			rString += line(s)
+		}
+	}
+
+	//Trimming return string to Discord max message length, minus 5 characters to show max length has been reached
+	return truncateText(rString, 1995), nil
+}
+
+// sortSightings sorts b in place by q.Sort, reversing the order if q.Reversed is true.
+// Ties are broken by name, location and date so the output is stable.
+func sortSightings(b []BirdSighting, q ObsQuery, tax map[string]Taxon) {
+	byName := func(i, j int) bool {
+		if b[i].ComName != b[j].ComName {
+			return b[i].ComName < b[j].ComName
+		}
+		if b[i].LocName != b[j].LocName {
+			return b[i].LocName < b[j].LocName
+		}
+		return b[i].ObsDt < b[j].ObsDt
+	}
+
+	var less func(i, j int) bool
+	switch q.Sort {
+	case sortCount:
+		// Largest counts first
+		less = func(i, j int) bool {
+			if b[i].HowMany != b[j].HowMany {
+				return b[i].HowMany > b[j].HowMany
+			}
+			return byName(i, j)
+		}
+	case sortDate:
+		// Newest sightings first
+		less = func(i, j int) bool {
+			if b[i].ObsDt != b[j].ObsDt {
+				return b[i].ObsDt > b[j].ObsDt
+			}
+			return byName(i, j)
+		}
+	case sortTaxonomic:
+		less = func(i, j int) bool {
+			oi, oj := taxonOrder(tax, b[i].SpeciesCode), taxonOrder(tax, b[j].SpeciesCode)
+			if oi != oj {
+				return oi < oj
+			}
+			return byName(i, j)
+		}
+	default:
+		less = byName
+	}
+
+	// Sorting the list, depending on whether q.Reversed is true or false
+	if q.Reversed {
+		sort.SliceStable(b, func(i, j int) bool {
+			return less(j, i)
+		})
+	} else {
+		sort.SliceStable(b, less)
+	}
+}
+
+// taxonOrder returns the eBird taxonomic order of a species. Species missing from the taxonomy sort last.
+func taxonOrder(tax map[string]Taxon, code string) float64 {
+	if t, ok := tax[code]; ok {
+		return t.TaxonOrder
+	}
+	return 1e9
+}
+
+// familyGroup holds the sightings of one family, for use with group:family.
+type familyGroup struct {
+	name      string
+	order     float64
+	sightings []BirdSighting
+}
+
+// groupByFamily splits already sorted sightings into families, keeping their order within each family.
+// Families are listed in taxonomic order, so related families end up next to each other.
+func groupByFamily(b []BirdSighting, tax map[string]Taxon) []*familyGroup {
+	groups := make(map[string]*familyGroup)
+	var list []*familyGroup
+	for _, s := range b {
+		name := "Other"
+		order := 1e9
+		if t, ok := tax[s.SpeciesCode]; ok && t.FamilyComName != "" {
+			name = t.FamilyComName
+			order = t.TaxonOrder
+		}
+
+		g, ok := groups[name]
+		if !ok {
+			g = &familyGroup{name: name, order: order}
+			groups[name] = g
+			list = append(list, g)
+		}
+		if order < g.order {
+			g.order = order
+		}
+		g.sightings = append(g.sightings, s)
+	}
+
+	sort.SliceStable(list, func(i, j int) bool {
+		return list[i].order < list[j].order
+	})
+	return list
+}
+
+// parseSortOptions reads the sort: and group: options into q.
+func parseSortOptions(q *ObsQuery, options map[string]string) error {
+	if v, ok := options["sort"]; ok {
+		v = strings.ToLower(v)
+		valid := false
+		for _, o := range sortOrders {
+			if v == o {
+				valid = true
+			}
+		}
+		if !valid {
+			return usageErrorf("'sort' must be one of %s, got '%s'", strings.Join(sortOrders, ", "), v)
+		}
+		q.Sort = v
+	}
+
+	if v, ok := options["group"]; ok {
+		v = strings.ToLower(v)
+		if v != groupFamily {
+			return usageErrorf("'group' must be '%s', got '%s'", groupFamily, v)
+		}
+		q.Group = v
+	}
+
+	return nil
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestFormatSightingsSortAndGroup(t *testing.T) {
+	f := newFakeEBird(t)
+	f.route("/ref/taxonomy/ebird", "taxonomy.json")
+
+	b := []BirdSighting{
+		{SpeciesCode: "amerob", ComName: "American Robin", HowMany: 12, ObsDt: "2022-10-10 08:15"},
+		{SpeciesCode: "blujay", ComName: "Blue Jay", HowMany: 3, ObsDt: "2022-10-11 08:15"},
+		{SpeciesCode: "amecro", ComName: "American Crow", HowMany: 40, ObsDt: "2022-10-09 17:02"},
+		{SpeciesCode: "dowwoo", ComName: "Downy Woodpecker", ObsDt: "2022-10-09 17:02"},
+	}
+	line := func(s BirdSighting) string {
+		return s.ComName + "\n"
+	}
+
+	tests := []struct {
+		q    ObsQuery
+		want string
+	}{
+		{ObsQuery{}, "American Crow\nAmerican Robin\nBlue Jay\n"},
+		{ObsQuery{Reversed: true}, "Blue Jay\nAmerican Robin\nAmerican Crow\n"},
+		{ObsQuery{Sort: sortCount}, "American Crow\nAmerican Robin\nBlue Jay\n"},
+		{ObsQuery{Sort: sortCount, Reversed: true}, "Blue Jay\nAmerican Robin\nAmerican Crow\n"},
+		{ObsQuery{Sort: sortDate}, "Blue Jay\nAmerican Robin\nAmerican Crow\n"},
+		{ObsQuery{Sort: sortTaxonomic}, "Blue Jay\nAmerican Crow\nAmerican Robin\n"},
+		{ObsQuery{Group: groupFamily}, "__Crows, Jays, and Magpies__\nAmerican Crow\nBlue Jay\n__Thrushes and Allies__\nAmerican Robin\n"},
+	}
+	for _, tt := range tests {
+		in := append([]BirdSighting(nil), b...)
+		got, err := formatSightings(context.Background(), "", in, tt.q, line)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("%+v: got %q, want %q", tt.q, got, tt.want)
+		}
+	}
+
+	if species := f.lastRequest().URL.Query().Get("species"); species != "amecro,amerob,blujay" {
+		t.Errorf("species = %q, want only the listed species", species)
+	}
+}
+
+func TestParseSortOptionsErrors(t *testing.T) {
+	var q ObsQuery
+	if err := parseSortOptions(&q, map[string]string{"sort": "size"}); err == nil || !strings.Contains(err.Error(), "'sort' must be one of") {
+		t.Errorf("unexpected error %v", err)
+	}
+	if err := parseSortOptions(&q, map[string]string{"group": "order"}); err == nil {
+		t.Error("expected an error for group:order")
+	}
+}
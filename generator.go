@@ -0,0 +1,369 @@
+// Generator holds the word lists behind !generate: the default lists built in from birdgen.csv or loaded from
+// Config.GeneratorFile, which is reloaded when it changes, and the lists guilds upload for their own !generate
+
+package main
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// generatorPollInterval is how often FlaminGo checks whether the bird generator file changed.
+const generatorPollInterval = 10 * time.Second
+
+// maxWordListErrors is how many mistakes in a word list file are reported before the rest are only counted.
+const maxWordListErrors = 10
+
+// maxWordListUpload is the largest word list file a guild can upload, in bytes.
+const maxWordListUpload = 256 << 10
+
+// generateUsage explains the !generate command.
+const generateUsage = "use `!generate [0-3]`, `!generate upload <merge or replace>` with a .csv file attached, or `!generate reset`"
+
+// embeddedGenerator is the bird generator file built into FlaminGo, used unless Config.GeneratorFile is set.
+//
+//go:embed birdgen.csv
+var embeddedGenerator []byte
+
+// WordLists are the adjectives and nouns bird names are generated from.
+type WordLists struct {
+	Adjectives []string `json:"adjectives,omitempty"`
+	Nouns      []string `json:"nouns,omitempty"`
+}
+
+// complete reports whether bird names can be generated from the lists, which needs an adjective and a noun.
+func (w WordLists) complete() bool {
+	return len(w.Adjectives) > 0 && len(w.Nouns) > 0
+}
+
+var (
+	// generatorMu guards generatorWords, which loadGenerator replaces while commands read it.
+	generatorMu sync.RWMutex
+	// generatorWords holds the default lists, loaded by loadGenerator.
+	generatorWords WordLists
+)
+
+// defaultWords returns the default word lists. They are replaced, never changed, so callers may keep them.
+func defaultWords() WordLists {
+	generatorMu.RLock()
+	defer generatorMu.RUnlock()
+	return generatorWords
+}
+
+// guildWords returns the word lists for the guild's !generate: the defaults, the guild's uploaded lists, or both.
+func guildWords(guildID string) WordLists {
+	g := store.Guild(guildID)
+	if g.Words == nil {
+		return defaultWords()
+	}
+	if g.ReplaceWords {
+		return *g.Words
+	}
+
+	words := defaultWords()
+	return WordLists{
+		Adjectives: append(append([]string(nil), words.Adjectives...), g.Words.Adjectives...),
+		Nouns:      append(append([]string(nil), words.Nouns...), g.Words.Nouns...),
+	}
+}
+
+// parseWordLists reads a .csv file with an adjective and a noun on each line. Either may be left empty, but not both.
+// name is the file's name, used in errors, which say which line each mistake is on.
+func parseWordLists(r io.Reader, name string) (WordLists, error) {
+	reader := csv.NewReader(r)
+	// Rows with the wrong number of columns are reported below, with the rest of the mistakes
+	reader.FieldsPerRecord = -1
+
+	var words WordLists
+	var errs []error
+	mistakes := 0
+	mistake := func(line int, format string, a ...interface{}) {
+		mistakes++
+		if mistakes <= maxWordListErrors {
+			errs = append(errs, fmt.Errorf("%s line %d: %s", name, line, fmt.Sprintf(format, a...)))
+		}
+	}
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		// Error handling
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			// The reader cannot tell where the broken row ends, so nothing after it can be trusted
+			mistake(parseErr.Line, "%s", parseErr.Err)
+			break
+		}
+		if err != nil {
+			return WordLists{}, fmt.Errorf("reading %s: %w", name, err)
+		}
+
+		line, _ := reader.FieldPos(0)
+		if len(record) != 2 {
+			mistake(line, "want 2 columns (adjective, noun), got %d", len(record))
+			continue
+		}
+		adjective, noun := strings.TrimSpace(record[0]), strings.TrimSpace(record[1])
+		if adjective == "" && noun == "" {
+			mistake(line, "needs an adjective or a noun")
+			continue
+		}
+		if adjective != "" {
+			words.Adjectives = append(words.Adjectives, adjective)
+		}
+		if noun != "" {
+			words.Nouns = append(words.Nouns, noun)
+		}
+	}
+
+	if mistakes > maxWordListErrors {
+		errs = append(errs, fmt.Errorf("and %s more", pluralize(mistakes-maxWordListErrors, "mistake")))
+	}
+	return words, errors.Join(errs...)
+}
+
+// loadGenerator loads the given .csv file path into the bird generator, replacing the lists loaded before.
+// An empty path loads the built-in birdgen.csv. If the file has any mistakes, the lists loaded before are kept.
+func loadGenerator(file string) error {
+	var r io.Reader = bytes.NewReader(embeddedGenerator)
+	name := "built-in birdgen.csv"
+	if file != "" {
+		//Opening reader with .csv file
+		f, err := os.Open(file)
+		//Error checking
+		if err != nil {
+			return fmt.Errorf("opening bird generator file: %w", err)
+		}
+		defer f.Close()
+		r, name = f, filepath.Base(file)
+	}
+
+	words, err := parseWordLists(r, name)
+	//Error checking
+	if err != nil {
+		return err
+	}
+	if !words.complete() {
+		return fmt.Errorf("%s needs at least one adjective and one noun", name)
+	}
+
+	generatorMu.Lock()
+	generatorWords = words
+	generatorMu.Unlock()
+	return nil
+}
+
+// watchGenerator reloads the bird generator whenever the configured bird generator file changes. The built-in lists
+// never change, so nothing is checked while it is unset. It never returns, so it is started with go.
+func watchGenerator() {
+	var last time.Time
+	if file := Conf().GeneratorFile; file != "" {
+		if info, err := os.Stat(file); err == nil {
+			last = info.ModTime()
+		}
+	}
+	for range time.Tick(generatorPollInterval) {
+		// Read every time, since !admin reload may change it
+		if file := Conf().GeneratorFile; file != "" {
+			last = checkGenerator(file, last)
+		}
+	}
+}
+
+// checkGenerator reloads the bird generator if file was modified at another time than last, and returns the time it
+// was modified. A file with mistakes is logged once, and the lists loaded before are kept until it changes again.
+func checkGenerator(file string, last time.Time) time.Time {
+	info, err := os.Stat(file)
+	// Error handling
+	if err != nil {
+		// Editors may briefly remove the file while saving it, so it is tried again next time
+		logger.Debug("checking bird generator file", slog.Any("err", err))
+		return last
+	}
+	if info.ModTime().Equal(last) {
+		return last
+	}
+
+	err = loadGenerator(file)
+	// Error handling
+	if err != nil {
+		logger.Error("reloading bird generator, keeping the old lists", slog.Any("err", err))
+		return info.ModTime()
+	}
+	words := defaultWords()
+	logger.Info("reloaded bird generator", slog.Int("adjectives", len(words.Adjectives)), slog.Int("nouns", len(words.Nouns)))
+	return info.ModTime()
+}
+
+// runGenerate handles !generate [0-3], which generates a bird name from the guild's word lists, and
+// !generate upload <merge|replace> and !generate reset, which change them.
+func runGenerate(s Sender, inv *Invocation) error {
+	if len(inv.Args) > 0 {
+		switch inv.Args[0] {
+		case "upload":
+			return uploadWords(s, inv)
+		case "reset":
+			err := requireManageServer(s, inv)
+			// Error handling
+			if err != nil {
+				return err
+			}
+			err = store.Update(inv.GuildID, func(g *GuildSettings) error {
+				g.Words, g.ReplaceWords = nil, false
+				return nil
+			})
+			// Error handling
+			if err != nil {
+				return err
+			}
+			inv.Log.Info("reset guild word lists")
+			inv.send(s, tr(inv.Locale, "This server's !generate uses the default lists again."))
+			return nil
+		}
+	}
+
+	// Using -1 if the user did not input an optional argument, so that the number of adjectives is random
+	count := "-1"
+	if len(inv.Args) > 0 {
+		count = inv.Args[0]
+	}
+	//Converting optional argument to integer
+	i, err := strconv.Atoi(count)
+	// Error handling
+	if err != nil {
+		inv.Log.Debug("ignoring non-numeric adjective count", slog.Any("err", err))
+		i = -1
+	}
+
+	// Without any words, there is nothing to generate from
+	words := guildWords(inv.GuildID)
+	if !words.complete() {
+		return usageErrorf("!generate is turned off because FlaminGo has no bird generator lists loaded")
+	}
+	inv.send(s, GenerateBird(words, i))
+	return nil
+}
+
+// uploadWords handles !generate upload <merge|replace>, saving the attached .csv file as the guild's word lists.
+func uploadWords(s Sender, inv *Invocation) error {
+	err := requireManageServer(s, inv)
+	// Error handling
+	if err != nil {
+		return err
+	}
+
+	replace := false
+	if len(inv.Args) > 1 {
+		switch inv.Args[1] {
+		case "merge":
+		case "replace":
+			replace = true
+		default:
+			return usageErrorf("%s", generateUsage)
+		}
+	}
+
+	a := csvAttachment(inv.Attachments)
+	if a == nil {
+		return usageErrorf("attach a .csv file with an adjective and a noun on each line")
+	}
+	data, err := downloadAttachment(inv.Context(), a)
+	// Error handling
+	if err != nil {
+		return err
+	}
+
+	words, err := parseWordLists(bytes.NewReader(data), a.Filename)
+	// Error handling
+	if err != nil {
+		return usageErrorf("fix these mistakes and upload it again:\n%s", err)
+	}
+	if replace && !words.complete() {
+		return usageErrorf("replacing the default lists needs at least one adjective and one noun")
+	}
+	if len(words.Adjectives) == 0 && len(words.Nouns) == 0 {
+		return usageErrorf("attach a .csv file with an adjective and a noun on each line")
+	}
+
+	err = store.Update(inv.GuildID, func(g *GuildSettings) error {
+		g.Words, g.ReplaceWords = &words, replace
+		return nil
+	})
+	// Error handling
+	if err != nil {
+		return err
+	}
+	inv.Log.Info("saved guild word lists", slog.Int("adjectives", len(words.Adjectives)), slog.Int("nouns", len(words.Nouns)),
+		slog.Bool("replace", replace))
+
+	adjectives, nouns := pluralizeIn(inv.Locale, len(words.Adjectives), "adjective"), pluralizeIn(inv.Locale, len(words.Nouns), "noun")
+	if replace {
+		inv.send(s, tr(inv.Locale, "Saved %s and %s. This server's !generate now uses only these.", adjectives, nouns))
+	} else {
+		inv.send(s, tr(inv.Locale, "Saved %s and %s. This server's !generate now uses them along with the default lists.", adjectives, nouns))
+	}
+	return nil
+}
+
+// csvAttachment returns the first attachment named *.csv, or nil if there is none.
+func csvAttachment(attachments []*discordgo.MessageAttachment) *discordgo.MessageAttachment {
+	for _, a := range attachments {
+		if strings.EqualFold(filepath.Ext(a.Filename), ".csv") {
+			return a
+		}
+	}
+	return nil
+}
+
+// downloadAttachment returns the contents of a word list file attached to a message,
+// refusing files larger than maxWordListUpload.
+func downloadAttachment(ctx context.Context, a *discordgo.MessageAttachment) ([]byte, error) {
+	tooLarge := usageErrorf("the file is larger than %d KB", maxWordListUpload>>10)
+	if a.Size > maxWordListUpload {
+		return nil, tooLarge
+	}
+
+	//Creating HTTP request, cancelled with ctx
+	req, err := http.NewRequestWithContext(ctx, "GET", a.URL, nil)
+	// Error handling
+	if err != nil {
+		return nil, fmt.Errorf("creating attachment request: %w", err)
+	}
+	res, err := http.DefaultClient.Do(req)
+	// Error handling
+	if err != nil {
+		return nil, fmt.Errorf("downloading attachment: %w", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("downloading attachment: %s", res.Status)
+	}
+
+	// The size Discord reports is checked above, but the download is limited too
+	data, err := io.ReadAll(io.LimitReader(res.Body, maxWordListUpload+1))
+	// Error handling
+	if err != nil {
+		return nil, fmt.Errorf("reading attachment: %w", err)
+	}
+	if len(data) > maxWordListUpload {
+		return nil, tooLarge
+	}
+	return data, nil
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// useDefaultWords loads the built-in lists as the default lists, and loads them again after the test in case it
+// changed them.
+func useDefaultWords(t *testing.T) {
+	t.Helper()
+	if err := loadGenerator(""); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { loadGenerator("") })
+}
+
+// sendWithAttachment is sendAsAdmin for a message with a .csv file attached, served from a test server.
+func sendWithAttachment(t *testing.T, content, filename, csv string) []sentMessage {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(csv))
+	}))
+	t.Cleanup(srv.Close)
+
+	m := newMessage(content)
+	m.Attachments = []*discordgo.MessageAttachment{{Filename: filename, URL: srv.URL + "/" + filename, Size: len(csv)}}
+	s := &fakeSession{permissions: discordgo.PermissionManageServer}
+	handleMessage(s, m)
+	commandPool.wait()
+	return s.messages()
+}
+
+func TestParseWordListsErrors(t *testing.T) {
+	words, err := parseWordLists(strings.NewReader("yellow,warbler\nlonely\n,\nspotted,\n,towhee\nsnowy,owl,extra\n"), "test.csv")
+	if err == nil {
+		t.Fatal("no error for a file with mistakes")
+	}
+	for _, want := range []string{
+		"test.csv line 2: want 2 columns (adjective, noun), got 1",
+		"test.csv line 3: needs an adjective or a noun",
+		"test.csv line 6: want 2 columns (adjective, noun), got 3",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not contain %q", err, want)
+		}
+	}
+	if strings.Join(words.Adjectives, "|") != "yellow|spotted" || strings.Join(words.Nouns, "|") != "warbler|towhee" {
+		t.Errorf("unexpected lists %+v", words)
+	}
+
+	_, err = parseWordLists(strings.NewReader("a,b\n\"unclosed,quote\n"), "test.csv")
+	if err == nil || !strings.HasPrefix(err.Error(), "test.csv line 2: ") {
+		t.Errorf("got %v, want a quote error on line 2", err)
+	}
+
+	_, err = parseWordLists(strings.NewReader(strings.Repeat("lonely\n", 15)), "test.csv")
+	if err == nil || !strings.HasSuffix(err.Error(), "and 5 mistakes more") {
+		t.Errorf("got %v, want the extra mistakes counted", err)
+	}
+}
+
+func TestCheckGeneratorReload(t *testing.T) {
+	useDefaultWords(t)
+	file := filepath.Join(t.TempDir(), "birdgen.csv")
+	write := func(content string, modified time.Time) {
+		t.Helper()
+		if err := os.WriteFile(file, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(file, modified, modified); err != nil {
+			t.Fatal(err)
+		}
+	}
+	start := time.Date(2022, time.October, 12, 15, 0, 0, 0, time.UTC)
+
+	write("fancy,flamingo\n", start)
+	last := checkGenerator(file, time.Time{})
+	if words := defaultWords(); !last.Equal(start) || strings.Join(words.Nouns, "|") != "flamingo" {
+		t.Fatalf("file not loaded: %s, %+v", last, words)
+	}
+
+	// Mistakes keep the lists loaded before
+	write("fancy\n", start.Add(time.Minute))
+	last = checkGenerator(file, last)
+	if words := defaultWords(); !last.Equal(start.Add(time.Minute)) || strings.Join(words.Nouns, "|") != "flamingo" {
+		t.Errorf("broken file replaced the lists: %s, %+v", last, words)
+	}
+
+	write("plain,pigeon\n", start.Add(2*time.Minute))
+	checkGenerator(file, last)
+	if words := defaultWords(); strings.Join(words.Nouns, "|") != "pigeon" {
+		t.Errorf("fixed file not loaded: %+v", words)
+	}
+}
+
+func TestLoadGeneratorBuiltIn(t *testing.T) {
+	useDefaultWords(t)
+	// The built-in lists do not depend on the working directory
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	generatorMu.Lock()
+	generatorWords = WordLists{}
+	generatorMu.Unlock()
+	if err := loadGenerator(""); err != nil {
+		t.Fatal(err)
+	}
+	if words := defaultWords(); !words.complete() {
+		t.Errorf("built-in lists are empty: %d adjectives, %d nouns", len(words.Adjectives), len(words.Nouns))
+	}
+	if err := loadGenerator("birdgen.csv"); err == nil {
+		t.Error("loaded a missing file")
+	}
+}
+
+func TestGenerateRefusesEmptyLists(t *testing.T) {
+	newFakeEBird(t)
+	useTempStore(t)
+	useDefaultWords(t)
+	generatorMu.Lock()
+	generatorWords = WordLists{}
+	generatorMu.Unlock()
+
+	sent := send(t, "!generate 2")
+	if len(sent) != 1 || sent[0].Content != "Error: !generate is turned off because FlaminGo has no bird generator lists loaded" {
+		t.Errorf("unexpected reply %+v", sent)
+	}
+}
+
+func TestGenerateUpload(t *testing.T) {
+	newFakeEBird(t)
+	useTempStore(t)
+	useDefaultWords(t)
+	defaults := defaultWords()
+
+	sent := sendWithAttachment(t, "!generate upload replace", "club.csv", "clubhouse,duck\n")
+	if len(sent) != 1 || sent[0].Content != "Saved 1 adjective and 1 noun. This server's !generate now uses only these." {
+		t.Fatalf("unexpected reply %+v", sent)
+	}
+	if sent := send(t, "!generate 1"); len(sent) != 1 || sent[0].Content != "Clubhouse Duck" {
+		t.Errorf("replaced lists not used: %+v", sent)
+	}
+
+	sent = sendWithAttachment(t, "!generate upload", "club.csv", "clubhouse,duck\nmuddy,\n")
+	if len(sent) != 1 || !strings.HasSuffix(sent[0].Content, "now uses them along with the default lists.") {
+		t.Fatalf("unexpected reply %+v", sent)
+	}
+	words := guildWords("g1")
+	if len(words.Adjectives) != len(defaults.Adjectives)+2 || len(words.Nouns) != len(defaults.Nouns)+1 {
+		t.Errorf("lists not merged: %d adjectives, %d nouns", len(words.Adjectives), len(words.Nouns))
+	}
+	if len(guildWords("g2").Nouns) != len(defaults.Nouns) {
+		t.Error("another guild got the uploaded lists")
+	}
+
+	if sent := sendAsAdmin(t, "!generate reset"); len(sent) != 1 || sent[0].Content != "This server's !generate uses the default lists again." {
+		t.Errorf("unexpected reply %+v", sent)
+	}
+	if len(guildWords("g1").Nouns) != len(defaults.Nouns) {
+		t.Error("reset kept the uploaded lists")
+	}
+}
+
+func TestGenerateUploadErrors(t *testing.T) {
+	newFakeEBird(t)
+	useTempStore(t)
+	useDefaultWords(t)
+
+	tests := []struct {
+		content, filename, csv string
+		want                   string
+	}{
+		{"!generate upload", "club.txt", "a,b\n", "Error: attach a .csv file with an adjective and a noun on each line"},
+		{"!generate upload sideways", "club.csv", "a,b\n", "Error: use `!generate [0-3]`"},
+		{"!generate upload", "club.csv", "a,b\nc\n", "Error: fix these mistakes and upload it again:\nclub.csv line 2: want 2 columns (adjective, noun), got 1"},
+		{"!generate upload replace", "club.csv", "a,\n", "Error: replacing the default lists needs at least one adjective and one noun"},
+		{"!generate upload", "club.csv", strings.Repeat("a,b\n", 70000), "Error: the file is larger than 256 KB"},
+	}
+	for _, tt := range tests {
+		sent := sendWithAttachment(t, tt.content, tt.filename, tt.csv)
+		if len(sent) != 1 || !strings.HasPrefix(sent[0].Content, tt.want) {
+			t.Errorf("%s %s: unexpected reply %+v", tt.content, tt.filename, sent)
+		}
+	}
+	if store.Guild("g1").Words != nil {
+		t.Error("a refused upload was saved")
+	}
+
+	// Only server managers can change the lists
+	if sent := send(t, "!generate reset"); len(sent) != 1 || !strings.Contains(sent[0].Content, "Manage Server") {
+		t.Errorf("unexpected reply %+v", sent)
+	}
+}
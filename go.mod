@@ -1,10 +1,12 @@
 module github.com/R1V3N/FlaminGo
 
-go 1.18
+go 1.21
 
 require (
 	github.com/bwmarrin/discordgo v0.25.0
+	github.com/gocolly/colly v1.2.0
 	github.com/joho/godotenv v1.4.0
+	golang.org/x/text v0.3.6
 )
 
 require (
@@ -14,7 +16,6 @@ require (
 	github.com/antchfx/xmlquery v1.3.11 // indirect
 	github.com/antchfx/xpath v1.2.1 // indirect
 	github.com/gobwas/glob v0.2.3 // indirect
-	github.com/gocolly/colly v1.2.0 // indirect
 	github.com/golang/groupcache v0.0.0-20200121045136-8c9f03a8e57e // indirect
 	github.com/golang/protobuf v1.3.1 // indirect
 	github.com/gorilla/websocket v1.4.2 // indirect
@@ -24,6 +25,5 @@ require (
 	golang.org/x/crypto v0.0.0-20210421170649-83a5a9bb288b // indirect
 	golang.org/x/net v0.0.0-20210916014120-12bc252f5db8 // indirect
 	golang.org/x/sys v0.0.0-20210423082822-04245dca01da // indirect
-	golang.org/x/text v0.3.6 // indirect
 	google.golang.org/appengine v1.6.7 // indirect
 )
@@ -0,0 +1,469 @@
+// Guildconfig contains the !config command, which lets server admins change FlaminGo's settings for their server
+
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// defaultPrefix starts every command unless a guild picks its own.
+const defaultPrefix = "!"
+
+// defaultEmbedColor is FlaminGo's pink, used for embeds unless a guild picks its own color.
+const defaultEmbedColor = 16711833
+
+// commandNames lists every command, for enabling and disabling them per guild.
+var commandNames = []string{"flamingo", "get", "rare", "region", "checklists", "checklist", "top100", "stats", "nearest", "season",
+	"history", "onthisday", "hotspots", "locations", "locale", "config", "bird", "generate", "trip"}
+
+// alwaysEnabled are the commands a guild cannot turn off, so admins can always get help and undo their settings.
+var alwaysEnabled = map[string]bool{"flamingo": true, "config": true}
+
+// guildSetting is a setting admins can change with !config.
+type guildSetting struct {
+	name        string
+	description string
+	// get returns the setting's current value, and whether it was set for the guild rather than left at its default.
+	get func(guildID string, g GuildSettings) (string, bool)
+	// set parses value and stores it in g. An empty value resets the setting to its default.
+	// It runs inside Store.Update, so it must not use the store itself.
+	set func(g *GuildSettings, value string) error
+}
+
+// guildSettings are the settings available through !config, in the order !config list shows them.
+var guildSettings = []guildSetting{
+	{
+		name:        "prefix",
+		description: "the text commands start with",
+		get: func(_ string, g GuildSettings) (string, bool) {
+			if g.Prefix == "" {
+				return defaultPrefix, false
+			}
+			return g.Prefix, true
+		},
+		set: func(g *GuildSettings, value string) error {
+			if len(value) > 5 || strings.ContainsAny(value, " \t`") {
+				return usageErrorf("a prefix must be 1 to 5 characters without spaces or backticks")
+			}
+			g.Prefix = value
+			return nil
+		},
+	},
+	{
+		name:        "location",
+		description: "the location used when a command is given none",
+		get: func(guildID string, g GuildSettings) (string, bool) {
+			if g.DefaultLocation == "" {
+				return builtinDefaultLocationName(guildID), false
+			}
+			return g.DefaultLocation, true
+		},
+		set: func(g *GuildSettings, value string) error {
+			if value == "" {
+				g.DefaultLocation = ""
+				return nil
+			}
+			if _, ok := locationIn(*g, value); !ok {
+				return usageErrorf("'%s' is not a known location, see `!locations`", value)
+			}
+			g.DefaultLocation = value
+			return nil
+		},
+	},
+	{
+		name:        "radius",
+		description: "the default search radius of !get and !hotspots in km",
+		get: func(guildID string, g GuildSettings) (string, bool) {
+			return strconv.Itoa(guildRadius(guildID)), g.Radius != 0
+		},
+		set: func(g *GuildSettings, value string) error {
+			if value == "" {
+				g.Radius = 0
+				return nil
+			}
+			r, err := intOption(map[string]string{"radius": value}, "radius", 1, 50, 0)
+			// Error handling
+			if err != nil {
+				return err
+			}
+			g.Radius = r
+			return nil
+		},
+	},
+	{
+		name:        "rare_multiplier",
+		description: "how many times the radius !rare searches, since rare sightings are few",
+		get: func(_ string, g GuildSettings) (string, bool) {
+			if g.RareMultiplier == 0 {
+				return strconv.FormatFloat(defaultRareMultiplier(), 'f', -1, 64), false
+			}
+			return strconv.FormatFloat(g.RareMultiplier, 'f', -1, 64), true
+		},
+		set: func(g *GuildSettings, value string) error {
+			if value == "" {
+				g.RareMultiplier = 0
+				return nil
+			}
+			m, err := strconv.ParseFloat(value, 64)
+			// Error handling
+			if err != nil || m < 1 || m > 10 {
+				return usageErrorf("'rare_multiplier' must be a number from 1 to 10, got '%s'", value)
+			}
+			g.RareMultiplier = m
+			return nil
+		},
+	},
+	{
+		name:        "color",
+		description: "the color of FlaminGo's embeds, e.g. #ff0099",
+		get: func(guildID string, g GuildSettings) (string, bool) {
+			return fmt.Sprintf("#%06x", embedColor(guildID)), g.EmbedColor != 0
+		},
+		set: func(g *GuildSettings, value string) error {
+			if value == "" {
+				g.EmbedColor = 0
+				return nil
+			}
+			hex := strings.TrimPrefix(strings.TrimPrefix(value, "#"), "0x")
+			c, err := strconv.ParseUint(hex, 16, 32)
+			// Error handling
+			if err != nil || len(hex) != 6 {
+				return usageErrorf("'%s' is not a color, use six hex digits such as #ff0099", value)
+			}
+			// Discord shows a color of 0 as no color, so black is stored as the closest color it does show
+			if c == 0 {
+				c = 1
+			}
+			g.EmbedColor = int(c)
+			return nil
+		},
+	},
+	{
+		name:        "locale",
+		description: "the server's language for replies and species names",
+		get: func(_ string, g GuildSettings) (string, bool) {
+			if g.Locale == "" {
+				return defaultLocale, false
+			}
+			return g.Locale, true
+		},
+		set: func(g *GuildSettings, value string) error {
+			if value == "" {
+				g.Locale = ""
+				return nil
+			}
+			l, err := parseLocale(value)
+			// Error handling
+			if err != nil {
+				return err
+			}
+			g.Locale = l
+			return nil
+		},
+	},
+	{
+		name:        "timezone",
+		description: "the time zone trip times are in, e.g. America/New_York",
+		get: func(guildID string, g GuildSettings) (string, bool) {
+			tz := guildTimeZone(guildID)
+			// The system's time zone is only called Local, so its abbreviation is shown instead
+			if tz == time.Local {
+				return now().Format("MST"), false
+			}
+			return tz.String(), g.TimeZone != ""
+		},
+		set: func(g *GuildSettings, value string) error {
+			if value == "" {
+				g.TimeZone = ""
+				return nil
+			}
+			tz, err := parseTimeZone(value)
+			// Error handling
+			if err != nil {
+				return err
+			}
+			g.TimeZone = tz
+			return nil
+		},
+	},
+	{
+		name:        "announcements",
+		description: "the channel that gets announcements about FlaminGo, e.g. #birding",
+		get: func(_ string, g GuildSettings) (string, bool) {
+			if g.AnnouncementChannel == "" {
+				return "none", false
+			}
+			return "<#" + g.AnnouncementChannel + ">", true
+		},
+		set: func(g *GuildSettings, value string) error {
+			id := strings.TrimSuffix(strings.TrimPrefix(value, "<#"), ">")
+			if strings.Trim(id, "0123456789") != "" {
+				return usageErrorf("'%s' is not a channel, mention one like #birding", value)
+			}
+			g.AnnouncementChannel = id
+			return nil
+		},
+	},
+	{
+		name:        "commands",
+		description: "the commands FlaminGo answers in this server, separated by commas, or 'all'",
+		get: func(_ string, g GuildSettings) (string, bool) {
+			if len(g.EnabledCommands) == 0 {
+				return "all", false
+			}
+			return strings.Join(g.EnabledCommands, ","), true
+		},
+		set: func(g *GuildSettings, value string) error {
+			if value == "" || value == "all" {
+				g.EnabledCommands = nil
+				return nil
+			}
+			var enabled []string
+			for _, c := range strings.Split(value, ",") {
+				c = strings.TrimPrefix(strings.TrimSpace(c), "!")
+				if c == "" {
+					continue
+				}
+				if !isCommandName(c) {
+					return usageErrorf("'%s' is not a FlaminGo command", c)
+				}
+				enabled = append(enabled, c)
+			}
+			// The commands that cannot be turned off are always listed, so the setting reads true
+			for c := range alwaysEnabled {
+				enabled = append(enabled, c)
+			}
+			sort.Strings(enabled)
+			g.EnabledCommands = dedupe(enabled)
+			return nil
+		},
+	},
+}
+
+// configUsage explains the !config command.
+const configUsage = "use `!config list`, `!config get <setting>` or `!config set <setting> <value or reset>`"
+
+// runConfig handles !config list, !config get <setting> and !config set <setting> <value|reset>.
+// Only members with the Manage Server permission may use it.
+func runConfig(s Sender, inv *Invocation) error {
+	err := requireManageServer(s, inv)
+	// Error handling
+	if err != nil {
+		return err
+	}
+
+	// Values are taken as typed, since a prefix may contain a colon
+	var args []string
+	for _, a := range inv.Args {
+		if a != "" {
+			args = append(args, a)
+		}
+	}
+	if len(args) == 0 {
+		return usageErrorf("%s", configUsage)
+	}
+
+	g := store.Guild(inv.GuildID)
+	switch {
+	case args[0] == "list" && len(args) == 1:
+		rString := "**" + tr(inv.Locale, "FlaminGo settings for this server") + ":**\n"
+		for _, setting := range guildSettings {
+			rString += formatGuildSetting(inv, setting, g) + "\n"
+		}
+		inv.send(s, rString)
+	case args[0] == "get" && len(args) == 2:
+		setting, err := findGuildSetting(args[1])
+		// Error handling
+		if err != nil {
+			return err
+		}
+		inv.send(s, formatGuildSetting(inv, setting, g)+"\n"+tr(inv.Locale, setting.description))
+	case args[0] == "set" && len(args) >= 3:
+		setting, err := findGuildSetting(args[1])
+		// Error handling
+		if err != nil {
+			return err
+		}
+		value := strings.Join(args[2:], "")
+		if value == "reset" {
+			value = ""
+		}
+		err = store.Update(inv.GuildID, func(g *GuildSettings) error {
+			return setting.set(g, value)
+		})
+		// Error handling
+		if err != nil {
+			return err
+		}
+		inv.Log.Info("changed guild setting", slog.String("setting", setting.name), slog.String("value", value))
+
+		// The reply uses the new locale if that is what changed
+		inv.Locale = localeFor(inv.GuildID, inv.UserID)
+		inv.send(s, tr(inv.Locale, "Updated: %s", formatGuildSetting(inv, setting, store.Guild(inv.GuildID))))
+	default:
+		return usageErrorf("%s", configUsage)
+	}
+	return nil
+}
+
+// requireManageServer returns a UsageError unless the invocation's user may manage the guild.
+func requireManageServer(s Sender, inv *Invocation) error {
+	if inv.GuildID == "" {
+		return usageErrorf("server settings can only be changed in a server")
+	}
+	perms, err := s.UserChannelPermissions(inv.UserID, inv.ChannelID)
+	// Error handling
+	if err != nil {
+		return fmt.Errorf("checking permissions: %w", err)
+	}
+	// Administrators get every permission, including this one
+	if perms&discordgo.PermissionManageServer == 0 {
+		return usageErrorf("you need the Manage Server permission to change FlaminGo's settings")
+	}
+	return nil
+}
+
+// findGuildSetting returns the !config setting with the given name.
+func findGuildSetting(name string) (guildSetting, error) {
+	var names []string
+	for _, setting := range guildSettings {
+		if setting.name == name {
+			return setting, nil
+		}
+		names = append(names, setting.name)
+	}
+	return guildSetting{}, usageErrorf("'%s' is not a setting, use one of %s", name, strings.Join(names, ", "))
+}
+
+// formatGuildSetting returns a setting and its value for a !config reply, e.g. "`radius`: 5 (default)".
+func formatGuildSetting(inv *Invocation, setting guildSetting, g GuildSettings) string {
+	value, set := setting.get(inv.GuildID, g)
+	if set {
+		return fmt.Sprintf("`%s`: %s", setting.name, value)
+	}
+	return fmt.Sprintf("`%s`: %s %s", setting.name, value, tr(inv.Locale, "(default)"))
+}
+
+// isCommandName reports whether name is one of FlaminGo's commands.
+func isCommandName(name string) bool {
+	for _, c := range commandNames {
+		if c == name {
+			return true
+		}
+	}
+	return false
+}
+
+// commandEnabled reports whether a guild has FlaminGo answer a command. Direct messages have every command,
+// and guilds cannot turn off !admin, which only answers FlaminGo's owners anyway.
+func commandEnabled(guildID, command string) bool {
+	if guildID == "" || alwaysEnabled[command] || command == "admin" {
+		return true
+	}
+	enabled := store.Guild(guildID).EnabledCommands
+	if len(enabled) == 0 {
+		return true
+	}
+	for _, c := range enabled {
+		if c == command {
+			return true
+		}
+	}
+	return false
+}
+
+// guildPrefix returns the text commands start with in a guild. Direct messages use the default prefix.
+func guildPrefix(guildID string) string {
+	if guildID != "" {
+		if p := store.Guild(guildID).Prefix; p != "" {
+			return p
+		}
+	}
+	return defaultPrefix
+}
+
+// guildRadius returns the default search radius in km for a guild.
+func guildRadius(guildID string) int {
+	if r := store.Guild(guildID).Radius; r != 0 {
+		return r
+	}
+	return Conf().Radius
+}
+
+// defaultRareMultiplier is how many times the radius !rare searches when a guild has not picked a multiplier, from the
+// configured radii.
+func defaultRareMultiplier() float64 {
+	c := Conf()
+	return float64(c.RareRadius) / float64(c.Radius)
+}
+
+// guildRareRadius returns the default !rare search radius in km for a guild: its radius times its rare multiplier,
+// capped at the 50 km eBird allows.
+func guildRareRadius(guildID string) int {
+	g := store.Guild(guildID)
+	if g.Radius == 0 && g.RareMultiplier == 0 {
+		return Conf().RareRadius
+	}
+	m := g.RareMultiplier
+	if m == 0 {
+		m = defaultRareMultiplier()
+	}
+	r := int(math.Round(float64(guildRadius(guildID)) * m))
+	if r > 50 {
+		r = 50
+	}
+	return r
+}
+
+// parseTimeZone returns the IANA name of a time zone typed by a user. Commands are lowercased, so names such as
+// america/new_york and utc are put back in the case the time zone database uses.
+func parseTimeZone(value string) (string, error) {
+	titled := []rune(value)
+	for i, r := range titled {
+		if i == 0 || strings.ContainsRune("/_-", titled[i-1]) {
+			titled[i] = unicode.ToUpper(r)
+		}
+	}
+	for _, name := range []string{value, string(titled), strings.ToUpper(value)} {
+		if _, err := time.LoadLocation(name); err == nil {
+			return name, nil
+		}
+	}
+	return "", usageErrorf("'%s' is not a time zone, use a name such as America/New_York", value)
+}
+
+// guildTimeZone returns the time zone a guild's trips are planned in: its own, the configured one, or the system's.
+func guildTimeZone(guildID string) *time.Location {
+	name := store.Guild(guildID).TimeZone
+	if name == "" {
+		name = Conf().TimeZone
+	}
+	if name == "" {
+		return time.Local
+	}
+	tz, err := time.LoadLocation(name)
+	// Error handling
+	if err != nil {
+		// Names are checked when they are set, so this only happens if the time zone database changed
+		logger.Warn("loading time zone, using the system's", slog.String("guild", guildID), slog.Any("err", err))
+		return time.Local
+	}
+	return tz
+}
+
+// embedColor returns the color of a guild's embeds.
+func embedColor(guildID string) int {
+	if c := store.Guild(guildID).EmbedColor; c != 0 {
+		return c
+	}
+	return defaultEmbedColor
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestConfigNeedsManageServer(t *testing.T) {
+	newFakeEBird(t)
+	useTempStore(t)
+
+	sent := send(t, "!config set radius 10")
+	if len(sent) != 1 || sent[0].Content != "Error: you need the Manage Server permission to change FlaminGo's settings" {
+		t.Fatalf("unexpected reply %+v", sent)
+	}
+	if store.Guild("g1").Radius != 0 {
+		t.Error("radius changed without permission")
+	}
+}
+
+func TestConfigRadius(t *testing.T) {
+	f := newFakeEBird(t)
+	useTempStore(t)
+	f.route("/data/obs/geo/recent", "recent.json")
+	f.route("/data/obs/geo/recent/notable", "notable.json")
+
+	sent := sendAsAdmin(t, "!config set radius 10")
+	if len(sent) != 1 || sent[0].Content != "Updated: `radius`: 10" {
+		t.Fatalf("unexpected reply %+v", sent)
+	}
+	send(t, "!get rit")
+	if got := f.lastRequest().URL.Query().Get("dist"); got != "10" {
+		t.Errorf("dist = %q, want 10", got)
+	}
+
+	// !rare keeps searching three times as far, until the multiplier changes
+	send(t, "!rare rit")
+	if got := f.lastRequest().URL.Query().Get("dist"); got != "30" {
+		t.Errorf("rare dist = %q, want 30", got)
+	}
+	sendAsAdmin(t, "!config set rare_multiplier 2.5")
+	send(t, "!rare rit")
+	if got := f.lastRequest().URL.Query().Get("dist"); got != "25" {
+		t.Errorf("rare dist = %q, want 25", got)
+	}
+
+	// reset goes back to the default
+	sent = sendAsAdmin(t, "!config set radius reset")
+	if len(sent) != 1 || sent[0].Content != "Updated: `radius`: 5 (default)" {
+		t.Fatalf("unexpected reply %+v", sent)
+	}
+}
+
+func TestConfigLocationAndColor(t *testing.T) {
+	f := newFakeEBird(t)
+	useTempStore(t)
+	f.route("/data/obs/geo/recent", "recent.json")
+	changeConf(t, func(c *Config) { c.TimeZone = "America/New_York" })
+
+	sendAsAdmin(t, "!config set location mendon")
+	sent := send(t, "!get")
+	if len(sent) != 1 || !strings.HasPrefix(sent[0].Content, "**Verified eBird sightings within 5 km of Mendon Ponds Park") {
+		t.Errorf("unexpected reply %+v", sent)
+	}
+
+	sendAsAdmin(t, "!config set color #00ff00")
+	sent = send(t, "!flamingo")
+	if len(sent) != 1 || sent[0].Embed == nil || sent[0].Embed.Color != 0x00ff00 {
+		t.Errorf("unexpected help %+v", sent)
+	}
+
+	sent = sendAsAdmin(t, "!config list")
+	want := "**FlaminGo settings for this server:**\n" +
+		"`prefix`: ! (default)\n" +
+		"`location`: mendon\n" +
+		"`radius`: 5 (default)\n" +
+		"`rare_multiplier`: 3 (default)\n" +
+		"`color`: #00ff00\n" +
+		"`locale`: en (default)\n" +
+		"`timezone`: America/New_York (default)\n" +
+		"`announcements`: none (default)\n" +
+		"`commands`: all (default)\n"
+	if len(sent) != 1 || sent[0].Content != want {
+		t.Errorf("unexpected list %+v", sent)
+	}
+}
+
+func TestConfigCommands(t *testing.T) {
+	f := newFakeEBird(t)
+	useTempStore(t)
+	f.route("/data/obs/geo/recent/notable", "notable.json")
+
+	sent := sendAsAdmin(t, "!config set commands rare, nearest")
+	if len(sent) != 1 || sent[0].Content != "Updated: `commands`: config,flamingo,nearest,rare" {
+		t.Fatalf("unexpected reply %+v", sent)
+	}
+
+	// Disabled commands are ignored, so another bot can answer them
+	if sent := send(t, "!get rit"); len(sent) != 0 {
+		t.Errorf("disabled command replied %+v", sent)
+	}
+	if sent := send(t, "!rare rit"); len(sent) != 1 {
+		t.Errorf("enabled command sent %d messages", len(sent))
+	}
+
+	sendAsAdmin(t, "!config set commands all")
+	if sent := send(t, "!locations"); len(sent) != 1 {
+		t.Errorf("re-enabled command sent %d messages", len(sent))
+	}
+}
+
+func TestConfigErrors(t *testing.T) {
+	newFakeEBird(t)
+	useTempStore(t)
+
+	tests := map[string]string{
+		"!config":                       "Error: use `!config list`",
+		"!config set shape round":       "Error: 'shape' is not a setting, use one of prefix, location, radius",
+		"!config set radius 99":         "Error: 'radius' must be a whole number from 1 to 50, got '99'",
+		"!config set rare_multiplier 0": "Error: 'rare_multiplier' must be a number from 1 to 10",
+		"!config set color pink":        "Error: 'pink' is not a color",
+		"!config set location atlantis": "Error: 'atlantis' is not a known location",
+		"!config set commands get,fly":  "Error: 'fly' is not a FlaminGo command",
+		"!config set locale tlh":        "Error: 'tlh' is not a supported language",
+		"!config set prefix toolong":    "Error: a prefix must be 1 to 5 characters",
+	}
+	for msg, want := range tests {
+		sent := sendAsAdmin(t, msg)
+		if len(sent) != 1 || !strings.HasPrefix(sent[0].Content, want) {
+			t.Errorf("%s: got %+v, want prefix %q", msg, sent, want)
+		}
+	}
+}
@@ -0,0 +1,217 @@
+// History contains the !history and !onthisday commands comparing a date's sightings across years
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// YearSightings holds the historic sightings at a place on one date.
+type YearSightings struct {
+	// Date is the date the sightings are from.
+	Date time.Time
+	// Sightings holds the most recent sighting of each species that day.
+	Sightings []BirdSighting
+}
+
+// Limits for the number of years compared.
+const (
+	defaultHistoryYears = 5
+	maxHistoryYears     = 10
+	// historyConcurrency is the most historic requests sent to eBird at once.
+	historyConcurrency = 4
+)
+
+// Usage messages for the history commands.
+const (
+	historyUsage   = "use `!history <location or region:code> <YYYY-MM-DD> [years:2-10]`"
+	onThisDayUsage = "use `!onthisday <location or region:code> [2-10]`"
+)
+
+// runHistory handles !history <location> <date> [years:n].
+func runHistory(s Sender, inv *Invocation) error {
+	ctx := inv.Context()
+	positional, options := splitOptions(inv.Args)
+	code, name, positional, err := codeArg(ctx, inv.GuildID, "history", positional, options)
+	// Error handling
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return usageErrorf("%s", historyUsage)
+	}
+	date, err := parseDate(positional[0])
+	// Error handling
+	if err != nil {
+		return err
+	}
+	years, err := intOption(options, "years", 2, maxHistoryYears, defaultHistoryYears)
+	// Error handling
+	if err != nil {
+		return err
+	}
+
+	h, err := GetHistory(ctx, code, date, years, inv.Locale)
+	// Error handling
+	if err != nil {
+		return err
+	}
+	inv.send(s, FormatHistory(name, h, inv.Locale))
+	return nil
+}
+
+// runOnThisDay handles !onthisday <location> [years].
+func runOnThisDay(s Sender, inv *Invocation) error {
+	ctx := inv.Context()
+	positional, options := splitOptions(inv.Args)
+	code, name, positional, err := codeArg(ctx, inv.GuildID, "onthisday", positional, options)
+	// Error handling
+	if err != nil {
+		return err
+	}
+	if len(positional) > 1 {
+		return usageErrorf("%s", onThisDayUsage)
+	}
+	years := defaultHistoryYears
+	if len(positional) == 1 {
+		years, err = intOption(map[string]string{"years": positional[0]}, "years", 2, maxHistoryYears, years)
+		// Error handling
+		if err != nil {
+			return err
+		}
+	}
+
+	h, err := GetHistory(ctx, code, today(), years, inv.Locale)
+	// Error handling
+	if err != nil {
+		return err
+	}
+	inv.send(s, FormatHistory(name, h, inv.Locale))
+	return nil
+}
+
+// GetHistory returns the historic sightings at an eBird location or region on date's month and day, for date's year and
+// the years before it, newest first. Years without that date (February 29th) are skipped.
+func GetHistory(ctx context.Context, code string, date time.Time, years int, locale string) ([]YearSightings, error) {
+	var dates []time.Time
+	for y := 0; y < years; y++ {
+		d := time.Date(date.Year()-y, date.Month(), date.Day(), 0, 0, 0, 0, time.Local)
+		if d.Month() == date.Month() {
+			dates = append(dates, d)
+		}
+	}
+
+	h := make([]YearSightings, len(dates))
+	err := runLimited(len(dates), historyConcurrency, func(i int) error {
+		d := dates[i]
+		h[i].Date = d
+		url := fmt.Sprintf("%s/data/obs/%s/historic/%d/%d/%d?rank=mrec&detail=simple%s", Conf().EBirdURL, code, d.Year(), d.Month(), d.Day(), sppLocaleParam(locale))
+		err := ebirdGet(ctx, url, &h[i].Sightings)
+		// Error handling
+		if err != nil {
+			return fmt.Errorf("getting historic observations for %s on %s: %w", code, d.Format(dateLayout), err)
+		}
+		return nil
+	})
+	// Error handling
+	if err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// FormatHistory returns a compact table of species totals per year, followed by the species new this year and the
+// species seen before but missing this year, in locale. The first entry of h is "this year".
+// Birds are not totalled, since the historic data only has the count of each species' most recent report.
+func FormatHistory(name string, h []YearSightings, locale string) string {
+	rString := tr(locale, "**eBird sightings at %s on %s:**\n", name, monthDay(h[0].Date, locale))
+	rString += fmt.Sprintf("```\n%-5s %8s\n", tr(locale, "Year"), tr(locale, "Species"))
+
+	// seenBefore counts the earlier years each species was seen in
+	seenBefore := make(map[string]int)
+	thisYear := make(map[string]bool)
+	for i, y := range h {
+		species := make(map[string]bool)
+		for _, s := range y.Sightings {
+			species[s.ComName] = true
+		}
+		rString += fmt.Sprintf("%-5d %8d\n", y.Date.Year(), len(species))
+
+		for sp := range species {
+			if i == 0 {
+				thisYear[sp] = true
+			} else {
+				seenBefore[sp]++
+			}
+		}
+	}
+	rString += "```\n"
+
+	var newSpecies []string
+	for sp := range thisYear {
+		if seenBefore[sp] == 0 {
+			newSpecies = append(newSpecies, sp)
+		}
+	}
+	sort.Strings(newSpecies)
+
+	// Species missing this year, the most reliable ones first
+	var missing []string
+	for sp := range seenBefore {
+		if !thisYear[sp] {
+			missing = append(missing, sp)
+		}
+	}
+	sort.Slice(missing, func(i, j int) bool {
+		if seenBefore[missing[i]] != seenBefore[missing[j]] {
+			return seenBefore[missing[i]] > seenBefore[missing[j]]
+		}
+		return missing[i] < missing[j]
+	})
+	for i, sp := range missing {
+		missing[i] = fmt.Sprintf("%s (%d/%d)", sp, seenBefore[sp], len(h)-1)
+	}
+
+	rString += tr(locale, "**New in %d:** %s\n", h[0].Date.Year(), joinOrNone(newSpecies, locale))
+	rString += tr(locale, "**Missing in %d** (earlier years seen): %s\n", h[0].Date.Year(), joinOrNone(missing, locale))
+
+	//Trimming return string to Discord max message length, minus 5 characters to show max length has been reached
+	return truncateText(rString, 1995)
+}
+
+// joinOrNone joins a list with commas, or returns "none" in locale if it is empty.
+func joinOrNone(list []string, locale string) string {
+	if len(list) == 0 {
+		return tr(locale, "none")
+	}
+	return strings.Join(list, ", ")
+}
+
+// codeArg takes the eBird code to query from region: or from the location named by the first positional argument,
+// returning the code, a name for printing, and the remaining arguments.
+func codeArg(ctx context.Context, guildID, command string, positional []string, options map[string]string) (code, name string, rest []string, err error) {
+	if v, ok := options["region"]; ok {
+		code, err = parseRegionCode(v)
+		// Error handling
+		if err != nil {
+			return "", "", nil, err
+		}
+		return code, regionNameOrCode(ctx, code), positional, nil
+	}
+
+	if len(positional) == 0 {
+		return "", "", nil, usageErrorf("!%s needs a location or a region, e.g. `!%s %s` or `!%s region:US-NY-055`", command, command, firstLocationName(), command)
+	}
+	loc, ok := namedLocation(guildID, positional[0])
+	if !ok {
+		return "", "", nil, usageErrorf("'%s' is not a valid option for !%s", positional[0], command)
+	}
+	if loc.code == "" {
+		return "", "", nil, usageErrorf("'%s' has no eBird location code, try a region instead", positional[0])
+	}
+	return loc.code, loc.name, positional[1:], nil
+}
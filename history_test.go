@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestHistory(t *testing.T) {
+	f := newFakeEBird(t)
+	fixNow(t, "2022-10-12")
+	f.route("/data/obs/L976278/historic/2022/10/10", "historic_2022.json")
+	f.route("/data/obs/L976278/historic/2021/10/10", "historic_2021.json")
+	f.route("/data/obs/L976278/historic/2020/10/10", "historic_2020.json")
+
+	sent := send(t, "!history rit 2022-10-10 years:3")
+	want := "**eBird sightings at Rochester Institute of Technology on October 10:**\n" +
+		"```\nYear   Species\n" +
+		"2022         2\n" +
+		"2021         3\n" +
+		"2020         1\n" +
+		"```\n" +
+		"**New in 2022:** Snowy Owl\n" +
+		"**Missing in 2022** (earlier years seen): Blue Jay (2/2), American Crow (1/2)\n"
+	if len(sent) != 1 || sent[0].Content != want {
+		t.Fatalf("got %+v, want %q", sent, want)
+	}
+	if q := f.lastRequestTo("/data/obs/L976278/historic/2021/10/10").URL.Query(); q.Get("rank") != "mrec" {
+		t.Errorf("unexpected query %v", q)
+	}
+}
+
+func TestOnThisDay(t *testing.T) {
+	f := newFakeEBird(t)
+	fixNow(t, "2022-10-10")
+	f.route("/data/obs/L976278/historic/2022/10/10", "historic_2022.json")
+	f.route("/data/obs/L976278/historic/2021/10/10", "historic_2021.json")
+
+	sent := send(t, "!onthisday rit 2")
+	if len(sent) != 1 || !strings.Contains(sent[0].Content, "**Missing in 2022** (earlier years seen): American Crow (1/1), Blue Jay (1/1)") {
+		t.Fatalf("unexpected reply %+v", sent)
+	}
+
+	for msg, want := range map[string]string{
+		"!onthisday rit 11": "Error: 'years' must be a whole number from 2 to 10",
+		"!onthisday":        "Error: !onthisday needs a location or a region",
+		"!history rit":      "Error: use `!history",
+	} {
+		sent := send(t, msg)
+		if len(sent) != 1 || !strings.HasPrefix(sent[0].Content, want) {
+			t.Errorf("%s: got %+v, want prefix %q", msg, sent, want)
+		}
+	}
+}
+
+func TestGetHistorySkipsLeapDay(t *testing.T) {
+	f := newFakeEBird(t)
+	fixNow(t, "2024-03-01")
+	f.route("/data/obs/US-NY/historic/2024/2/29", "historic_2020.json")
+	f.route("/data/obs/US-NY/historic/2020/2/29", "historic_2020.json")
+
+	d, _ := parseDate("2024-02-29")
+	h, err := GetHistory(context.Background(), "US-NY", d, 5, "en")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(h) != 2 || h[1].Date.Year() != 2020 {
+		t.Errorf("expected 2024 and 2020 only, got %+v", h)
+	}
+}
@@ -0,0 +1,251 @@
+// Hotspots contains the !hotspots commands for discovering eBird hotspots and saving them as guild locations
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Hotspot holds information about an eBird hotspot from the hotspot geo endpoint.
+type Hotspot struct {
+	LocID             string
+	LocName           string `export:"name"`
+	CountryCode       string
+	Subnational1Code  string
+	Subnational2Code  string
+	Lat               float64 `export:"lat"`
+	Lng               float64 `export:"lng"`
+	LatestObsDt       string  `export:"time"`
+	NumSpeciesAllTime int
+}
+
+// hotspotInfo holds the response of eBird's hotspot info endpoint.
+type hotspotInfo struct {
+	LocID     string
+	Name      string
+	Latitude  float64
+	Longitude float64
+	IsHotspot bool
+}
+
+var (
+	// lastHotspotsMu guards lastHotspots.
+	lastHotspotsMu sync.Mutex
+	// lastHotspots holds the most recent !hotspots near results per channel, so users can save one by its number.
+	lastHotspots = make(map[string][]Hotspot)
+)
+
+// locationKeyPattern matches the names guild locations can be saved under.
+var locationKeyPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,31}$`)
+
+// hotspotsUsage explains the !hotspots subcommands.
+const hotspotsUsage = "use `!hotspots near <lat,long or location> [radius] [export:format]` or `!hotspots save <number or code> <name> [replace]`"
+
+// runHotspots handles the !hotspots near and !hotspots save subcommands.
+func runHotspots(s Sender, inv *Invocation) error {
+	ctx := inv.Context()
+	positional, options := splitOptions(inv.Args)
+	if len(positional) == 0 {
+		return usageErrorf("%s", hotspotsUsage)
+	}
+
+	switch positional[0] {
+	case "near":
+		if len(positional) < 2 || len(positional) > 3 {
+			return usageErrorf("%s", hotspotsUsage)
+		}
+		lat, long, name, err := resolvePlace(ctx, inv.GuildID, positional[1])
+		// Error handling
+		if err != nil {
+			return err
+		}
+		// The radius can follow the place as a number, or be given with radius: like in !get
+		if len(positional) == 3 {
+			if _, ok := options["radius"]; ok {
+				return usageErrorf("give the radius once, either as a number or with 'radius:'")
+			}
+			options["radius"] = positional[2]
+		}
+		radius, err := intOption(options, "radius", 1, 50, guildRadius(inv.GuildID))
+		// Error handling
+		if err != nil {
+			return err
+		}
+		err = checkOptions(options, "hotspots", "radius", "export")
+		// Error handling
+		if err != nil {
+			return err
+		}
+
+		f, err := exportOption(options)
+		// Error handling
+		if err != nil {
+			return err
+		}
+
+		h, err := GetHotspots(ctx, lat, long, radius)
+		// Error handling
+		if err != nil {
+			return err
+		}
+
+		// Remembering the results so they can be saved by number
+		lastHotspotsMu.Lock()
+		lastHotspots[inv.ChannelID] = h
+		lastHotspotsMu.Unlock()
+
+		if f != "" {
+			return inv.sendExport(s, f, "hotspots", fmt.Sprintf("eBird hotspots within %d km of %s", radius, name), h)
+		}
+		inv.send(s, FormatHotspots(name, radius, h, inv.Locale))
+	case "save":
+		err := requireManageServer(s, inv)
+		// Error handling
+		if err != nil {
+			return err
+		}
+		err = checkOptions(options, "hotspots")
+		// Error handling
+		if err != nil {
+			return err
+		}
+		if len(positional) < 3 || len(positional) > 4 || len(positional) == 4 && positional[3] != "replace" {
+			return usageErrorf("%s", hotspotsUsage)
+		}
+		msg, err := SaveHotspot(ctx, inv.GuildID, inv.ChannelID, positional[1], positional[2], len(positional) == 4, inv.Locale)
+		// Error handling
+		if err != nil {
+			return err
+		}
+		inv.send(s, msg)
+	default:
+		return usageErrorf("'%s' is not a valid option for !hotspots, %s", positional[0], hotspotsUsage)
+	}
+
+	return nil
+}
+
+// resolvePlace turns a "lat,long" pair or a location name into coordinates and a name for printing.
+func resolvePlace(ctx context.Context, guildID, place string) (lat, long float64, name string, err error) {
+	if latStr, longStr, ok := strings.Cut(place, ","); ok {
+		lat, errLat := strconv.ParseFloat(strings.TrimSpace(latStr), 64)
+		long, errLong := strconv.ParseFloat(strings.TrimSpace(longStr), 64)
+		if errLat != nil || errLong != nil || lat < -90 || lat > 90 || long < -180 || long > 180 {
+			return 0, 0, "", usageErrorf("'%s' is not a valid lat,long pair", place)
+		}
+		return lat, long, fmt.Sprintf("%.2f,%.2f", lat, long), nil
+	}
+
+	loc, ok := namedLocation(guildID, place)
+	if !ok {
+		return 0, 0, "", usageErrorf("'%s' is not a known location or a lat,long pair", place)
+	}
+	return loc.lat, loc.long, loc.name, nil
+}
+
+// GetHotspots returns the eBird hotspots within radius (km) of the given coordinates,
+// with the most species seen all time first.
+func GetHotspots(ctx context.Context, lat, long float64, radius int) ([]Hotspot, error) {
+	// Creating URL
+	url := fmt.Sprintf("%s/ref/hotspot/geo?lat=%v&lng=%v&dist=%d&fmt=json", Conf().EBirdURL, lat, long, radius)
+
+	var h []Hotspot
+	err := ebirdGet(ctx, url, &h)
+	// Error handling
+	if err != nil {
+		return nil, fmt.Errorf("getting hotspots near %v,%v: %w", lat, long, err)
+	}
+
+	sort.SliceStable(h, func(i, j int) bool {
+		if h[i].NumSpeciesAllTime != h[j].NumSpeciesAllTime {
+			return h[i].NumSpeciesAllTime > h[j].NumSpeciesAllTime
+		}
+		return h[i].LocName < h[j].LocName
+	})
+	return h, nil
+}
+
+// FormatHotspots returns a numbered list of hotspots for a Discord message in locale.
+func FormatHotspots(name string, radius int, h []Hotspot, locale string) string {
+	if len(h) == 0 {
+		return tr(locale, "**No eBird hotspots found within %d km of %s.**", radius, name)
+	}
+
+	rString := tr(locale, "**eBird hotspots within %d km of %s:**\n", radius, name)
+	for i, spot := range h {
+		latest := spot.LatestObsDt
+		if latest == "" {
+			latest = tr(locale, "never")
+		}
+		rString += tr(locale, "%d. %s (`%s`): %d species, latest %s\n", i+1, spot.LocName, spot.LocID, spot.NumSpeciesAllTime, latest)
+	}
+	rString += tr(locale, "Save one with `!hotspots save <number or code> <name>`\n")
+
+	//Trimming return string to Discord max message length, minus 5 characters to show max length has been reached
+	return truncateText(rString, 1995)
+}
+
+// SaveHotspot saves a hotspot as a location for the guild under key, and returns a reply in locale. A location the
+// guild already saved under key is only replaced if replace is set.
+// ref is either a number from the channel's last !hotspots near list, or an eBird location code.
+func SaveHotspot(ctx context.Context, guildID, channelID, ref, key string, replace bool, locale string) (string, error) {
+	if guildID == "" {
+		return "", usageErrorf("locations can only be saved in a server")
+	}
+	key = strings.ToLower(key)
+	if !locationKeyPattern.MatchString(key) {
+		return "", usageErrorf("'%s' is not a valid location name, use up to 32 letters, numbers, '-' and '_'", key)
+	}
+	if _, ok := Locations()[key]; ok {
+		return "", usageErrorf("'%s' is already a built-in location", key)
+	}
+
+	var loc Location
+	if n, err := strconv.Atoi(ref); err == nil {
+		// Looking the number up in the last list shown in this channel
+		lastHotspotsMu.Lock()
+		h := lastHotspots[channelID]
+		lastHotspotsMu.Unlock()
+		if n < 1 || n > len(h) {
+			return "", usageErrorf("there is no hotspot number %d, run `!hotspots near` first", n)
+		}
+		loc = Location{code: h[n-1].LocID, lat: h[n-1].Lat, long: h[n-1].Lng, name: h[n-1].LocName}
+	} else {
+		// Commands are lowercased, but eBird location codes start with an uppercase L
+		code := strings.ToUpper(ref)
+		if !strings.HasPrefix(code, "L") {
+			return "", usageErrorf("'%s' is not a hotspot number or an eBird location code", ref)
+		}
+
+		var info hotspotInfo
+		err := ebirdGet(ctx, fmt.Sprintf("%s/ref/hotspot/info/%s", Conf().EBirdURL, code), &info)
+		// Error handling
+		if err != nil {
+			return "", fmt.Errorf("getting hotspot info for %s: %w", code, err)
+		}
+		loc = Location{code: info.LocID, lat: info.Latitude, long: info.Longitude, name: info.Name}
+	}
+
+	err := store.Update(guildID, func(g *GuildSettings) error {
+		if g.Locations == nil {
+			g.Locations = make(map[string]locationEntry)
+		}
+		if _, ok := g.Locations[key]; ok && !replace {
+			return usageErrorf("this server already has a location called '%s', add `replace` to the command to overwrite it", key)
+		}
+		g.Locations[key] = loc.entry(key)
+		return nil
+	})
+	// Error handling
+	if err != nil {
+		return "", err
+	}
+
+	return tr(locale, "Saved **%s** (`%s`) as `%s`. Try it with `!get %s`.", loc.name, loc.code, key, key), nil
+}
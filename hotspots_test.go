@@ -0,0 +1,138 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHotspotsNearAndSave(t *testing.T) {
+	f := newFakeEBird(t)
+	path := useTempStore(t)
+	f.route("/ref/hotspot/geo", "hotspots.json")
+	f.route("/data/obs/geo/recent", "recent.json")
+
+	sent := send(t, "!hotspots near 43.08,-77.67 10")
+	if len(sent) != 1 {
+		t.Fatalf("sent %d messages, want 1", len(sent))
+	}
+	for _, want := range []string{
+		"**eBird hotspots within 10 km of 43.08,-77.67:**",
+		"1. Mendon Ponds Park (`L139800`): 251 species, latest 2022-10-11 16:20",
+		"3. Quiet Pond (`L2000001`): 12 species, latest never",
+	} {
+		if !strings.Contains(sent[0].Content, want) {
+			t.Errorf("reply %q does not contain %q", sent[0].Content, want)
+		}
+	}
+	if q := f.lastRequest().URL.Query(); q.Get("dist") != "10" || q.Get("lat") != "43.08" {
+		t.Errorf("unexpected query %v", q)
+	}
+
+	// radius: works like in !get
+	sent = send(t, "!hotspots near rit radius:20")
+	if len(sent) != 1 || !strings.HasPrefix(sent[0].Content, "**eBird hotspots within 20 km of Rochester Institute of Technology:**") {
+		t.Errorf("unexpected reply %+v", sent)
+	}
+	if q := f.lastRequest().URL.Query(); q.Get("dist") != "20" {
+		t.Errorf("unexpected query %v", q)
+	}
+
+	sent = sendAsAdmin(t, "!hotspots save 3 pond")
+	if len(sent) != 1 || !strings.HasPrefix(sent[0].Content, "Saved **Quiet Pond** (`L2000001`) as `pond`.") {
+		t.Fatalf("unexpected reply %+v", sent)
+	}
+
+	// The saved location works in other commands, and survives reopening the store
+	sent = send(t, "!get pond")
+	if len(sent) != 1 || !strings.Contains(sent[0].Content, "of Quiet Pond in the past") {
+		t.Errorf("saved location not usable: %+v", sent)
+	}
+	st, err := openStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e := st.Guild("g1").Locations["pond"]; e.Code != "L2000001" {
+		t.Errorf("saved location = %+v", e)
+	}
+}
+
+func TestHotspotsSaveByCode(t *testing.T) {
+	f := newFakeEBird(t)
+	useTempStore(t)
+	f.route("/ref/hotspot/info/L2000001", "hotspot_info.json")
+
+	sent := sendAsAdmin(t, "!hotspots save L2000001 pond")
+	if len(sent) != 1 || !strings.HasPrefix(sent[0].Content, "Saved **Quiet Pond**") {
+		t.Fatalf("unexpected reply %+v", sent)
+	}
+	if loc, ok := namedLocation("g1", "pond"); !ok || loc.lat != 43.05 {
+		t.Errorf("location not saved: %+v", loc)
+	}
+	if _, ok := namedLocation("g2", "pond"); ok {
+		t.Error("location leaked into another guild")
+	}
+}
+
+func TestHotspotsSaveOverwrite(t *testing.T) {
+	f := newFakeEBird(t)
+	useTempStore(t)
+	f.route("/ref/hotspot/info/L2000001", "hotspot_info.json")
+	err := store.Update("g1", func(g *GuildSettings) error {
+		g.Locations = map[string]locationEntry{"pond": {Key: "pond", Code: "L123", Lat: 1, Long: 2, Name: "Old Pond"}}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Members without Manage Server cannot save locations at all
+	sent := send(t, "!hotspots save L2000001 park")
+	if len(sent) != 1 || !strings.HasPrefix(sent[0].Content, "Error: you need the Manage Server permission") {
+		t.Errorf("unexpected reply %+v", sent)
+	}
+	if _, ok := namedLocation("g1", "park"); ok {
+		t.Error("location saved without permission")
+	}
+
+	// A saved location is only overwritten when asked to
+	sent = sendAsAdmin(t, "!hotspots save L2000001 pond")
+	if len(sent) != 1 || !strings.HasPrefix(sent[0].Content, "Error: this server already has a location called 'pond'") {
+		t.Errorf("unexpected reply %+v", sent)
+	}
+	if loc, _ := namedLocation("g1", "pond"); loc.name != "Old Pond" {
+		t.Errorf("location overwritten: %+v", loc)
+	}
+	sent = sendAsAdmin(t, "!hotspots save L2000001 pond replace")
+	if len(sent) != 1 || !strings.HasPrefix(sent[0].Content, "Saved **Quiet Pond**") {
+		t.Errorf("unexpected reply %+v", sent)
+	}
+	if loc, _ := namedLocation("g1", "pond"); loc.name != "Quiet Pond" {
+		t.Errorf("location not replaced: %+v", loc)
+	}
+}
+
+func TestHotspotsErrors(t *testing.T) {
+	newFakeEBird(t)
+	useTempStore(t)
+
+	tests := map[string]string{
+		"!hotspots":                     "Error: use `!hotspots near",
+		"!hotspots near 91,0":           "Error: '91,0' is not a valid lat,long pair",
+		"!hotspots near atlantis":       "Error: 'atlantis' is not a known location",
+		"!hotspots save 9 pond":         "Error: there is no hotspot number 9",
+		"!hotspots save L1 rit":         "Error: 'rit' is already a built-in location",
+		"!hotspots save L1 bad/name":    "Error: 'bad/name' is not a valid location name",
+		"!hotspots save nowhere place":  "Error: 'nowhere' is not a hotspot number",
+		"!hotspots save 1 pond keep":    "Error: use `!hotspots near",
+		"!hotspots near rit days:3":     "Error: 'days' is not a valid option for !hotspots",
+		"!hotspots near rit radius:99":  "Error: 'radius' must be a whole number from 1 to 50",
+		"!hotspots near rit 5 radius:5": "Error: give the radius once",
+		"!hotspots save 3 pond sort:x":  "Error: 'sort' is not a valid option for !hotspots",
+	}
+	for msg, want := range tests {
+		sent := sendAsAdmin(t, msg)
+		if len(sent) != 1 || !strings.HasPrefix(sent[0].Content, want) {
+			t.Errorf("%s: got %+v, want prefix %q", msg, sent, want)
+		}
+	}
+}
@@ -0,0 +1,722 @@
+// I18n translates FlaminGo's replies through a message catalog and picks the language for each guild and user
+
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// defaultLocale is the language used when neither the user nor the guild picked one. eBird's names are in English
+// unless asked otherwise.
+const defaultLocale = "en"
+
+// localeNames holds the languages FlaminGo speaks, keyed by the code used in settings and sent to eBird as sppLocale.
+var localeNames = map[string]string{
+	"en": "English",
+	"es": "Español",
+	"fr": "Français",
+}
+
+// catalog holds the translations of FlaminGo's strings, keyed by locale and then by the English format string.
+// Strings missing from a locale are shown in English.
+var catalog = map[string]map[string]string{
+	"es": {
+		// Errors
+		"'sort' must be one of %s, got '%s'":                                                           "'sort' debe ser uno de %s, se recibió '%s'",
+		"'group' must be '%s', got '%s'":                                                               "'group' debe ser '%s', se recibió '%s'",
+		"'%s' is not a valid lat,long pair":                                                            "'%s' no es un par lat,long válido",
+		"'%s' is not a known location or a lat,long pair":                                              "'%s' no es un lugar conocido ni un par lat,long",
+		"'%s' is not an eBird region code like US, US-NY or US-NY-055, find one with `!region search`": "'%s' no es un código de región de eBird como US, US-NY o US-NY-055, busca uno con `!region search`",
+		"'%s' is not a date like 2022-10-10":                                                           "'%s' no es una fecha como 2022-10-10",
+		"'%s' is not a month like 2022-10":                                                             "'%s' no es un mes como 2022-10",
+		"%s is in the future":                                                                          "%s está en el futuro",
+		"the range must not end before it starts":                                                      "el intervalo no puede terminar antes de empezar",
+		"ranges can cover at most %d days, got %d":                                                     "los intervalos pueden abarcar como máximo %d días, se recibieron %d",
+		"'%s' is not a valid option for !nearest":                                                      "'%s' no es una opción válida para !nearest",
+		"Error: %s": "Error: %s",
+		"Sorry, something went wrong while running that command. If this keeps happening, let an admin know the reference code `%s`.": "Lo siento, algo salió mal al ejecutar ese comando. Si sigue pasando, avisa a un administrador con el código de referencia `%s`.",
+		"'%s' is not a valid option for !%s":                                        "'%s' no es una opción válida para !%s",
+		"!%s needs a location or a region, e.g. `!%s %s` or `!%s region:US-NY-055`": "!%s necesita un lugar o una región, p. ej. `!%s %s` o `!%s region:US-NY-055`",
+		"'%s' must be a whole number from %d to %d, got '%s'":                       "'%s' debe ser un número entero de %d a %d, se recibió '%s'",
+		"'%s' cannot be used with 'region'":                                         "'%s' no se puede usar con 'region'",
+		"'%s' is not an export format, use %s":                                      "'%s' no es un formato de exportación, usa %s",
+		"a species name is needed":                                                  "hace falta el nombre de una especie",
+		"no species found matching '%s'":                                            "ninguna especie coincide con '%s'",
+		"'%s' matches several species: %s":                                          "'%s' coincide con varias especies: %s",
+		nearestUsage:                                                                "usa `!nearest <especie> [from <lugar>] [días] [export:formato]`, p. ej. `!nearest carpintero cabecirrojo from rit 7`",
+		localeUsage:                                                                 "usa `!locale`, `!locale me <idioma>` o `!locale server <idioma>`, con `reset` para volver al predeterminado",
+		"'%s' is not a supported language, use %s":                                  "'%s' no es un idioma disponible, usa %s",
+
+		// Rate limits
+		"Slow down! You can send FlaminGo another command in %s.":                                                                   "¡Más despacio! Puedes enviar otro comando a FlaminGo en %s.",
+		"This channel is sending FlaminGo a lot of commands. Try again in %s.":                                                      "Este canal está enviando muchos comandos a FlaminGo. Inténtalo de nuevo en %s.",
+		"%s is getting too many requests from FlaminGo right now. Try again in %s.":                                                 "%s está recibiendo demasiadas solicitudes de FlaminGo en este momento. Inténtalo de nuevo en %s.",
+		"FlaminGo is very busy right now. Try again in a minute.":                                                                   "FlaminGo está muy ocupado en este momento. Inténtalo de nuevo en un minuto.",
+		"That took longer than %s, so FlaminGo gave up. eBird or AllAboutBirds may be slow right now, try again in a little while.": "Eso tardó más de %s, así que FlaminGo se rindió. eBird o AllAboutBirds pueden estar lentos ahora, inténtalo de nuevo en un rato.",
+
+		// Bird generator
+		"This server's !generate uses the default lists again.":                                                        "!generate de este servidor vuelve a usar las listas predeterminadas.",
+		"Saved %s and %s. This server's !generate now uses only these.":                                                "Se guardaron %s y %s. !generate de este servidor ahora usa solo estos.",
+		"Saved %s and %s. This server's !generate now uses them along with the default lists.":                         "Se guardaron %s y %s. !generate de este servidor ahora los usa junto con las listas predeterminadas.",
+		"attach a .csv file with an adjective and a noun on each line":                                                 "adjunta un archivo .csv con un adjetivo y un sustantivo en cada línea",
+		"the file is larger than %d KB":                                                                                "el archivo pesa más de %d KB",
+		"fix these mistakes and upload it again:\n%s":                                                                  "corrige estos errores y vuelve a subirlo:\n%s",
+		"replacing the default lists needs at least one adjective and one noun":                                        "para reemplazar las listas predeterminadas se necesita al menos un adjetivo y un sustantivo",
+		"!generate is turned off because FlaminGo has no bird generator lists loaded":                                  "!generate está desactivado porque FlaminGo no tiene listas cargadas para el generador de aves",
+		"use `!generate [0-3]`, `!generate upload <merge or replace>` with a .csv file attached, or `!generate reset`": "usa `!generate [0-3]`, `!generate upload <merge o replace>` con un archivo .csv adjunto, o `!generate reset`",
+
+		// Trips
+		"use `!trip create <location> <YYYY-MM-DD HH:MM> [title] [hours:1-12]`, `!trip list` or `!trip cancel <id>`": "usa `!trip create <lugar> <AAAA-MM-DD HH:MM> [título] [hours:1-12]`, `!trip list` o `!trip cancel <id>`",
+		"trips can only be planned in a server":                                                "las salidas solo se pueden planear en un servidor",
+		"'%s' is not a date and time, use the format YYYY-MM-DD HH:MM":                         "'%s' no es una fecha y hora, usa el formato AAAA-MM-DD HH:MM",
+		"the trip must start in the future":                                                    "la salida debe empezar en el futuro",
+		"'%s' has no eBird location code, so there would be no checklists for the recap":       "'%s' no tiene código de ubicación de eBird, así que no habría listas para el resumen",
+		"this server already has %d trips planned, cancel one first":                           "este servidor ya tiene %d salidas planeadas, cancela una primero",
+		"there is no trip '%s', see `!trip list`":                                              "no hay ninguna salida '%s', mira `!trip list`",
+		"only the trip's organizer or someone with the Manage Server permission can cancel it": "solo quien organiza la salida o alguien con el permiso Gestionar servidor puede cancelarla",
+		"Bird walk at %s": "Paseo de aves en %s",
+		"Nobody yet":      "Nadie todavía",
+		"Where":           "Dónde",
+		"When":            "Cuándo",
+		"Going (%d)":      "Van (%d)",
+		"for %s":          "durante %s",
+		"at %s":           "en %s",
+		"Trip %s. Everyone going gets a reminder an hour before it starts, and a recap of the sightings afterwards.": "Salida %s. Quienes van reciben un recordatorio una hora antes de empezar, y un resumen de los avistamientos después.",
+		"Going":                               "Voy",
+		"Can't make it":                       "No puedo ir",
+		"This trip is over or was cancelled.": "Esta salida ya terminó o se canceló.",
+		"Sorry, something went wrong. Try again in a little while.": "Lo siento, algo salió mal. Inténtalo de nuevo en un rato.",
+		"**Planned trips:**\n":                                                "**Salidas planeadas:**\n",
+		"`%s` **%s** %s, %s, %d going\n":                                      "`%s` **%s** %s, %s, van %d\n",
+		"No trips are planned. Plan one with `!trip create`.":                 "No hay salidas planeadas. Planea una con `!trip create`.",
+		"Cancelled **%s**.":                                                   "Se canceló **%s**.",
+		"Reminder: **%s** %s starts %s.":                                      "Recordatorio: **%s** %s empieza %s.",
+		"**Recap of %s**: species on eBird checklists %s during the trip\n":   "**Resumen de %s**: especies en listas de eBird %s durante la salida\n",
+		"Thanks for coming, %s!\n":                                            "¡Gracias por venir, %s!\n",
+		"No checklists from the trip have been submitted to eBird yet.":       "Todavía no se han enviado a eBird listas de la salida.",
+		"**%s** is over, but its location was removed, so there is no recap.": "**%s** terminó, pero su lugar se eliminó, así que no hay resumen.",
+		"Plans a field trip: posts it with buttons to RSVP, reminds everyone going an hour before it starts, and afterwards posts a recap of the eBird sightings at the location during the trip, tagging everyone who went. Times are YYYY-MM-DD HH:MM.": "Planea una salida de campo: la publica con botones para confirmar asistencia, recuerda a quienes van una hora antes de empezar y después publica un resumen de los avistamientos de eBird en el lugar durante la salida, mencionando a quienes fueron. Las horas son AAAA-MM-DD HH:MM.",
+
+		// Hotspots and locations
+		"'%s' is not a valid option for !hotspots, %s":  "'%s' no es una opción válida para !hotspots, %s",
+		"'%s' is not a valid option for !locations, %s": "'%s' no es una opción válida para !locations, %s",
+		"mode must be '%s' or '%s', got '%s'":           "el modo debe ser '%s' o '%s', se recibió '%s'",
+		"hotspot mode needs the location's eBird code":  "el modo hotspot necesita el código de eBird del lugar",
+		hotspotsUsage: "usa `!hotspots near <lat,long o lugar> [radio] [export:formato]` o `!hotspots save <número o código> <nombre> [replace]`",
+		"**No eBird hotspots found within %d km of %s.**": "**No se encontraron hotspots de eBird a menos de %d km de %s.**",
+		"**eBird hotspots within %d km of %s:**\n":        "**Hotspots de eBird a menos de %d km de %s:**\n",
+		"never":                                  "nunca",
+		"%d. %s (`%s`): %d species, latest %s\n": "%d. %s (`%s`): %d especies, último %s\n",
+		"Save one with `!hotspots save <number or code> <name>`\n":                                     "Guarda uno con `!hotspots save <número o código> <nombre>`\n",
+		"Saved **%s** (`%s`) as `%s`. Try it with `!get %s`.":                                          "Se guardó **%s** (`%s`) como `%s`. Pruébalo con `!get %s`.",
+		"locations can only be saved in a server":                                                      "los lugares solo se pueden guardar en un servidor",
+		"'%s' is not a valid location name, use up to 32 letters, numbers, '-' and '_'":                "'%s' no es un nombre de lugar válido, usa hasta 32 letras, números, '-' y '_'",
+		"'%s' is already a built-in location":                                                          "'%s' ya es un lugar predefinido",
+		"there is no hotspot number %d, run `!hotspots near` first":                                    "no hay ningún hotspot número %d, usa `!hotspots near` primero",
+		"'%s' is not a hotspot number or an eBird location code":                                       "'%s' no es un número de hotspot ni un código de ubicación de eBird",
+		"give the radius once, either as a number or with 'radius:'":                                   "indica el radio una sola vez, como número o con 'radius:'",
+		"this server already has a location called '%s', add `replace` to the command to overwrite it": "este servidor ya tiene un lugar llamado '%s', agrega `replace` al comando para sobrescribirlo",
+		locationsUsage:                               "usa `!locations` o `!locations mode <lugar> <geo o hotspot>`",
+		"**Locations:**\n":                           "**Lugares:**\n",
+		"`%s`: %s (%s)\n":                            "`%s`: %s (%s)\n",
+		"`%s` (%s) now uses %s mode.":                "`%s` (%s) ahora usa el modo %s.",
+		"location modes can only be set in a server": "los modos de los lugares solo se pueden cambiar en un servidor",
+		"'%s' is not a known location":               "'%s' no es un lugar conocido",
+
+		// Checklists
+		checklistsUsage: "usa `!checklists <lugar o region:código> [1-10]`",
+		"use `!checklist <checklist ID>`, e.g. `!checklist S120000001`": "usa `!checklist <ID de la lista>`, p. ej. `!checklist S120000001`",
+		"'%s' is not an eBird checklist ID like S120000001":             "'%s' no es un ID de lista de eBird como S120000001",
+		"**No recent eBird checklists found at %s.**":                   "**No se encontraron listas recientes de eBird en %s.**",
+		"**Recent eBird checklists at %s:**\n":                          "**Listas recientes de eBird en %s:**\n",
+		"`%s` %s %s: %s%s, %d species, %s\n":                            "`%s` %s %s: %s%s, %d especies, %s\n",
+		"See one with `!checklist <checklist ID>`\n":                    "Mira una con `!checklist <ID de la lista>`\n",
+		"Checklist %s":               "Lista %s",
+		"Page %d/%d":                 "Página %d/%d",
+		", %d more species on eBird": ", %d especies más en eBird",
+		"Observer":                   "Observador",
+		"Date":                       "Fecha",
+		"Duration":                   "Duración",
+		"Species":                    "Especies",
+		"Observers":                  "Observadores",
+		"Complete":                   "Completa",
+		"Comments":                   "Comentarios",
+		"No species reported.":       "No se reportaron especies.",
+		"no duration":                "sin duración",
+		"None":                       "Ninguno",
+		"Yes":                        "Sí",
+		"No":                         "No",
+
+		// Stats
+		top100Usage: "usa `!top100 <código de región> [AAAA-MM-DD] [by:species o by:checklists]`",
+		statsUsage:  "usa `!stats <código de región> [AAAA-MM-DD]`, `!stats <código de región> <AAAA-MM-DD>..<AAAA-MM-DD>` o `!stats <código de región> month:AAAA-MM`",
+		"'by' must be 'species' or 'checklists', got '%s'":            "'by' debe ser 'species' o 'checklists', se recibió '%s'",
+		"**No eBird contributors found in %s on %s.**":                "**No se encontraron colaboradores de eBird en %s el %s.**",
+		"**Top eBirders by species in %s on %s:**\n":                  "**Mejores observadores de eBird por especies en %s el %s:**\n",
+		"**Top eBirders by checklists in %s on %s:**\n":               "**Mejores observadores de eBird por listas en %s el %s:**\n",
+		"%d. %s: %d species, %s\n":                                    "%d. %s: %d especies, %s\n",
+		"**eBird stats for %s on %s:**\n%s from %s, %d species\n":     "**Estadísticas de eBird de %s el %s:**\n%s de %s, %d especies\n",
+		"**eBird recap for %s, %s to %s:**\n":                         "**Resumen de eBird de %s, del %s al %s:**\n",
+		"Checklists":                                                  "Listas",
+		"Contributors":                                                "Observadores",
+		"%s in total, %.1f a day. Busiest day: %s with %s from %s.\n": "%s en total, %.1f al día. Día con más actividad: %s con %s de %s.\n",
+
+		// History
+		historyUsage:   "usa `!history <lugar o region:código> <AAAA-MM-DD> [years:2-10]`",
+		onThisDayUsage: "usa `!onthisday <lugar o region:código> [2-10]`",
+		"'%s' has no eBird location code, try a region instead": "'%s' no tiene código de ubicación de eBird, prueba con una región",
+		"**eBird sightings at %s on %s:**\n":                    "**Observaciones de eBird en %s el %s:**\n",
+		"Year":                                                  "Año",
+		"**New in %d:** %s\n":                                   "**Nuevas en %d:** %s\n",
+		"**Missing in %d** (earlier years seen): %s\n":          "**Ausentes en %d** (años en que se vio antes): %s\n",
+		"none": "ninguna",
+
+		// Seasons
+		seasonUsage: "usa `!season <especie> <lugar>` o `!season <especie> region:<código>`, opcionalmente con `years:1-5`",
+		"**Weekly frequency of %s at %s** (share of sampled days it was reported, %s)\n":  "**Frecuencia semanal de %s en %s** (proporción de días muestreados en que se reportó, %s)\n",
+		"%d sampled days are not loaded yet, run the same !season again to fill them in.": "%d días muestreados aún no están cargados, vuelve a ejecutar el mismo !season para completarlos.",
+		"It was not reported on any sampled day.":                                         "No se reportó en ningún día muestreado.",
+		"Best time: %s":       "Mejor momento: %s",
+		"week of %s (%.0f%%)": "semana del %s (%.0f%%)",
+
+		// Regions
+		regionUsage: "usa `!region search <nombre> [in:<código de región>]` o `!region info <código de región>`",
+		"'%s' is not a valid option for !region, %s": "'%s' no es una opción válida para !region, %s",
+		"'%s' has no smaller regions to search":      "'%s' no tiene regiones más pequeñas donde buscar",
+		"**No eBird regions found matching '%s'.** Try searching inside a country or state with `in:`, e.g. `in:US` or `in:US-NY`.": "**Ninguna región de eBird coincide con '%s'.** Prueba a buscar dentro de un país o estado con `in:`, p. ej. `in:US` o `in:US-NY`.",
+		"**eBird regions matching '%s':**\n":                 "**Regiones de eBird que coinciden con '%s':**\n",
+		"...and %d more, try a longer name\n":                "...y %d más, prueba un nombre más largo\n",
+		"`%s` is **%s** (%s). Use it with `!get region:%s`.": "`%s` es **%s** (%s). Úsala con `!get region:%s`.",
+
+		// !bird
+		"Bird not found!": "¡Ave no encontrada!",
+		"Make sure you spelled it right and have the name properly punctuated. Also make sure you have the full name (e.g. \"American Robin\" instead of just \"Robin\"). Birds outside of North America are unavailable.": "Asegúrate de escribir bien el nombre y con la puntuación correcta. Usa también el nombre completo (p. ej. \"American Robin\" en vez de solo \"Robin\"). Las aves de fuera de Norteamérica no están disponibles.",
+
+		// Dates, laid out for time with the month names looked up separately
+		"January 2": "2 de January",
+		"January":   "enero",
+		"February":  "febrero",
+		"March":     "marzo",
+		"April":     "abril",
+		"May":       "mayo",
+		"June":      "junio",
+		"July":      "julio",
+		"August":    "agosto",
+		"September": "septiembre",
+		"October":   "octubre",
+		"November":  "noviembre",
+		"December":  "diciembre",
+
+		// Plurals for pluralizeIn
+		"checklist":    "lista",
+		"checklists":   "listas",
+		"contributor":  "colaborador",
+		"contributors": "colaboradores",
+		"day":          "día",
+		"days":         "días",
+		"year":         "año",
+		"years":        "años",
+		"row":          "fila",
+		"rows":         "filas",
+		"second":       "segundo",
+		"seconds":      "segundos",
+		"minute":       "minuto",
+		"minutes":      "minutos",
+		"adjective":    "adjetivo",
+		"adjectives":   "adjetivos",
+		"noun":         "sustantivo",
+		"nouns":        "sustantivos",
+		"hour":         "hora",
+		"hours":        "horas",
+
+		// Observation headers
+		"in %s":              "en %s",
+		"at the %s hotspot":  "en el hotspot %s",
+		"within %d km of %s": "a menos de %d km de %s",
+		"Verified eBird sightings %s in the past %s":            "Avistamientos verificados de eBird %s en los últimos %s",
+		"Notable eBird sightings %s in the past %s":             "Avistamientos notables de eBird %s en los últimos %s",
+		"**No notable eBird sightings found.**":                 "**No se encontraron avistamientos notables en eBird.**",
+		"Nearest %s to %s in the past %s":                       "Reportes de %s más cercanos a %s en los últimos %s",
+		"**No %s reported within %d km of %s in the past %s.**": "**No se reportó %s a menos de %d km de %s en los últimos %s.**",
+		"%d. %.1f km %s: %s, %s on %s\n":                        "%d. %.1f km %s: %s, %s el %s\n",
+		"present":                                               "presente",
+		"**%s** (%s as %s)":                                     "**%s** (%s en %s)",
+
+		// !locale
+		"Your language is %s. Change it with `!locale me <%s>`, or this server's with `!locale server <%s>`.": "Tu idioma es %s. Cámbialo con `!locale me <%s>`, o el del servidor con `!locale server <%s>`.",
+		"Your language is now %s.":          "Tu idioma ahora es %s.",
+		"This server's language is now %s.": "El idioma de este servidor ahora es %s.",
+
+		// !config
+		"a prefix must be 1 to 5 characters without spaces or backticks":              "un prefijo debe tener de 1 a 5 caracteres sin espacios ni comillas invertidas",
+		"'rare_multiplier' must be a number from 1 to 10, got '%s'":                   "'rare_multiplier' debe ser un número de 1 a 10, se recibió '%s'",
+		"'%s' is not a color, use six hex digits such as #ff0099":                     "'%s' no es un color, usa seis dígitos hexadecimales como #ff0099",
+		"FlaminGo settings for this server":                                           "Ajustes de FlaminGo en este servidor",
+		"Updated: %s":                                                                 "Actualizado: %s",
+		"(default)":                                                                   "(predeterminado)",
+		"the text commands start with":                                                "el texto con el que empiezan los comandos",
+		"the location used when a command is given none":                              "el lugar que se usa cuando un comando no indica ninguno",
+		"the default search radius of !get and !hotspots in km":                       "el radio de búsqueda predeterminado de !get y !hotspots en km",
+		"how many times the radius !rare searches, since rare sightings are few":      "cuántas veces el radio busca !rare, ya que hay pocos avistamientos raros",
+		"the color of FlaminGo's embeds, e.g. #ff0099":                                "el color de los mensajes incrustados de FlaminGo, p. ej. #ff0099",
+		"the server's language for replies and species names":                         "el idioma del servidor para respuestas y nombres de especies",
+		"the time zone trip times are in, e.g. America/New_York":                      "la zona horaria de las salidas, p. ej. America/New_York",
+		"'%s' is not a time zone, use a name such as America/New_York":                "'%s' no es una zona horaria, usa un nombre como America/New_York",
+		"the commands FlaminGo answers in this server, separated by commas, or 'all'": "los comandos que FlaminGo responde en este servidor, separados por comas, o 'all'",
+		"the channel that gets announcements about FlaminGo, e.g. #birding":           "el canal que recibe los anuncios sobre FlaminGo, p. ej. #birding",
+		"'%s' is not a channel, mention one like #birding":                            "'%s' no es un canal, menciona uno como #birding",
+		configUsage: "usa `!config list`, `!config get <ajuste>` o `!config set <ajuste> <valor o reset>`",
+		"server settings can only be changed in a server":                     "los ajustes del servidor solo se pueden cambiar en un servidor",
+		"you need the Manage Server permission to change FlaminGo's settings": "necesitas el permiso Gestionar servidor para cambiar los ajustes de FlaminGo",
+		"'%s' is not a setting, use one of %s":                                "'%s' no es un ajuste, usa uno de estos: %s",
+		"'%s' is not a known location, see `!locations`":                      "'%s' no es un lugar conocido, mira `!locations`",
+		"'%s' is not a FlaminGo command":                                      "'%s' no es un comando de FlaminGo",
+		"Shows and changes FlaminGo's settings for this server: prefix, default location, radius, rare radius multiplier, embed color, language, enabled commands and announcement channel. Needs the Manage Server permission.": "Muestra y cambia los ajustes de FlaminGo en este servidor: prefijo, lugar predeterminado, radio, multiplicador del radio de rarezas, color, idioma, comandos activos y canal de anuncios. Requiere el permiso Gestionar servidor.",
+
+		// !admin
+		adminUsage:                                  "usa `!admin reload`, `!admin status`, `!admin guilds`, `!admin broadcast <mensaje>` o `!admin setstatus <texto o reset>`",
+		"only FlaminGo's owners can use !admin":     "solo los dueños de FlaminGo pueden usar !admin",
+		"reloading failed, nothing was changed: %s": "la recarga falló, no se cambió nada: %s",
+		"reloaded the configuration and locations, but not the bird generator: %s": "se recargaron la configuración y los lugares, pero no el generador de aves: %s",
+
+		// Help
+		"FlaminGo Command Help":          "Ayuda de comandos de FlaminGo",
+		"Displays this list of commands": "Muestra esta lista de comandos",
+		"Returns a list of birds seen within %dkm of the specified location in the past %s. Optionally, include 'radius:' and 'days:' to change the search, 'sort:' and 'group:family' to change the order, or 'reversed' to reverse it. Add 'export:' to get every sighting as a file instead.":                                                                                                                  "Muestra las aves vistas a menos de %d km del lugar indicado en los últimos %s. Opcionalmente, usa 'radius:' y 'days:' para cambiar la búsqueda, 'sort:' y 'group:family' para cambiar el orden, o 'reversed' para invertirlo. Agrega 'export:' para recibir todos los avistamientos como archivo.",
+		"Returns a list of notable bird sightings (rare, out of season, etc.) within %dkm of the specified location in the past %s, with a map of where they were seen. Optionally, include 'radius:' and 'days:' to change the search, 'sort:' and 'group:family' to change the order, or 'reversed' to reverse it. Add 'export:' to get every sighting as a file instead.":                                      "Muestra los avistamientos notables (raros, fuera de temporada, etc.) a menos de %d km del lugar indicado en los últimos %s, con un mapa de dónde se vieron. Opcionalmente, usa 'radius:' y 'days:' para cambiar la búsqueda, 'sort:' y 'group:family' para cambiar el orden, o 'reversed' para invertirlo. Agrega 'export:' para recibir todos los avistamientos como archivo.",
+		"Lists the locations you can use in commands. Server managers can set a location's mode to 'hotspot' to only see sightings reported at that exact eBird hotspot, or 'geo' to search the area around it. Commands also take 'mode:' for a single search.":                                                                                                                                                  "Muestra los lugares que puedes usar en los comandos. Los administradores del servidor pueden poner el modo de un lugar en 'hotspot' para ver solo los avistamientos de ese hotspot de eBird, o en 'geo' para buscar en el área alrededor. Los comandos también aceptan 'mode:' para una sola búsqueda.",
+		"Lists the most recent eBird checklists at a location, with who birded, when, how many species and for how long. See a whole checklist with '!checklist (checklist ID)'.":                                                                                                                                                                                                                                 "Muestra las listas de eBird más recientes de un lugar, con quién observó, cuándo, cuántas especies y durante cuánto tiempo. Mira una lista completa con '!checklist (ID de la lista)'.",
+		"Finds the closest recent reports of a species, with distance, direction, date, count and location. Species names can be common names, scientific names or banding codes. Add 'export:' to get the reports as a file.":                                                                                                                                                                                    "Busca los reportes recientes más cercanos de una especie, con distancia, dirección, fecha, cantidad y lugar. Puedes usar nombres comunes, científicos o códigos de anillamiento. Agrega 'export:' para recibir los reportes como archivo.",
+		"Charts how often a species was reported at a place in each week of the year, to show the best time to see it.":                                                                                                                                                                                                                                                                                           "Grafica con qué frecuencia se reportó una especie en un lugar cada semana del año, para mostrar la mejor época para verla.",
+		"Compares the eBird sightings on a date (or today) with the same date in earlier years: species per year, species new this year and species missing this year.":                                                                                                                                                                                                                                           "Compara los avistamientos de eBird de una fecha (u hoy) con la misma fecha de años anteriores: especies por año, especies nuevas este año y especies que faltan este año.",
+		"Shows the top eBirders in a region on a day, by species or by checklists. Defaults to today.":                                                                                                                                                                                                                                                                                                            "Muestra a los mejores observadores de eBird de una región en un día, por especies o por listas. Por defecto, hoy.",
+		"Shows how many checklists, contributors and species a region had on a day, or a day-by-day recap over a range of up to 31 days.":                                                                                                                                                                                                                                                                         "Muestra cuántas listas, colaboradores y especies tuvo una región en un día, o un resumen día a día de hasta 31 días.",
+		"Finds eBird region codes for countries, states and counties, for use with '!get region:US-NY-055' or '!rare region:US-NY'. Search inside a country or state with 'in:', e.g. 'in:US-NY'.":                                                                                                                                                                                                                "Busca códigos de región de eBird para países, estados y condados, para usar con '!get region:US-NY-055' o '!rare region:US-NY'. Busca dentro de un país o estado con 'in:', p. ej. 'in:US-NY'.",
+		"Lists eBird hotspots near a location, with their all-time species counts and latest sightings. Add 'export:' to get the list as a file. Server managers can save one as a location for this server with '!hotspots save (number/code) (name)', adding 'replace' to overwrite a saved one.":                                                                                                               "Muestra los hotspots de eBird cerca de un lugar, con su total histórico de especies y sus últimos avistamientos. Agrega 'export:' para recibir la lista como archivo. Los administradores del servidor pueden guardar uno como lugar de este servidor con '!hotspots save (número/código) (nombre)', agregando 'replace' para sobrescribir uno ya guardado.",
+		"Displays info for the specified bird. Uses information and names from AllAboutBirds.org.":                                                                                                                                                                                                                                                                                                                "Muestra información del ave indicada. Usa información y nombres (en inglés) de AllAboutBirds.org.",
+		"Randomly generates a bird name using a list of every bird species. Optionally, include 0-3 to specify the number of adjectives. Credit to Aidan Mahar for the lists and original idea! Server managers can attach a .csv of adjectives and nouns to `!generate upload merge` to add their own words, or `!generate upload replace` to use only theirs, and `!generate reset` goes back to the defaults.": "Genera al azar un nombre de ave con una lista de todas las especies. Opcionalmente, indica de 0 a 3 adjetivos. ¡Gracias a Aidan Mahar por las listas y la idea original! Los administradores del servidor pueden adjuntar un .csv de adjetivos y sustantivos a `!generate upload merge` para añadir sus propias palabras, o a `!generate upload replace` para usar solo las suyas, y `!generate reset` vuelve a las predeterminadas.",
+		"Sets the language of replies and species names, for you or for the whole server. Speaks %s.":                                                                                                                                                                                                                                                                                                             "Cambia el idioma de las respuestas y los nombres de especies, para ti o para todo el servidor. Idiomas: %s.",
+	},
+	"fr": {
+		// Errors
+		"'sort' must be one of %s, got '%s'":                                                           "'sort' doit être l'un de %s, reçu '%s'",
+		"'group' must be '%s', got '%s'":                                                               "'group' doit être '%s', reçu '%s'",
+		"'%s' is not a valid lat,long pair":                                                            "'%s' n'est pas une paire lat,long valide",
+		"'%s' is not a known location or a lat,long pair":                                              "'%s' n'est ni un lieu connu ni une paire lat,long",
+		"'%s' is not an eBird region code like US, US-NY or US-NY-055, find one with `!region search`": "'%s' n'est pas un code de région eBird comme US, US-NY ou US-NY-055, trouvez-en un avec `!region search`",
+		"'%s' is not a date like 2022-10-10":                                                           "'%s' n'est pas une date comme 2022-10-10",
+		"'%s' is not a month like 2022-10":                                                             "'%s' n'est pas un mois comme 2022-10",
+		"%s is in the future":                                                                          "%s est dans le futur",
+		"the range must not end before it starts":                                                      "l'intervalle ne peut pas finir avant de commencer",
+		"ranges can cover at most %d days, got %d":                                                     "les intervalles peuvent couvrir au plus %d jours, reçu %d",
+		"'%s' is not a valid option for !nearest":                                                      "'%s' n'est pas une option valide pour !nearest",
+		"Error: %s": "Erreur : %s",
+		"Sorry, something went wrong while running that command. If this keeps happening, let an admin know the reference code `%s`.": "Désolé, un problème est survenu pendant cette commande. Si cela se reproduit, donnez le code de référence `%s` à un administrateur.",
+		"'%s' is not a valid option for !%s":                                        "'%s' n'est pas une option valide pour !%s",
+		"!%s needs a location or a region, e.g. `!%s %s` or `!%s region:US-NY-055`": "!%s a besoin d'un lieu ou d'une région, par ex. `!%s %s` ou `!%s region:US-NY-055`",
+		"'%s' must be a whole number from %d to %d, got '%s'":                       "'%s' doit être un nombre entier de %d à %d, reçu '%s'",
+		"'%s' cannot be used with 'region'":                                         "'%s' ne peut pas être utilisé avec 'region'",
+		"'%s' is not an export format, use %s":                                      "'%s' n'est pas un format d'export, utilisez %s",
+		"a species name is needed":                                                  "il faut un nom d'espèce",
+		"no species found matching '%s'":                                            "aucune espèce ne correspond à '%s'",
+		"'%s' matches several species: %s":                                          "'%s' correspond à plusieurs espèces : %s",
+		nearestUsage:                                                                "utilisez `!nearest <espèce> [from <lieu>] [jours] [export:format]`, par ex. `!nearest pic à tête rouge from rit 7`",
+		localeUsage:                                                                 "utilisez `!locale`, `!locale me <langue>` ou `!locale server <langue>`, avec `reset` pour revenir à la langue par défaut",
+		"'%s' is not a supported language, use %s":                                  "'%s' n'est pas une langue disponible, utilisez %s",
+
+		// Rate limits
+		"Slow down! You can send FlaminGo another command in %s.":                                                                   "Doucement ! Vous pourrez envoyer une autre commande à FlaminGo dans %s.",
+		"This channel is sending FlaminGo a lot of commands. Try again in %s.":                                                      "Ce salon envoie beaucoup de commandes à FlaminGo. Réessayez dans %s.",
+		"%s is getting too many requests from FlaminGo right now. Try again in %s.":                                                 "%s reçoit trop de requêtes de FlaminGo en ce moment. Réessayez dans %s.",
+		"FlaminGo is very busy right now. Try again in a minute.":                                                                   "FlaminGo est très occupé en ce moment. Réessayez dans une minute.",
+		"That took longer than %s, so FlaminGo gave up. eBird or AllAboutBirds may be slow right now, try again in a little while.": "Cela a pris plus de %s, alors FlaminGo a abandonné. eBird ou AllAboutBirds sont peut-être lents en ce moment, réessayez un peu plus tard.",
+
+		// Bird generator
+		"This server's !generate uses the default lists again.":                                                        "!generate de ce serveur utilise de nouveau les listes par défaut.",
+		"Saved %s and %s. This server's !generate now uses only these.":                                                "%s et %s enregistrés. !generate de ce serveur n'utilise plus que ceux-ci.",
+		"Saved %s and %s. This server's !generate now uses them along with the default lists.":                         "%s et %s enregistrés. !generate de ce serveur les utilise désormais avec les listes par défaut.",
+		"attach a .csv file with an adjective and a noun on each line":                                                 "joignez un fichier .csv avec un adjectif et un nom sur chaque ligne",
+		"the file is larger than %d KB":                                                                                "le fichier dépasse %d Ko",
+		"fix these mistakes and upload it again:\n%s":                                                                  "corrigez ces erreurs et envoyez-le à nouveau :\n%s",
+		"replacing the default lists needs at least one adjective and one noun":                                        "remplacer les listes par défaut demande au moins un adjectif et un nom",
+		"!generate is turned off because FlaminGo has no bird generator lists loaded":                                  "!generate est désactivé car FlaminGo n'a aucune liste chargée pour le générateur d'oiseaux",
+		"use `!generate [0-3]`, `!generate upload <merge or replace>` with a .csv file attached, or `!generate reset`": "utilisez `!generate [0-3]`, `!generate upload <merge ou replace>` avec un fichier .csv joint, ou `!generate reset`",
+
+		// Trips
+		"use `!trip create <location> <YYYY-MM-DD HH:MM> [title] [hours:1-12]`, `!trip list` or `!trip cancel <id>`": "utilisez `!trip create <lieu> <AAAA-MM-JJ HH:MM> [titre] [hours:1-12]`, `!trip list` ou `!trip cancel <id>`",
+		"trips can only be planned in a server":                                                "les sorties ne peuvent être planifiées que dans un serveur",
+		"'%s' is not a date and time, use the format YYYY-MM-DD HH:MM":                         "'%s' n'est pas une date et une heure, utilisez le format AAAA-MM-JJ HH:MM",
+		"the trip must start in the future":                                                    "la sortie doit commencer dans le futur",
+		"'%s' has no eBird location code, so there would be no checklists for the recap":       "'%s' n'a pas de code de lieu eBird, donc il n'y aurait aucune liste pour le récapitulatif",
+		"this server already has %d trips planned, cancel one first":                           "ce serveur a déjà %d sorties planifiées, annulez-en une d'abord",
+		"there is no trip '%s', see `!trip list`":                                              "il n'y a pas de sortie '%s', voir `!trip list`",
+		"only the trip's organizer or someone with the Manage Server permission can cancel it": "seul l'organisateur de la sortie ou quelqu'un avec la permission Gérer le serveur peut l'annuler",
+		"Bird walk at %s": "Balade ornitho à %s",
+		"Nobody yet":      "Personne pour l'instant",
+		"Where":           "Où",
+		"When":            "Quand",
+		"Going (%d)":      "Participants (%d)",
+		"for %s":          "pendant %s",
+		"at %s":           "à %s",
+		"Trip %s. Everyone going gets a reminder an hour before it starts, and a recap of the sightings afterwards.": "Sortie %s. Les participants reçoivent un rappel une heure avant le début, et un récapitulatif des observations ensuite.",
+		"Going":                               "J'y vais",
+		"Can't make it":                       "Je ne peux pas",
+		"This trip is over or was cancelled.": "Cette sortie est terminée ou a été annulée.",
+		"Sorry, something went wrong. Try again in a little while.": "Désolé, quelque chose s'est mal passé. Réessayez dans un moment.",
+		"**Planned trips:**\n":                                                "**Sorties planifiées :**\n",
+		"`%s` **%s** %s, %s, %d going\n":                                      "`%s` **%s** %s, %s, %d participants\n",
+		"No trips are planned. Plan one with `!trip create`.":                 "Aucune sortie n'est planifiée. Planifiez-en une avec `!trip create`.",
+		"Cancelled **%s**.":                                                   "**%s** annulée.",
+		"Reminder: **%s** %s starts %s.":                                      "Rappel : **%s** %s commence %s.",
+		"**Recap of %s**: species on eBird checklists %s during the trip\n":   "**Récapitulatif de %s** : espèces des listes eBird %s pendant la sortie\n",
+		"Thanks for coming, %s!\n":                                            "Merci d'être venus, %s !\n",
+		"No checklists from the trip have been submitted to eBird yet.":       "Aucune liste de la sortie n'a encore été envoyée à eBird.",
+		"**%s** is over, but its location was removed, so there is no recap.": "**%s** est terminée, mais son lieu a été supprimé, il n'y a donc pas de récapitulatif.",
+		"Plans a field trip: posts it with buttons to RSVP, reminds everyone going an hour before it starts, and afterwards posts a recap of the eBird sightings at the location during the trip, tagging everyone who went. Times are YYYY-MM-DD HH:MM.": "Planifie une sortie sur le terrain : la publie avec des boutons pour s'inscrire, rappelle la sortie aux participants une heure avant le début, puis publie un récapitulatif des observations eBird sur le lieu pendant la sortie en mentionnant les participants. Les heures s'écrivent AAAA-MM-JJ HH:MM.",
+
+		// Hotspots and locations
+		"'%s' is not a valid option for !hotspots, %s":  "'%s' n'est pas une option valide pour !hotspots, %s",
+		"'%s' is not a valid option for !locations, %s": "'%s' n'est pas une option valide pour !locations, %s",
+		"mode must be '%s' or '%s', got '%s'":           "le mode doit être '%s' ou '%s', reçu '%s'",
+		"hotspot mode needs the location's eBird code":  "le mode hotspot a besoin du code eBird du lieu",
+		hotspotsUsage: "utilisez `!hotspots near <lat,long ou lieu> [rayon] [export:format]` ou `!hotspots save <numéro ou code> <nom> [replace]`",
+		"**No eBird hotspots found within %d km of %s.**": "**Aucun hotspot eBird trouvé à moins de %d km de %s.**",
+		"**eBird hotspots within %d km of %s:**\n":        "**Hotspots eBird à moins de %d km de %s :**\n",
+		"never":                                  "jamais",
+		"%d. %s (`%s`): %d species, latest %s\n": "%d. %s (`%s`) : %d espèces, dernière %s\n",
+		"Save one with `!hotspots save <number or code> <name>`\n":                                     "Enregistrez-en un avec `!hotspots save <numéro ou code> <nom>`\n",
+		"Saved **%s** (`%s`) as `%s`. Try it with `!get %s`.":                                          "**%s** (`%s`) enregistré sous `%s`. Essayez-le avec `!get %s`.",
+		"locations can only be saved in a server":                                                      "les lieux ne peuvent être enregistrés que dans un serveur",
+		"'%s' is not a valid location name, use up to 32 letters, numbers, '-' and '_'":                "'%s' n'est pas un nom de lieu valide, utilisez jusqu'à 32 lettres, chiffres, '-' et '_'",
+		"'%s' is already a built-in location":                                                          "'%s' est déjà un lieu intégré",
+		"there is no hotspot number %d, run `!hotspots near` first":                                    "il n'y a pas de hotspot numéro %d, lancez d'abord `!hotspots near`",
+		"'%s' is not a hotspot number or an eBird location code":                                       "'%s' n'est ni un numéro de hotspot ni un code de lieu eBird",
+		"give the radius once, either as a number or with 'radius:'":                                   "indiquez le rayon une seule fois, en nombre ou avec 'radius:'",
+		"this server already has a location called '%s', add `replace` to the command to overwrite it": "ce serveur a déjà un lieu appelé '%s', ajoutez `replace` à la commande pour le remplacer",
+		locationsUsage:                               "utilisez `!locations` ou `!locations mode <lieu> <geo ou hotspot>`",
+		"**Locations:**\n":                           "**Lieux :**\n",
+		"`%s`: %s (%s)\n":                            "`%s` : %s (%s)\n",
+		"`%s` (%s) now uses %s mode.":                "`%s` (%s) utilise maintenant le mode %s.",
+		"location modes can only be set in a server": "les modes des lieux ne peuvent être changés que dans un serveur",
+		"'%s' is not a known location":               "'%s' n'est pas un lieu connu",
+
+		// Checklists
+		checklistsUsage: "utilisez `!checklists <lieu ou region:code> [1-10]`",
+		"use `!checklist <checklist ID>`, e.g. `!checklist S120000001`": "utilisez `!checklist <ID de la liste>`, par ex. `!checklist S120000001`",
+		"'%s' is not an eBird checklist ID like S120000001":             "'%s' n'est pas un ID de liste eBird comme S120000001",
+		"**No recent eBird checklists found at %s.**":                   "**Aucune liste eBird récente trouvée à %s.**",
+		"**Recent eBird checklists at %s:**\n":                          "**Listes eBird récentes à %s :**\n",
+		"`%s` %s %s: %s%s, %d species, %s\n":                            "`%s` %s %s : %s%s, %d espèces, %s\n",
+		"See one with `!checklist <checklist ID>`\n":                    "Affichez-en une avec `!checklist <ID de la liste>`\n",
+		"Checklist %s":               "Liste %s",
+		"Page %d/%d":                 "Page %d/%d",
+		", %d more species on eBird": ", %d espèces de plus sur eBird",
+		"Observer":                   "Observateur",
+		"Date":                       "Date",
+		"Duration":                   "Durée",
+		"Species":                    "Espèces",
+		"Observers":                  "Observateurs",
+		"Complete":                   "Complète",
+		"Comments":                   "Commentaires",
+		"No species reported.":       "Aucune espèce signalée.",
+		"no duration":                "sans durée",
+		"None":                       "Aucun",
+		"Yes":                        "Oui",
+		"No":                         "Non",
+
+		// Stats
+		top100Usage: "utilisez `!top100 <code de région> [AAAA-MM-JJ] [by:species ou by:checklists]`",
+		statsUsage:  "utilisez `!stats <code de région> [AAAA-MM-JJ]`, `!stats <code de région> <AAAA-MM-JJ>..<AAAA-MM-JJ>` ou `!stats <code de région> month:AAAA-MM`",
+		"'by' must be 'species' or 'checklists', got '%s'":            "'by' doit être 'species' ou 'checklists', reçu '%s'",
+		"**No eBird contributors found in %s on %s.**":                "**Aucun contributeur eBird trouvé à %s le %s.**",
+		"**Top eBirders by species in %s on %s:**\n":                  "**Meilleurs observateurs eBird par espèces à %s le %s :**\n",
+		"**Top eBirders by checklists in %s on %s:**\n":               "**Meilleurs observateurs eBird par listes à %s le %s :**\n",
+		"%d. %s: %d species, %s\n":                                    "%d. %s : %d espèces, %s\n",
+		"**eBird stats for %s on %s:**\n%s from %s, %d species\n":     "**Statistiques eBird de %s le %s :**\n%s de %s, %d espèces\n",
+		"**eBird recap for %s, %s to %s:**\n":                         "**Récapitulatif eBird de %s, du %s au %s :**\n",
+		"Checklists":                                                  "Listes",
+		"Contributors":                                                "Observateurs",
+		"%s in total, %.1f a day. Busiest day: %s with %s from %s.\n": "%s au total, %.1f par jour. Jour le plus actif : %s avec %s de %s.\n",
+
+		// History
+		historyUsage:   "utilisez `!history <lieu ou region:code> <AAAA-MM-JJ> [years:2-10]`",
+		onThisDayUsage: "utilisez `!onthisday <lieu ou region:code> [2-10]`",
+		"'%s' has no eBird location code, try a region instead": "'%s' n'a pas de code de lieu eBird, essayez plutôt une région",
+		"**eBird sightings at %s on %s:**\n":                    "**Observations eBird à %s le %s :**\n",
+		"Year":                                                  "Année",
+		"**New in %d:** %s\n":                                   "**Nouvelles en %d :** %s\n",
+		"**Missing in %d** (earlier years seen): %s\n":          "**Absentes en %d** (années où elle a été vue avant) : %s\n",
+		"none": "aucune",
+
+		// Seasons
+		seasonUsage: "utilisez `!season <espèce> <lieu>` ou `!season <espèce> region:<code>`, éventuellement avec `years:1-5`",
+		"**Weekly frequency of %s at %s** (share of sampled days it was reported, %s)\n":  "**Fréquence hebdomadaire de %s à %s** (part des jours échantillonnés où elle a été signalée, %s)\n",
+		"%d sampled days are not loaded yet, run the same !season again to fill them in.": "%d jours échantillonnés ne sont pas encore chargés, relancez le même !season pour les compléter.",
+		"It was not reported on any sampled day.":                                         "Elle n'a été signalée aucun jour échantillonné.",
+		"Best time: %s":       "Meilleure période : %s",
+		"week of %s (%.0f%%)": "semaine du %s (%.0f%%)",
+
+		// Regions
+		regionUsage: "utilisez `!region search <nom> [in:<code de région>]` ou `!region info <code de région>`",
+		"'%s' is not a valid option for !region, %s": "'%s' n'est pas une option valide pour !region, %s",
+		"'%s' has no smaller regions to search":      "'%s' n'a pas de régions plus petites où chercher",
+		"**No eBird regions found matching '%s'.** Try searching inside a country or state with `in:`, e.g. `in:US` or `in:US-NY`.": "**Aucune région eBird ne correspond à '%s'.** Essayez de chercher dans un pays ou un État avec `in:`, par ex. `in:US` ou `in:US-NY`.",
+		"**eBird regions matching '%s':**\n":                 "**Régions eBird correspondant à '%s' :**\n",
+		"...and %d more, try a longer name\n":                "...et %d de plus, essayez un nom plus long\n",
+		"`%s` is **%s** (%s). Use it with `!get region:%s`.": "`%s` est **%s** (%s). Utilisez-la avec `!get region:%s`.",
+
+		// !bird
+		"Bird not found!": "Oiseau introuvable !",
+		"Make sure you spelled it right and have the name properly punctuated. Also make sure you have the full name (e.g. \"American Robin\" instead of just \"Robin\"). Birds outside of North America are unavailable.": "Vérifiez l'orthographe et la ponctuation du nom. Utilisez aussi le nom complet (par ex. \"American Robin\" et pas seulement \"Robin\"). Les oiseaux hors d'Amérique du Nord ne sont pas disponibles.",
+
+		// Dates, laid out for time with the month names looked up separately
+		"January 2": "2 January",
+		"January":   "janvier",
+		"February":  "février",
+		"March":     "mars",
+		"April":     "avril",
+		"May":       "mai",
+		"June":      "juin",
+		"July":      "juillet",
+		"August":    "août",
+		"September": "septembre",
+		"October":   "octobre",
+		"November":  "novembre",
+		"December":  "décembre",
+
+		// Plurals for pluralizeIn
+		"checklist":    "liste",
+		"checklists":   "listes",
+		"contributor":  "contributeur",
+		"contributors": "contributeurs",
+		"day":          "jour",
+		"days":         "jours",
+		"year":         "an",
+		"years":        "ans",
+		"row":          "ligne",
+		"rows":         "lignes",
+		"second":       "seconde",
+		"seconds":      "secondes",
+		"minute":       "minute",
+		"minutes":      "minutes",
+		"adjective":    "adjectif",
+		"adjectives":   "adjectifs",
+		"noun":         "nom",
+		"nouns":        "noms",
+		"hour":         "heure",
+		"hours":        "heures",
+
+		// Observation headers
+		"in %s":              "dans %s",
+		"at the %s hotspot":  "au hotspot %s",
+		"within %d km of %s": "à moins de %d km de %s",
+		"Verified eBird sightings %s in the past %s":            "Observations eBird vérifiées %s depuis %s",
+		"Notable eBird sightings %s in the past %s":             "Observations eBird remarquables %s depuis %s",
+		"**No notable eBird sightings found.**":                 "**Aucune observation eBird remarquable trouvée.**",
+		"Nearest %s to %s in the past %s":                       "Signalements de %s les plus proches de %s depuis %s",
+		"**No %s reported within %d km of %s in the past %s.**": "**Aucun signalement de %s à moins de %d km de %s depuis %s.**",
+		"%d. %.1f km %s: %s, %s on %s\n":                        "%d. %.1f km %s : %s, %s le %s\n",
+		"present":                                               "présent",
+		"**%s** (%s as %s)":                                     "**%s** (%s en %s)",
+
+		// !locale
+		"Your language is %s. Change it with `!locale me <%s>`, or this server's with `!locale server <%s>`.": "Votre langue est %s. Changez-la avec `!locale me <%s>`, ou celle du serveur avec `!locale server <%s>`.",
+		"Your language is now %s.":          "Votre langue est maintenant %s.",
+		"This server's language is now %s.": "La langue de ce serveur est maintenant %s.",
+
+		// !config
+		"a prefix must be 1 to 5 characters without spaces or backticks":              "un préfixe doit faire 1 à 5 caractères sans espaces ni accents graves",
+		"'rare_multiplier' must be a number from 1 to 10, got '%s'":                   "'rare_multiplier' doit être un nombre de 1 à 10, reçu '%s'",
+		"'%s' is not a color, use six hex digits such as #ff0099":                     "'%s' n'est pas une couleur, utilisez six chiffres hexadécimaux comme #ff0099",
+		"FlaminGo settings for this server":                                           "Réglages de FlaminGo sur ce serveur",
+		"Updated: %s":                                                                 "Mis à jour : %s",
+		"(default)":                                                                   "(par défaut)",
+		"the text commands start with":                                                "le texte qui commence les commandes",
+		"the location used when a command is given none":                              "le lieu utilisé quand une commande n'en indique aucun",
+		"the default search radius of !get and !hotspots in km":                       "le rayon de recherche par défaut de !get et !hotspots en km",
+		"how many times the radius !rare searches, since rare sightings are few":      "combien de fois le rayon !rare cherche, car les observations rares sont peu nombreuses",
+		"the color of FlaminGo's embeds, e.g. #ff0099":                                "la couleur des encadrés de FlaminGo, par ex. #ff0099",
+		"the server's language for replies and species names":                         "la langue du serveur pour les réponses et les noms d'espèces",
+		"the time zone trip times are in, e.g. America/New_York":                      "le fuseau horaire des sorties, par ex. America/New_York",
+		"'%s' is not a time zone, use a name such as America/New_York":                "'%s' n'est pas un fuseau horaire, utilisez un nom comme America/New_York",
+		"the commands FlaminGo answers in this server, separated by commas, or 'all'": "les commandes auxquelles FlaminGo répond sur ce serveur, séparées par des virgules, ou 'all'",
+		"the channel that gets announcements about FlaminGo, e.g. #birding":           "le salon qui reçoit les annonces sur FlaminGo, par ex. #birding",
+		"'%s' is not a channel, mention one like #birding":                            "'%s' n'est pas un salon, mentionnez-en un comme #birding",
+		configUsage: "utilisez `!config list`, `!config get <réglage>` ou `!config set <réglage> <valeur ou reset>`",
+		"server settings can only be changed in a server":                     "les réglages du serveur ne peuvent être changés que sur un serveur",
+		"you need the Manage Server permission to change FlaminGo's settings": "il faut la permission Gérer le serveur pour changer les réglages de FlaminGo",
+		"'%s' is not a setting, use one of %s":                                "'%s' n'est pas un réglage, utilisez l'un de ceux-ci : %s",
+		"'%s' is not a known location, see `!locations`":                      "'%s' n'est pas un lieu connu, voir `!locations`",
+		"'%s' is not a FlaminGo command":                                      "'%s' n'est pas une commande FlaminGo",
+		"Shows and changes FlaminGo's settings for this server: prefix, default location, radius, rare radius multiplier, embed color, language, enabled commands and announcement channel. Needs the Manage Server permission.": "Affiche et change les réglages de FlaminGo sur ce serveur : préfixe, lieu par défaut, rayon, multiplicateur du rayon des raretés, couleur, langue, commandes actives et salon d'annonces. Nécessite la permission Gérer le serveur.",
+
+		// !admin
+		adminUsage:                                  "utilisez `!admin reload`, `!admin status`, `!admin guilds`, `!admin broadcast <message>` ou `!admin setstatus <texte ou reset>`",
+		"only FlaminGo's owners can use !admin":     "seuls les propriétaires de FlaminGo peuvent utiliser !admin",
+		"reloading failed, nothing was changed: %s": "le rechargement a échoué, rien n'a été modifié : %s",
+		"reloaded the configuration and locations, but not the bird generator: %s": "la configuration et les lieux ont été rechargés, mais pas le générateur d'oiseaux : %s",
+
+		// Help
+		"FlaminGo Command Help":          "Aide des commandes FlaminGo",
+		"Displays this list of commands": "Affiche cette liste de commandes",
+		"Returns a list of birds seen within %dkm of the specified location in the past %s. Optionally, include 'radius:' and 'days:' to change the search, 'sort:' and 'group:family' to change the order, or 'reversed' to reverse it. Add 'export:' to get every sighting as a file instead.":                                                                                                                  "Liste les oiseaux vus à moins de %d km du lieu indiqué depuis %s. Ajoutez 'radius:' et 'days:' pour changer la recherche, 'sort:' et 'group:family' pour changer l'ordre, ou 'reversed' pour l'inverser. Ajoutez 'export:' pour recevoir toutes les observations dans un fichier.",
+		"Returns a list of notable bird sightings (rare, out of season, etc.) within %dkm of the specified location in the past %s, with a map of where they were seen. Optionally, include 'radius:' and 'days:' to change the search, 'sort:' and 'group:family' to change the order, or 'reversed' to reverse it. Add 'export:' to get every sighting as a file instead.":                                      "Liste les observations remarquables (rares, hors saison, etc.) à moins de %d km du lieu indiqué depuis %s, avec une carte des endroits. Ajoutez 'radius:' et 'days:' pour changer la recherche, 'sort:' et 'group:family' pour changer l'ordre, ou 'reversed' pour l'inverser. Ajoutez 'export:' pour recevoir toutes les observations dans un fichier.",
+		"Lists the locations you can use in commands. Server managers can set a location's mode to 'hotspot' to only see sightings reported at that exact eBird hotspot, or 'geo' to search the area around it. Commands also take 'mode:' for a single search.":                                                                                                                                                  "Liste les lieux utilisables dans les commandes. Les gestionnaires du serveur peuvent mettre le mode d'un lieu sur 'hotspot' pour ne voir que les observations de ce hotspot eBird, ou sur 'geo' pour chercher dans la zone autour. Les commandes acceptent aussi 'mode:' pour une seule recherche.",
+		"Lists the most recent eBird checklists at a location, with who birded, when, how many species and for how long. See a whole checklist with '!checklist (checklist ID)'.":                                                                                                                                                                                                                                 "Liste les dernières listes eBird d'un lieu, avec l'observateur, la date, le nombre d'espèces et la durée. Affichez une liste complète avec '!checklist (ID de la liste)'.",
+		"Finds the closest recent reports of a species, with distance, direction, date, count and location. Species names can be common names, scientific names or banding codes. Add 'export:' to get the reports as a file.":                                                                                                                                                                                    "Trouve les signalements récents les plus proches d'une espèce, avec distance, direction, date, nombre et lieu. Les noms peuvent être vernaculaires, scientifiques ou des codes de baguage. Ajoutez 'export:' pour recevoir les signalements dans un fichier.",
+		"Charts how often a species was reported at a place in each week of the year, to show the best time to see it.":                                                                                                                                                                                                                                                                                           "Trace la fréquence à laquelle une espèce a été signalée à un endroit chaque semaine de l'année, pour montrer la meilleure période pour la voir.",
+		"Compares the eBird sightings on a date (or today) with the same date in earlier years: species per year, species new this year and species missing this year.":                                                                                                                                                                                                                                           "Compare les observations eBird d'une date (ou d'aujourd'hui) avec la même date les années précédentes : espèces par an, espèces nouvelles et espèces manquantes cette année.",
+		"Shows the top eBirders in a region on a day, by species or by checklists. Defaults to today.":                                                                                                                                                                                                                                                                                                            "Affiche les meilleurs observateurs eBird d'une région pour un jour, par espèces ou par listes. Aujourd'hui par défaut.",
+		"Shows how many checklists, contributors and species a region had on a day, or a day-by-day recap over a range of up to 31 days.":                                                                                                                                                                                                                                                                         "Affiche le nombre de listes, de contributeurs et d'espèces d'une région pour un jour, ou un récapitulatif jour par jour sur 31 jours au plus.",
+		"Finds eBird region codes for countries, states and counties, for use with '!get region:US-NY-055' or '!rare region:US-NY'. Search inside a country or state with 'in:', e.g. 'in:US-NY'.":                                                                                                                                                                                                                "Trouve les codes de région eBird des pays, états et comtés, à utiliser avec '!get region:US-NY-055' ou '!rare region:US-NY'. Cherchez dans un pays ou un état avec 'in:', par ex. 'in:US-NY'.",
+		"Lists eBird hotspots near a location, with their all-time species counts and latest sightings. Add 'export:' to get the list as a file. Server managers can save one as a location for this server with '!hotspots save (number/code) (name)', adding 'replace' to overwrite a saved one.":                                                                                                               "Liste les hotspots eBird près d'un lieu, avec leur nombre total d'espèces et leurs dernières observations. Ajoutez 'export:' pour recevoir la liste dans un fichier. Les gestionnaires du serveur peuvent en enregistrer un comme lieu du serveur avec '!hotspots save (numéro/code) (nom)', en ajoutant 'replace' pour remplacer un lieu déjà enregistré.",
+		"Displays info for the specified bird. Uses information and names from AllAboutBirds.org.":                                                                                                                                                                                                                                                                                                                "Affiche des informations sur l'oiseau indiqué. Utilise les informations et les noms (en anglais) d'AllAboutBirds.org.",
+		"Randomly generates a bird name using a list of every bird species. Optionally, include 0-3 to specify the number of adjectives. Credit to Aidan Mahar for the lists and original idea! Server managers can attach a .csv of adjectives and nouns to `!generate upload merge` to add their own words, or `!generate upload replace` to use only theirs, and `!generate reset` goes back to the defaults.": "Génère un nom d'oiseau au hasard à partir de la liste de toutes les espèces. Indiquez de 0 à 3 pour choisir le nombre d'adjectifs. Merci à Aidan Mahar pour les listes et l'idée originale ! Les gestionnaires du serveur peuvent joindre un .csv d'adjectifs et de noms à `!generate upload merge` pour ajouter leurs propres mots, ou à `!generate upload replace` pour n'utiliser que les leurs, et `!generate reset` revient aux listes par défaut.",
+		"Sets the language of replies and species names, for you or for the whole server. Speaks %s.":                                                                                                                                                                                                                                                                                                             "Change la langue des réponses et des noms d'espèces, pour vous ou pour tout le serveur. Langues : %s.",
+	},
+}
+
+// tr formats a string in the given locale, falling back to English for unknown locales and missing translations.
+func tr(locale, format string, a ...interface{}) string {
+	if t, ok := catalog[locale][format]; ok {
+		format = t
+	}
+	if len(a) == 0 {
+		return format
+	}
+	return fmt.Sprintf(format, a...)
+}
+
+// pluralizeIn is pluralize for a locale. The singular and plural nouns are looked up in the catalog.
+func pluralizeIn(locale string, n int, noun string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, tr(locale, noun))
+	}
+	return fmt.Sprintf("%d %s", n, tr(locale, noun+"s"))
+}
+
+// monthDay formats the month and day of t in locale, e.g. "May 10" or "10 de mayo". The catalog holds the order as a
+// time layout and the month names, which time only knows in English.
+func monthDay(t time.Time, locale string) string {
+	month := t.Month().String()
+	return strings.Replace(t.Format(tr(locale, "January 2")), month, tr(locale, month), 1)
+}
+
+// parseLocale checks that a locale typed by a user is one FlaminGo speaks, and returns its code.
+func parseLocale(v string) (string, error) {
+	code := strings.ToLower(strings.ReplaceAll(v, "-", "_"))
+	// eBird's regional variants such as es_MX fall back to the base language
+	base, _, _ := strings.Cut(code, "_")
+	if _, ok := localeNames[base]; !ok {
+		return "", usageErrorf("'%s' is not a supported language, use %s", v, localeCodes())
+	}
+	return base, nil
+}
+
+// localeCodes returns the supported locale codes separated by slashes, for use in help text.
+func localeCodes() string {
+	codes := make([]string, 0, len(localeNames))
+	for code := range localeNames {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return strings.Join(codes, "/")
+}
+
+// localeNamesList returns the names of the supported languages separated by commas, for use in help text.
+func localeNamesList() string {
+	names := make([]string, 0, len(localeNames))
+	for _, code := range strings.Split(localeCodes(), "/") {
+		names = append(names, localeNames[code])
+	}
+	return strings.Join(names, ", ")
+}
+
+// localeFor returns the locale for a user in a guild: the user's own setting, then the guild's, then the default.
+func localeFor(guildID, userID string) string {
+	if l := store.User(userID).Locale; l != "" {
+		return l
+	}
+	if guildID != "" {
+		if l := store.Guild(guildID).Locale; l != "" {
+			return l
+		}
+	}
+	return defaultLocale
+}
+
+// sppLocaleParam returns the query parameter asking eBird for species names in a locale, or nothing for English,
+// which eBird uses by default.
+func sppLocaleParam(locale string) string {
+	if locale == "" || locale == defaultLocale {
+		return ""
+	}
+	return "&sppLocale=" + locale
+}
+
+// localeUsage explains the !locale command.
+const localeUsage = "use `!locale`, `!locale me <language>` or `!locale server <language>`, with `reset` to go back to the default"
+
+// runLocale handles !locale, !locale me <language|reset> and !locale server <language|reset>.
+func runLocale(s Sender, inv *Invocation) error {
+	positional, _ := splitOptions(inv.Args)
+	if len(positional) == 0 {
+		inv.send(s, tr(inv.Locale, "Your language is %s. Change it with `!locale me <%s>`, or this server's with `!locale server <%s>`.",
+			localeNames[inv.Locale], localeCodes(), localeCodes()))
+		return nil
+	}
+	if len(positional) != 2 {
+		return usageErrorf("%s", localeUsage)
+	}
+
+	// reset clears the setting so the next level down applies
+	locale := ""
+	if positional[1] != "reset" {
+		var err error
+		locale, err = parseLocale(positional[1])
+		// Error handling
+		if err != nil {
+			return err
+		}
+	}
+
+	switch positional[0] {
+	case "me":
+		err := store.UpdateUser(inv.UserID, func(u *UserSettings) error {
+			u.Locale = locale
+			return nil
+		})
+		// Error handling
+		if err != nil {
+			return err
+		}
+		inv.Locale = localeFor(inv.GuildID, inv.UserID)
+		inv.send(s, tr(inv.Locale, "Your language is now %s.", localeNames[inv.Locale]))
+	case "server":
+		err := requireManageServer(s, inv)
+		// Error handling
+		if err != nil {
+			return err
+		}
+		err = store.Update(inv.GuildID, func(g *GuildSettings) error {
+			g.Locale = locale
+			return nil
+		})
+		// Error handling
+		if err != nil {
+			return err
+		}
+		inv.Locale = localeFor(inv.GuildID, inv.UserID)
+		serverLocale := locale
+		if serverLocale == "" {
+			serverLocale = defaultLocale
+		}
+		inv.send(s, tr(inv.Locale, "This server's language is now %s.", localeNames[serverLocale]))
+	default:
+		return usageErrorf("%s", localeUsage)
+	}
+	return nil
+}
@@ -0,0 +1,284 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+	"unicode"
+)
+
+// verbPattern matches the formatting verbs of a format string.
+var verbPattern = regexp.MustCompile(`%[-+# 0-9.]*[a-zA-Z%]`)
+
+func TestCatalogVerbs(t *testing.T) {
+	for locale, messages := range catalog {
+		if _, ok := localeNames[locale]; !ok {
+			t.Errorf("catalog has unknown locale %q", locale)
+		}
+		for key, msg := range messages {
+			want := strings.Join(verbPattern.FindAllString(key, -1), " ")
+			if got := strings.Join(verbPattern.FindAllString(msg, -1), " "); got != want {
+				t.Errorf("%s: %q has verbs %q, want %q", locale, msg, got, want)
+			}
+		}
+	}
+
+	// Every locale translates the same strings
+	for key := range catalog["es"] {
+		if _, ok := catalog["fr"][key]; !ok {
+			t.Errorf("fr is missing %q", key)
+		}
+	}
+	for key := range catalog["fr"] {
+		if _, ok := catalog["es"][key]; !ok {
+			t.Errorf("es is missing %q", key)
+		}
+	}
+}
+
+func TestHelpTranslated(t *testing.T) {
+	english := DisplayHelp("g1", "en")
+	for _, locale := range []string{"es", "fr"} {
+		help := DisplayHelp("g1", locale)
+		if help.Title == english.Title {
+			t.Errorf("%s: title not translated", locale)
+		}
+		for i, f := range help.Fields {
+			if f.Value == english.Fields[i].Value {
+				t.Errorf("%s: %s not translated", locale, f.Name)
+			}
+		}
+	}
+}
+
+func TestLocaleCommand(t *testing.T) {
+	f := newFakeEBird(t)
+	useTempStore(t)
+	f.route("/data/obs/geo/recent", "recent.json")
+
+	sent := send(t, "!locale")
+	if len(sent) != 1 || !strings.HasPrefix(sent[0].Content, "Your language is English.") {
+		t.Fatalf("unexpected reply %+v", sent)
+	}
+
+	// The server's language applies to everyone in it
+	sent = send(t, "!locale server fr")
+	if len(sent) != 1 || !strings.HasPrefix(sent[0].Content, "Error: you need the Manage Server permission") {
+		t.Fatalf("unexpected reply %+v", sent)
+	}
+	sent = sendAsAdmin(t, "!locale server fr")
+	if len(sent) != 1 || sent[0].Content != "La langue de ce serveur est maintenant Français." {
+		t.Fatalf("unexpected reply %+v", sent)
+	}
+	sent = send(t, "!get mendon")
+	if len(sent) != 1 || !strings.HasPrefix(sent[0].Content, "**Observations eBird vérifiées à moins de 5 km de Mendon Ponds Park depuis 14 jours:**") {
+		t.Errorf("unexpected reply %+v", sent)
+	}
+	if got := f.lastRequest().URL.Query().Get("sppLocale"); got != "fr" {
+		t.Errorf("sppLocale = %q, want fr", got)
+	}
+
+	// A user's own language wins over the server's
+	sent = send(t, "!locale me es-MX")
+	if len(sent) != 1 || sent[0].Content != "Tu idioma ahora es Español." {
+		t.Fatalf("unexpected reply %+v", sent)
+	}
+	sent = send(t, "!get atlantis")
+	if len(sent) != 1 || sent[0].Content != "Error: 'atlantis' no es una opción válida para !get" {
+		t.Errorf("unexpected reply %+v", sent)
+	}
+	sent = send(t, "!nearest")
+	if len(sent) != 1 || !strings.HasPrefix(sent[0].Content, "Error: usa `!nearest <especie>") {
+		t.Errorf("unexpected reply %+v", sent)
+	}
+
+	// reset goes back to the server's language, then to English
+	sent = send(t, "!locale me reset")
+	if len(sent) != 1 || sent[0].Content != "Votre langue est maintenant Français." {
+		t.Fatalf("unexpected reply %+v", sent)
+	}
+	sent = sendAsAdmin(t, "!locale server reset")
+	if len(sent) != 1 || sent[0].Content != "This server's language is now English." {
+		t.Fatalf("unexpected reply %+v", sent)
+	}
+	send(t, "!get mendon")
+	if f.lastRequest().URL.Query().Has("sppLocale") {
+		t.Errorf("English requests should not set sppLocale: %s", f.lastRequest().URL)
+	}
+
+	sent = send(t, "!locale me klingon")
+	if len(sent) != 1 || sent[0].Content != "Error: 'klingon' is not a supported language, use en/es/fr" {
+		t.Errorf("unexpected reply %+v", sent)
+	}
+}
+
+func TestFindTaxonLocalized(t *testing.T) {
+	f := newFakeEBird(t)
+	f.handle("/ref/taxonomy/ebird", func(w http.ResponseWriter, r *http.Request) {
+		name := "Snowy Owl"
+		if r.URL.Query().Get("locale") == "es" {
+			name = "Búho Nival"
+		}
+		fmt.Fprintf(w, `[{"sciName": "Bubo scandiacus", "comName": %q, "speciesCode": "snoowl1", "bandingCodes": ["SNOW"]}]`, name)
+	})
+
+	// Accents are optional
+	taxon, err := findTaxon(context.Background(), "buho nival", "es")
+	if err != nil || taxon.ComName != "Búho Nival" {
+		t.Errorf("findTaxon(context.Background(), buho nival) = %+v, %v", taxon, err)
+	}
+
+	// English names still work, with the name in the user's language
+	taxon, err = findTaxon(context.Background(), "snowy owl", "es")
+	if err != nil || taxon.ComName != "Búho Nival" {
+		t.Errorf("findTaxon(context.Background(), snowy owl) = %+v, %v", taxon, err)
+	}
+}
+
+func TestRepliesTranslated(t *testing.T) {
+	f := newFakeEBird(t)
+	useTempStore(t)
+	f.route("/ref/hotspot/geo", "hotspots.json")
+	f.route("/ref/taxonomy/ebird", "taxonomy.json")
+	sendAsAdmin(t, "!locale server es")
+
+	sent := send(t, "!hotspots near 43.08,-77.67 10")
+	if len(sent) != 1 || !strings.HasPrefix(sent[0].Content, "**Hotspots de eBird a menos de 10 km de 43.08,-77.67:**\n") ||
+		!strings.Contains(sent[0].Content, "3. Quiet Pond (`L2000001`): 12 especies, último nunca\n") {
+		t.Errorf("unexpected list %+v", sent)
+	}
+	sent = sendAsAdmin(t, "!hotspots save 3 pond")
+	if len(sent) != 1 || sent[0].Content != "Se guardó **Quiet Pond** (`L2000001`) como `pond`. Pruébalo con `!get pond`." {
+		t.Errorf("unexpected reply %+v", sent)
+	}
+	sent = sendAsAdmin(t, "!hotspots save 3 pond")
+	if len(sent) != 1 || !strings.HasPrefix(sent[0].Content, "Error: este servidor ya tiene un lugar llamado 'pond'") {
+		t.Errorf("unexpected reply %+v", sent)
+	}
+	sent = send(t, "!locations")
+	if len(sent) != 1 || !strings.HasPrefix(sent[0].Content, "**Lugares:**\n") {
+		t.Errorf("unexpected list %+v", sent)
+	}
+
+	embeds, err := ChecklistEmbeds(context.Background(), Checklist{SubID: "S1"}, "fr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := embeds[0]
+	if e.Title != "Liste S1" || e.Footer.Text != "Page 1/1" || e.Description != "Aucune espèce signalée." ||
+		e.Fields[0].Name != "Observateur" || e.Fields[0].Value != "Aucun" || e.Fields[2].Value != "sans durée" || e.Fields[5].Value != "Non" {
+		t.Errorf("unexpected embed %+v", e)
+	}
+
+	date := time.Date(2022, time.October, 10, 0, 0, 0, 0, time.Local)
+	if got := monthDay(date, "es"); got != "10 de octubre" {
+		t.Errorf("monthDay(es) = %q", got)
+	}
+	history := FormatHistory("Mendon", []YearSightings{{Date: date}}, "fr")
+	if !strings.HasPrefix(history, "**Observations eBird à Mendon le 10 octobre :**\n```\nAnnée  Espèces\n") ||
+		!strings.HasSuffix(history, "**Absentes en 2022** (années où elle a été vue avant) : aucune\n") {
+		t.Errorf("unexpected history %q", history)
+	}
+	stats := FormatDayStats("US-NY", DayStats{Date: date, NumChecklists: 1, NumContributors: 2, NumSpecies: 30}, "es")
+	if stats != "**Estadísticas de eBird de US-NY el 2022-10-10:**\n1 lista de 2 colaboradores, 30 especies\n" {
+		t.Errorf("unexpected stats %q", stats)
+	}
+	if got := BirdEmbed(EmbedInfo{Name: "Bird not found!"}, "es"); got.Title != "¡Ave no encontrada!" {
+		t.Errorf("unexpected embed %+v", got)
+	}
+}
+
+// translatedFormats returns the strings FlaminGo's source translates, keyed to their position: the formats passed to
+// usageErrorf and tr, the string arguments of usageErrorf that UsageError.translate looks up, and the nouns passed to
+// pluralizeIn. Strings that are not a literal or a constant are skipped.
+func translatedFormats(t *testing.T) map[string]string {
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, ".", func(fi fs.FileInfo) bool { return !strings.HasSuffix(fi.Name(), "_test.go") }, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// str returns the value of a string literal, or of a constant set to one
+	str := func(arg ast.Expr) (string, bool) {
+		if id, ok := arg.(*ast.Ident); ok && id.Obj != nil {
+			if spec, ok := id.Obj.Decl.(*ast.ValueSpec); ok && len(spec.Values) == 1 {
+				arg = spec.Values[0]
+			}
+		}
+		lit, ok := arg.(*ast.BasicLit)
+		if !ok || lit.Kind != token.STRING {
+			return "", false
+		}
+		s, err := strconv.Unquote(lit.Value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s, true
+	}
+
+	formats := make(map[string]string)
+	for _, pkg := range pkgs {
+		for _, file := range pkg.Files {
+			ast.Inspect(file, func(n ast.Node) bool {
+				call, ok := n.(*ast.CallExpr)
+				if !ok {
+					return true
+				}
+				fun, ok := call.Fun.(*ast.Ident)
+				if !ok {
+					return true
+				}
+				pos := fset.Position(call.Pos()).String()
+				switch {
+				case fun.Name == "usageErrorf" && len(call.Args) > 0:
+					if s, ok := str(call.Args[0]); ok {
+						formats[s] = pos
+					}
+					for _, arg := range call.Args[1:] {
+						if s, ok := str(arg); ok && strings.Contains(s, "`") {
+							formats[s] = pos
+						}
+					}
+				case fun.Name == "tr" && len(call.Args) > 1:
+					if s, ok := str(call.Args[1]); ok {
+						formats[s] = pos
+					}
+				case fun.Name == "pluralizeIn" && len(call.Args) == 3:
+					if s, ok := str(call.Args[2]); ok {
+						formats[s] = pos
+						formats[s+"s"] = pos
+					}
+				}
+				return true
+			})
+		}
+	}
+	return formats
+}
+
+func TestCatalogComplete(t *testing.T) {
+	formats := translatedFormats(t)
+	if len(formats) == 0 {
+		t.Fatal("found no strings to translate")
+	}
+	for format, pos := range formats {
+		// Formats such as "%s" have nothing to translate
+		if strings.IndexFunc(verbPattern.ReplaceAllString(format, ""), unicode.IsLetter) < 0 {
+			continue
+		}
+		for locale := range catalog {
+			if _, ok := catalog[locale][format]; !ok {
+				t.Errorf("%s: %s is missing %q", pos, locale, format)
+			}
+		}
+	}
+}
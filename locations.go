@@ -0,0 +1,100 @@
+// Locations contains the !locations commands for listing locations and choosing how they are queried
+
+package main
+
+import (
+	"sort"
+)
+
+// locationsUsage explains the !locations subcommands.
+const locationsUsage = "use `!locations` or `!locations mode <location> <geo or hotspot>`"
+
+// runLocations handles !locations and !locations mode.
+func runLocations(s Sender, inv *Invocation) error {
+	positional, _ := splitOptions(inv.Args)
+	if len(positional) == 0 {
+		inv.send(s, ListLocations(inv.GuildID, inv.Locale))
+		return nil
+	}
+
+	switch positional[0] {
+	case "mode":
+		err := requireManageServer(s, inv)
+		// Error handling
+		if err != nil {
+			return err
+		}
+		if len(positional) != 3 {
+			return usageErrorf("%s", locationsUsage)
+		}
+		msg, err := SetLocationMode(inv.GuildID, positional[1], positional[2], inv.Locale)
+		// Error handling
+		if err != nil {
+			return err
+		}
+		inv.send(s, msg)
+	default:
+		return usageErrorf("'%s' is not a valid option for !locations, %s", positional[0], locationsUsage)
+	}
+	return nil
+}
+
+// ListLocations returns the built-in and saved locations available to a guild, with their query modes, in locale.
+func ListLocations(guildID, locale string) string {
+	g := store.Guild(guildID)
+
+	builtin := Locations()
+	var names []string
+	for name := range builtin {
+		names = append(names, name)
+	}
+	for name := range g.Locations {
+		if _, ok := builtin[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	rString := tr(locale, "**Locations:**\n")
+	for _, name := range names {
+		loc, _ := namedLocation(guildID, name)
+		mode := loc.mode
+		if mode == "" {
+			mode = modeGeo
+		}
+		rString += tr(locale, "`%s`: %s (%s)\n", name, loc.name, mode)
+	}
+
+	//Trimming return string to Discord max message length, minus 5 characters to show max length has been reached
+	return truncateText(rString, 1995)
+}
+
+// SetLocationMode sets the query mode a guild uses for a location, and returns a reply in locale.
+func SetLocationMode(guildID, name, mode, locale string) (string, error) {
+	if guildID == "" {
+		return "", usageErrorf("location modes can only be set in a server")
+	}
+	loc, ok := namedLocation(guildID, name)
+	if !ok {
+		return "", usageErrorf("'%s' is not a known location", name)
+	}
+	err := validateMode(mode, loc.code)
+	// Error handling
+	if err != nil {
+		return "", err
+	}
+
+	err = store.Update(guildID, func(g *GuildSettings) error {
+		if g.LocationModes == nil {
+			g.LocationModes = make(map[string]string)
+		}
+		g.LocationModes[name] = mode
+		return nil
+	})
+	// Error handling
+	if err != nil {
+		return "", err
+	}
+
+	return tr(locale, "`%s` (%s) now uses %s mode.", name, loc.name, mode), nil
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHotspotMode(t *testing.T) {
+	f := newFakeEBird(t)
+	useTempStore(t)
+	f.route("/data/obs/L139800/recent", "recent.json")
+	f.route("/data/obs/L139800/recent/notable", "notable.json")
+
+	sent := send(t, "!get mendon mode:hotspot days:7")
+	if len(sent) != 1 || !strings.HasPrefix(sent[0].Content, "**Verified eBird sightings at the Mendon Ponds Park hotspot in the past 7 days:**") {
+		t.Fatalf("unexpected reply %+v", sent)
+	}
+	if got := f.lastRequest().URL.Query().Get("back"); got != "7" {
+		t.Errorf("back = %q, want 7", got)
+	}
+
+	// Setting the mode for the guild applies it without the option
+	sent = send(t, "!locations mode mendon hotspot")
+	if len(sent) != 1 || !strings.HasPrefix(sent[0].Content, "Error: you need the Manage Server permission") {
+		t.Fatalf("unexpected reply %+v", sent)
+	}
+	if loc, _ := namedLocation("g1", "mendon"); loc.mode != "" {
+		t.Fatalf("mode set without permission: %+v", loc)
+	}
+	sent = sendAsAdmin(t, "!locations mode mendon hotspot")
+	if len(sent) != 1 || sent[0].Content != "`mendon` (Mendon Ponds Park) now uses hotspot mode." {
+		t.Fatalf("unexpected reply %+v", sent)
+	}
+	sent = send(t, "!rare mendon")
+	if len(sent) != 1 || !strings.HasPrefix(sent[0].Content, "**Notable eBird sightings at the Mendon Ponds Park hotspot") {
+		t.Fatalf("unexpected reply %+v", sent)
+	}
+	if got := f.lastRequest().URL.Path; got != "/data/obs/L139800/recent/notable" {
+		t.Errorf("path = %q", got)
+	}
+
+	// mode:geo still overrides the guild setting for one search
+	f.route("/data/obs/geo/recent", "recent.json")
+	send(t, "!get mendon mode:geo")
+	if got := f.lastRequest().URL.Path; got != "/data/obs/geo/recent" {
+		t.Errorf("path = %q", got)
+	}
+
+	sent = send(t, "!locations")
+	if len(sent) != 1 || !strings.Contains(sent[0].Content, "`mendon`: Mendon Ponds Park (hotspot)\n") || !strings.Contains(sent[0].Content, "`rit`: Rochester Institute of Technology (geo)\n") {
+		t.Errorf("unexpected list %+v", sent)
+	}
+}
+
+func TestLocationModeErrors(t *testing.T) {
+	newFakeEBird(t)
+	useTempStore(t)
+
+	tests := map[string]string{
+		"!get rit mode:circle":          "Error: mode must be 'geo' or 'hotspot'",
+		"!locations mode atlantis geo":  "Error: 'atlantis' is not a known location",
+		"!locations mode rit sometimes": "Error: mode must be",
+		"!locations rename rit":         "Error: 'rename' is not a valid option for !locations",
+	}
+	for msg, want := range tests {
+		sent := sendAsAdmin(t, msg)
+		if len(sent) != 1 || !strings.HasPrefix(sent[0].Content, want) {
+			t.Errorf("%s: got %+v, want prefix %q", msg, sent, want)
+		}
+	}
+}
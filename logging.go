@@ -0,0 +1,274 @@
+// Logging sets up FlaminGo's structured logger and the per-command correlation used in log lines and error replies
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// logLevel is the level logger writes at. setupLogger changes it, so the level can be reloaded while commands log.
+var logLevel slog.LevelVar
+
+// logger is the structured logger used throughout FlaminGo, a text logger writing to stderr at logLevel.
+var logger = slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: &logLevel}))
+
+// setupLogger sets the level logger writes at ("debug", "info", "warn" or "error"), and makes it slog's default logger.
+// Unknown levels fall back to info.
+func setupLogger(level string) {
+	var l slog.Level
+	err := l.UnmarshalText([]byte(level))
+	// Error handling
+	if err != nil {
+		l = slog.LevelInfo
+	}
+
+	logLevel.Set(l)
+	slog.SetDefault(logger)
+}
+
+// Invocation holds information about a single command sent to FlaminGo, so that every log line and error reply
+// produced while handling it can be tied back together with the same correlation ID.
+type Invocation struct {
+	// ID is a short random string identifying this invocation in logs and in error messages shown to users.
+	ID string
+	// Command is the command name without its prefix, e.g. "get".
+	Command string
+	// Args holds the arguments given after the command name.
+	Args []string
+	// Content is the whole message as it was typed, keeping the case and spacing that Args lose.
+	Content string
+	// Attachments are the files attached to the message.
+	Attachments []*discordgo.MessageAttachment
+	// GuildID, ChannelID and UserID identify where the command was sent and by whom.
+	GuildID   string
+	ChannelID string
+	UserID    string
+	// Locale is the language to reply in, from the user's or the guild's settings.
+	Locale string
+	// Log is a logger carrying all of the above as attributes.
+	Log *slog.Logger
+
+	// ctx is cancelled when the command runs out of time. See Context.
+	ctx context.Context
+}
+
+// Context returns the invocation's context, which upstream requests made for the command should use.
+func (inv *Invocation) Context() context.Context {
+	if inv.ctx == nil {
+		return context.Background()
+	}
+	return inv.ctx
+}
+
+// newInvocation creates an Invocation with a fresh correlation ID for the given message and tokenized command.
+func newInvocation(m *discordgo.MessageCreate, command string, args []string) *Invocation {
+	inv := &Invocation{
+		ID:          newCorrelationID(),
+		Command:     command,
+		Args:        args,
+		Content:     m.Content,
+		Attachments: m.Attachments,
+		GuildID:     m.GuildID,
+		ChannelID:   m.ChannelID,
+		UserID:      m.Author.ID,
+		Locale:      localeFor(m.GuildID, m.Author.ID),
+	}
+
+	inv.Log = logger.With(
+		slog.String("cid", inv.ID),
+		slog.String("guild", inv.GuildID),
+		slog.String("channel", inv.ChannelID),
+		slog.String("user", inv.UserID),
+		slog.String("command", inv.Command),
+		slog.String("args", strings.Join(inv.Args, " ")),
+	)
+
+	return inv
+}
+
+// textAfter returns the message as it was typed after the first occurrence of word, which is matched ignoring case,
+// e.g. the announcement in "!admin broadcast Meet at 8!".
+func (inv *Invocation) textAfter(word string) string {
+	// Lowercasing can change the length of the text before word, so it is matched in the message itself
+	for i := range inv.Content {
+		if n, ok := foldPrefix(inv.Content[i:], word); ok {
+			return strings.TrimSpace(inv.Content[i+n:])
+		}
+	}
+	return ""
+}
+
+// foldPrefix reports whether s starts with prefix ignoring case, and returns the length in bytes of that start of s.
+func foldPrefix(s, prefix string) (int, bool) {
+	n := 0
+	for _, want := range prefix {
+		r, size := utf8.DecodeRuneInString(s[n:])
+		if size == 0 || !strings.EqualFold(string(r), string(want)) {
+			return 0, false
+		}
+		n += size
+	}
+	return n, true
+}
+
+// userError returns the friendly message shown to users when a command fails. The full error is only kept in the logs.
+func (inv *Invocation) userError() string {
+	return tr(inv.Locale, "Sorry, something went wrong while running that command. If this keeps happening, let an admin know the reference code `%s`.", inv.ID)
+}
+
+// fail logs err with the invocation's attributes and sends the friendly error message to the invocation's channel.
+// A UsageError is the user's mistake, so its message is sent instead. Timeouts and a BusyError say to try again later.
+func (inv *Invocation) fail(s Sender, err error) {
+	var usageErr *UsageError
+	if errors.As(err, &usageErr) {
+		inv.Log.Info("invalid command usage", slog.String("reason", usageErr.msg))
+		inv.send(s, tr(inv.Locale, "Error: %s", usageErr.translate(inv.Locale)))
+		return
+	}
+
+	// Slow upstreams are not the user's fault, and they should know FlaminGo stopped waiting
+	if errors.Is(err, context.DeadlineExceeded) {
+		inv.Log.Warn("command timed out", slog.Any("err", err))
+		inv.send(s, tr(inv.Locale, "That took longer than %s, so FlaminGo gave up. eBird or AllAboutBirds may be slow right now, try again in a little while.", waitText(inv.Locale, time.Duration(Conf().CommandTimeout)*time.Second)))
+		return
+	}
+
+	// Upstream limits are shared by everyone, so they are not the user's fault either
+	var busyErr *BusyError
+	if errors.As(err, &busyErr) {
+		inv.Log.Warn("upstream busy", slog.String("upstream", busyErr.Upstream), slog.Duration("wait", busyErr.Wait))
+		inv.send(s, tr(inv.Locale, "%s is getting too many requests from FlaminGo right now. Try again in %s.", busyErr.Upstream, waitText(inv.Locale, busyErr.Wait)))
+		return
+	}
+
+	inv.Log.Error("command failed", slog.Any("err", err))
+	inv.send(s, inv.userError())
+	reportProblem(s, inv, "error", err.Error(), "")
+}
+
+// send sends a text message to the invocation's channel, logging the error if Discord rejects it.
+func (inv *Invocation) send(s Sender, msg string) {
+	_, err := s.ChannelMessageSend(inv.ChannelID, msg)
+	// Error handling
+	if err != nil {
+		inv.Log.Warn("sending message failed", slog.Any("err", err))
+	}
+}
+
+// typingInterval is how often the typing indicator is renewed while a command runs. Discord shows it for 10 seconds.
+const typingInterval = 8 * time.Second
+
+// startTyping shows the typing indicator in the invocation's channel until the returned function is called.
+func (inv *Invocation) startTyping(s Sender) func() {
+	typing := func() {
+		err := s.ChannelTyping(inv.ChannelID)
+		// Error handling
+		if err != nil {
+			inv.Log.Debug("sending typing indicator failed", slog.Any("err", err))
+		}
+	}
+
+	// The first indicator is sent before the command runs, so it cannot arrive after the reply
+	typing()
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(typingInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				typing()
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+// sendEmbed sends an embed in the guild's color to the invocation's channel, logging the error if Discord rejects it.
+func (inv *Invocation) sendEmbed(s Sender, embed *discordgo.MessageEmbed) {
+	embed.Color = embedColor(inv.GuildID)
+	_, err := s.ChannelMessageSendEmbed(inv.ChannelID, embed)
+	// Error handling
+	if err != nil {
+		inv.Log.Warn("sending embed failed", slog.Any("err", err))
+	}
+}
+
+// Discord's limits on the embeds in one message.
+const (
+	maxMessageEmbeds     = 10
+	maxMessageEmbedChars = 6000
+)
+
+// sendEmbeds sends embeds in the guild's color to the invocation's channel, as few messages as Discord's limits allow,
+// logging the error if Discord rejects one.
+func (inv *Invocation) sendEmbeds(s Sender, embeds []*discordgo.MessageEmbed) {
+	for len(embeds) > 0 {
+		n, chars := 0, 0
+		for n < len(embeds) && n < maxMessageEmbeds {
+			chars += embedChars(embeds[n])
+			if n > 0 && chars > maxMessageEmbedChars {
+				break
+			}
+			embeds[n].Color = embedColor(inv.GuildID)
+			n++
+		}
+		_, err := s.ChannelMessageSendEmbeds(inv.ChannelID, embeds[:n])
+		// Error handling
+		if err != nil {
+			inv.Log.Warn("sending embeds failed", slog.Any("err", err))
+		}
+		embeds = embeds[n:]
+	}
+}
+
+// embedChars counts the characters of an embed that Discord adds up against maxMessageEmbedChars.
+func embedChars(e *discordgo.MessageEmbed) int {
+	n := utf8.RuneCountInString(e.Title) + utf8.RuneCountInString(e.Description)
+	if e.Footer != nil {
+		n += utf8.RuneCountInString(e.Footer.Text)
+	}
+	for _, f := range e.Fields {
+		n += utf8.RuneCountInString(f.Name) + utf8.RuneCountInString(f.Value)
+	}
+	return n
+}
+
+// sendFile sends a text message with a file attached to the invocation's channel, logging the error if Discord rejects it.
+func (inv *Invocation) sendFile(s Sender, msg, name, contentType string, data []byte) {
+	_, err := s.ChannelMessageSendComplex(inv.ChannelID, &discordgo.MessageSend{
+		Content: msg,
+		Files: []*discordgo.File{
+			{Name: name, ContentType: contentType, Reader: bytes.NewReader(data)},
+		},
+	})
+	// Error handling
+	if err != nil {
+		inv.Log.Warn("sending file failed", slog.Any("err", err), slog.String("file", name))
+	}
+}
+
+// newCorrelationID returns 8 random hex characters. If the system's random source fails, a fixed placeholder is used
+// rather than failing the command.
+func newCorrelationID() string {
+	b := make([]byte, 4)
+	_, err := rand.Read(b)
+	// Error handling
+	if err != nil {
+		return "00000000"
+	}
+	return hex.EncodeToString(b)
+}
@@ -4,8 +4,20 @@
 
 package main
 
-//Main calls the Start() function defined in the bot file, and
+import (
+	"log/slog"
+	"os"
+)
+
+//Main loads the configuration, calls the Start() function defined in the bot file, and
 func main() {
+	err := loadConfig(os.Args[1:])
+	// Error handling
+	if err != nil {
+		logger.Error("loading configuration", slog.Any("err", err))
+		os.Exit(1)
+	}
+
 	Start()
 	<-make(chan struct{})
 
@@ -0,0 +1,271 @@
+// Map renders sighting maps as PNG images, with an optional GeoJSON basemap loaded from disk
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"os"
+	"strconv"
+	"sync"
+)
+
+// MapPoint is a numbered place drawn on a sighting map.
+type MapPoint struct {
+	Number int
+	Lat    float64
+	Lng    float64
+}
+
+// Sighting map layout.
+const (
+	mapSize   = 600
+	mapMargin = 30
+	// markerRadius is the radius in pixels of the numbered markers.
+	markerRadius = 11
+	// minMapExtentKm is the smallest distance from the center to the edge of a map, so a single place is not zoomed in too far.
+	minMapExtentKm = 2.0
+)
+
+// Colors used on sighting maps.
+var (
+	colorBasemap = color.RGBA{0xc8, 0xd2, 0xdc, 0xff}
+	colorRadius  = color.RGBA{0xff, 0x99, 0xcc, 0xff}
+	colorWhite   = color.RGBA{0xff, 0xff, 0xff, 0xff}
+)
+
+// Basemap holds the lines of a GeoJSON basemap, as [long, lat] pairs.
+type Basemap struct {
+	Lines [][][2]float64
+}
+
+var (
+	// basemapMu guards basemap.
+	basemapMu sync.Mutex
+	// basemap is the basemap drawn under sighting maps, or nil for none.
+	basemap *Basemap
+)
+
+// geoJSONGeometry is the part of a GeoJSON geometry FlaminGo reads.
+type geoJSONGeometry struct {
+	Type        string
+	Coordinates json.RawMessage
+	Geometries  []geoJSONGeometry
+}
+
+// loadBasemap reads a GeoJSON file (a FeatureCollection, Feature or bare geometry) and keeps the outlines of its
+// lines and polygons. Points are ignored.
+func loadBasemap(file string) (*Basemap, error) {
+	data, err := os.ReadFile(file)
+	// Error handling
+	if err != nil {
+		return nil, fmt.Errorf("reading basemap: %w", err)
+	}
+
+	var doc struct {
+		Type     string
+		Features []struct {
+			Geometry geoJSONGeometry
+		}
+		Geometry *geoJSONGeometry
+		geoJSONGeometry
+	}
+	err = json.Unmarshal(data, &doc)
+	// Error handling
+	if err != nil {
+		return nil, fmt.Errorf("parsing basemap %s: %w", file, err)
+	}
+
+	bm := &Basemap{}
+	switch doc.Type {
+	case "FeatureCollection":
+		for _, f := range doc.Features {
+			err = bm.add(f.Geometry)
+			if err != nil {
+				break
+			}
+		}
+	case "Feature":
+		if doc.Geometry != nil {
+			err = bm.add(*doc.Geometry)
+		}
+	default:
+		// The outer Type field hides the one of the embedded geometry
+		doc.geoJSONGeometry.Type = doc.Type
+		err = bm.add(doc.geoJSONGeometry)
+	}
+	// Error handling
+	if err != nil {
+		return nil, fmt.Errorf("parsing basemap %s: %w", file, err)
+	}
+	return bm, nil
+}
+
+// add appends the outlines of a geometry to the basemap.
+func (bm *Basemap) add(g geoJSONGeometry) error {
+	var err error
+	switch g.Type {
+	case "LineString":
+		var line [][2]float64
+		err = json.Unmarshal(g.Coordinates, &line)
+		bm.Lines = append(bm.Lines, line)
+	case "MultiLineString", "Polygon":
+		var lines [][][2]float64
+		err = json.Unmarshal(g.Coordinates, &lines)
+		bm.Lines = append(bm.Lines, lines...)
+	case "MultiPolygon":
+		var polygons [][][][2]float64
+		err = json.Unmarshal(g.Coordinates, &polygons)
+		for _, p := range polygons {
+			bm.Lines = append(bm.Lines, p...)
+		}
+	case "GeometryCollection":
+		for _, child := range g.Geometries {
+			err = bm.add(child)
+			if err != nil {
+				break
+			}
+		}
+	}
+	return err
+}
+
+// projection maps lat/long to pixels with an equirectangular projection around a center, which is accurate enough
+// at the scale of a county.
+type projection struct {
+	centerLat, centerLng float64
+	// pxPerKm is the map scale.
+	pxPerKm float64
+}
+
+// kmPerDegreeLat is the length of a degree of latitude.
+const kmPerDegreeLat = 110.57
+
+// point returns the pixel for a lat/long.
+func (p projection) point(lat, lng float64) (float64, float64) {
+	xKm := (lng - p.centerLng) * kmPerDegreeLat * math.Cos(p.centerLat*math.Pi/180)
+	yKm := (lat - p.centerLat) * kmPerDegreeLat
+	return mapSize/2 + xKm*p.pxPerKm, mapSize/2 - yKm*p.pxPerKm
+}
+
+// RenderSightingMap draws the numbered places of a !rare list as a PNG map. Geo searches are centered on the club
+// location with the search radius drawn around it. Hotspot and region searches are fitted to the places instead.
+func RenderSightingMap(q ObsQuery, points []MapPoint) ([]byte, error) {
+	img := newCanvas(mapSize, mapSize)
+
+	// Working out the center and how far the map reaches from it
+	var p projection
+	extentKm := minMapExtentKm
+	hasCenter := q.Region == ""
+	if hasCenter {
+		p.centerLat, p.centerLng = q.Location.lat, q.Location.long
+	} else {
+		minLat, maxLat, minLng, maxLng := points[0].Lat, points[0].Lat, points[0].Lng, points[0].Lng
+		for _, pt := range points {
+			minLat, maxLat = math.Min(minLat, pt.Lat), math.Max(maxLat, pt.Lat)
+			minLng, maxLng = math.Min(minLng, pt.Lng), math.Max(maxLng, pt.Lng)
+		}
+		p.centerLat, p.centerLng = (minLat+maxLat)/2, (minLng+maxLng)/2
+	}
+	if hasCenter && q.Mode != modeHotspot {
+		extentKm = math.Max(extentKm, float64(q.Radius))
+	}
+	for _, pt := range points {
+		extentKm = math.Max(extentKm, haversineKm(p.centerLat, p.centerLng, pt.Lat, pt.Lng))
+	}
+	p.pxPerKm = (mapSize/2 - mapMargin) / (extentKm * 1.05)
+
+	// Basemap
+	basemapMu.Lock()
+	bm := basemap
+	basemapMu.Unlock()
+	if bm != nil {
+		for _, line := range bm.Lines {
+			for i := 1; i < len(line); i++ {
+				x0, y0 := p.point(line[i-1][1], line[i-1][0])
+				x1, y1 := p.point(line[i][1], line[i][0])
+				drawLine(img, x0, y0, x1, y1, colorBasemap)
+			}
+		}
+	}
+
+	// Search radius and club location
+	if hasCenter {
+		cx, cy := p.point(p.centerLat, p.centerLng)
+		if q.Mode != modeHotspot {
+			drawRing(img, cx, cy, float64(q.Radius)*p.pxPerKm, 2, colorRadius)
+		}
+		fillRect(img, image.Rect(int(cx)-6, int(cy)-6, int(cx)+6, int(cy)+6), colorAxis)
+	}
+
+	// Numbered places, drawn from the last so the first ones end up on top
+	for i := len(points) - 1; i >= 0; i-- {
+		x, y := p.point(points[i].Lat, points[i].Lng)
+		drawDisc(img, x, y, markerRadius+2, colorWhite)
+		drawDisc(img, x, y, markerRadius, colorPink)
+		label := strconv.Itoa(points[i].Number)
+		drawText(img, int(x)-textWidth(label, 2)/2, int(y)-5, label, 2, colorWhite)
+	}
+
+	drawScaleBar(img, p.pxPerKm)
+	return encodePNG(img)
+}
+
+// drawScaleBar draws a bar of a round number of kilometers in the bottom left corner.
+func drawScaleBar(img draw.Image, pxPerKm float64) {
+	km := 1.0
+	for _, step := range []float64{1, 2, 5, 10, 20, 50} {
+		if step*pxPerKm <= mapSize/4 {
+			km = step
+		}
+	}
+	length := int(km * pxPerKm)
+	y := mapSize - 14
+	fillRect(img, image.Rect(10, y, 10+length, y+3), colorAxis)
+	fillRect(img, image.Rect(10, y-6, 12, y+3), colorAxis)
+	fillRect(img, image.Rect(8+length, y-6, 10+length, y+3), colorAxis)
+	drawText(img, 14+length, y-7, fmt.Sprintf("%g KM", km), 2, colorAxis)
+}
+
+// drawDisc fills a circle.
+func drawDisc(img draw.Image, cx, cy, r float64, c color.Color) {
+	for y := int(cy - r); y <= int(cy+r); y++ {
+		for x := int(cx - r); x <= int(cx+r); x++ {
+			dx, dy := float64(x)+0.5-cx, float64(y)+0.5-cy
+			if dx*dx+dy*dy <= r*r {
+				img.Set(x, y, c)
+			}
+		}
+	}
+}
+
+// drawRing draws the outline of a circle of radius r, width pixels thick.
+func drawRing(img draw.Image, cx, cy, r, width float64, c color.Color) {
+	b := img.Bounds()
+	for y := int(math.Max(cy-r-width, float64(b.Min.Y))); y <= int(math.Min(cy+r+width, float64(b.Max.Y))); y++ {
+		for x := int(math.Max(cx-r-width, float64(b.Min.X))); x <= int(math.Min(cx+r+width, float64(b.Max.X))); x++ {
+			d := math.Hypot(float64(x)+0.5-cx, float64(y)+0.5-cy)
+			if math.Abs(d-r) <= width/2 {
+				img.Set(x, y, c)
+			}
+		}
+	}
+}
+
+// drawLine draws a one pixel line, skipping lines entirely outside the image.
+func drawLine(img draw.Image, x0, y0, x1, y1 float64, c color.Color) {
+	b := img.Bounds()
+	if math.Max(x0, x1) < float64(b.Min.X) || math.Min(x0, x1) > float64(b.Max.X) ||
+		math.Max(y0, y1) < float64(b.Min.Y) || math.Min(y0, y1) > float64(b.Max.Y) {
+		return
+	}
+	steps := int(math.Max(math.Abs(x1-x0), math.Abs(y1-y0))) + 1
+	for i := 0; i <= steps; i++ {
+		t := float64(i) / float64(steps)
+		img.Set(int(x0+(x1-x0)*t), int(y0+(y1-y0)*t), c)
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bytes"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRenderSightingMap(t *testing.T) {
+	q := ObsQuery{Location: Locations()["braddock"], Radius: 15}
+	points := []MapPoint{{Number: 1, Lat: 43.3304, Lng: -77.7125}, {Number: 2, Lat: 43.3040, Lng: -77.7128}}
+	data, err := RenderSightingMap(q, points)
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("map is not a PNG: %v", err)
+	}
+	if b := img.Bounds(); b.Dx() != mapSize || b.Dy() != mapSize {
+		t.Errorf("unexpected image size %v", b)
+	}
+
+	// The edge of the first marker is pink
+	p := projection{centerLat: q.Location.lat, centerLng: q.Location.long, pxPerKm: (mapSize/2 - mapMargin) / (15 * 1.05)}
+	x, y := p.point(points[0].Lat, points[0].Lng)
+	if r, g, b, _ := img.At(int(x)-markerRadius+2, int(y)).RGBA(); uint8(r>>8) != colorPink.R || uint8(g>>8) != colorPink.G || uint8(b>>8) != colorPink.B {
+		t.Errorf("marker color = %d,%d,%d, want pink", r>>8, g>>8, b>>8)
+	}
+}
+
+func TestRenderSightingMapRegion(t *testing.T) {
+	// Region searches have no center, so the map fits the places
+	q := ObsQuery{Region: "US-NY-055", Radius: 15}
+	data, err := RenderSightingMap(q, []MapPoint{{Number: 1, Lat: 43.2, Lng: -77.6}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := png.Decode(bytes.NewReader(data)); err != nil {
+		t.Fatalf("map is not a PNG: %v", err)
+	}
+}
+
+func TestLoadBasemap(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "basemap.geojson")
+	geojson := `{"type": "FeatureCollection", "features": [
+		{"type": "Feature", "geometry": {"type": "LineString", "coordinates": [[-77.7, 43.3], [-77.6, 43.2]]}},
+		{"type": "Feature", "geometry": {"type": "Polygon", "coordinates": [[[-77.7, 43.3], [-77.6, 43.3], [-77.6, 43.2], [-77.7, 43.3]]]}},
+		{"type": "Feature", "geometry": {"type": "MultiPolygon", "coordinates": [[[[-77.7, 43.3], [-77.6, 43.3]]], [[[-77.5, 43.1], [-77.4, 43.1]]]]}},
+		{"type": "Feature", "geometry": {"type": "Point", "coordinates": [-77.7, 43.3]}}
+	]}`
+	if err := os.WriteFile(file, []byte(geojson), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	bm, err := loadBasemap(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bm.Lines) != 4 {
+		t.Errorf("got %d lines, want 4", len(bm.Lines))
+	}
+	if bm.Lines[1][2] != [2]float64{-77.6, 43.2} {
+		t.Errorf("polygon point = %v", bm.Lines[1][2])
+	}
+
+	// A basemap is drawn under the map without getting in the way of the markers
+	basemap = bm
+	t.Cleanup(func() { basemap = nil })
+	if _, err := RenderSightingMap(ObsQuery{Location: Locations()["braddock"], Radius: 15}, []MapPoint{{Number: 1, Lat: 43.3, Lng: -77.7}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(file, []byte(`{"type": "LineString", "coordinates": "nope"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadBasemap(file); err == nil {
+		t.Error("want an error for malformed coordinates")
+	}
+}
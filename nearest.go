@@ -0,0 +1,179 @@
+// Nearest contains the !nearest command for finding the closest recent sightings of a species
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// NearbySighting is a sighting with its distance and direction from where the search started.
+type NearbySighting struct {
+	BirdSighting
+	// DistanceKm is the great-circle distance in kilometers.
+	DistanceKm float64
+	// Bearing is the compass direction, e.g. "NE".
+	Bearing string
+}
+
+// Limits for !nearest.
+const (
+	// nearestResults is the number of reports shown.
+	nearestResults = 10
+	// nearestMaxDistance is the search radius in km, the most eBird allows for this endpoint.
+	nearestMaxDistance = 50
+)
+
+// nearestUsage explains the !nearest command.
+const nearestUsage = "use `!nearest <species> [from <location>] [days] [export:format]`, e.g. `!nearest red-headed woodpecker from rit 7`"
+
+// runNearest handles !nearest <species> [from location] [days].
+func runNearest(s Sender, inv *Invocation) error {
+	ctx := inv.Context()
+	positional, options := splitOptions(inv.Args)
+	if len(positional) == 0 {
+		return usageErrorf("%s", nearestUsage)
+	}
+
+	// The number of days comes from days: or a trailing number
+	days, err := intOption(options, "days", 1, 30, Conf().BackDays)
+	// Error handling
+	if err != nil {
+		return err
+	}
+	last := positional[len(positional)-1]
+	if _, convErr := strconv.Atoi(last); convErr == nil {
+		days, err = intOption(map[string]string{"days": last}, "days", 1, 30, days)
+		// Error handling
+		if err != nil {
+			return err
+		}
+		positional = positional[:len(positional)-1]
+	}
+
+	// Everything before "from" is the species name
+	speciesWords := positional
+	loc := defaultLocation(inv.GuildID)
+	for i, w := range positional {
+		if w != "from" {
+			continue
+		}
+		speciesWords = positional[:i]
+		if len(positional[i+1:]) != 1 {
+			return usageErrorf("%s", nearestUsage)
+		}
+		var ok bool
+		loc, ok = namedLocation(inv.GuildID, positional[i+1])
+		if !ok {
+			return usageErrorf("'%s' is not a valid option for !nearest", positional[i+1])
+		}
+		break
+	}
+	if len(speciesWords) == 0 {
+		return usageErrorf("%s", nearestUsage)
+	}
+
+	taxon, err := findTaxon(ctx, strings.Join(speciesWords, " "), inv.Locale)
+	// Error handling
+	if err != nil {
+		return err
+	}
+
+	f, err := exportOption(options)
+	// Error handling
+	if err != nil {
+		return err
+	}
+
+	nearby, err := GetNearest(ctx, taxon, loc, days, inv.Locale)
+	// Error handling
+	if err != nil {
+		return err
+	}
+	if f != "" {
+		return inv.sendExport(s, f, "nearest-"+taxon.SpeciesCode,
+			tr(inv.Locale, "Nearest %s to %s in the past %s", taxon.ComName, loc.name, pluralizeIn(inv.Locale, days, "day")), nearby)
+	}
+	inv.send(s, FormatNearest(taxon, loc, days, nearby, inv.Locale))
+	return nil
+}
+
+// GetNearest returns the closest recent sightings of a species to loc within the past days, closest first,
+// with names in locale.
+func GetNearest(ctx context.Context, taxon Taxon, loc Location, days int, locale string) ([]NearbySighting, error) {
+	url := fmt.Sprintf("%s/data/nearest/geo/recent/%s?lat=%v&lng=%v&back=%d&dist=%d&maxResults=%d%s", Conf().EBirdURL, taxon.SpeciesCode,
+		loc.lat, loc.long, days, nearestMaxDistance, nearestResults, sppLocaleParam(locale))
+
+	var b []BirdSighting
+	err := ebirdGet(ctx, url, &b)
+	// Error handling
+	if err != nil {
+		return nil, fmt.Errorf("getting nearest %s to %s: %w", taxon.SpeciesCode, loc.name, err)
+	}
+
+	nearby := make([]NearbySighting, len(b))
+	for i, s := range b {
+		nearby[i] = NearbySighting{
+			BirdSighting: s,
+			DistanceKm:   haversineKm(loc.lat, loc.long, s.Lat, s.Lng),
+			Bearing:      compassPoint(bearingDegrees(loc.lat, loc.long, s.Lat, s.Lng)),
+		}
+	}
+	sort.SliceStable(nearby, func(i, j int) bool {
+		return nearby[i].DistanceKm < nearby[j].DistanceKm
+	})
+	if len(nearby) > nearestResults {
+		nearby = nearby[:nearestResults]
+	}
+	return nearby, nil
+}
+
+// FormatNearest returns the nearest sightings of a species for a Discord message in locale.
+func FormatNearest(taxon Taxon, loc Location, days int, nearby []NearbySighting, locale string) string {
+	if len(nearby) == 0 {
+		return tr(locale, "**No %s reported within %d km of %s in the past %s.**", taxon.ComName, nearestMaxDistance, loc.name, pluralizeIn(locale, days, "day"))
+	}
+
+	rString := fmt.Sprintf("**%s:**\n", tr(locale, "Nearest %s to %s in the past %s", taxon.ComName, loc.name, pluralizeIn(locale, days, "day")))
+	for i, s := range nearby {
+		count := tr(locale, "present")
+		if s.HowMany > 0 {
+			count = strconv.Itoa(s.HowMany)
+		}
+		rString += tr(locale, "%d. %.1f km %s: %s, %s on %s\n", i+1, s.DistanceKm, s.Bearing, s.LocName, count, s.ObsDt)
+	}
+
+	//Trimming return string to Discord max message length, minus 5 characters to show max length has been reached
+	return truncateText(rString, 1995)
+}
+
+// haversineKm returns the great-circle distance in kilometers between two points.
+func haversineKm(lat1, long1, lat2, long2 float64) float64 {
+	const earthRadiusKm = 6371.0
+	φ1, φ2 := lat1*math.Pi/180, lat2*math.Pi/180
+	dφ := (lat2 - lat1) * math.Pi / 180
+	dλ := (long2 - long1) * math.Pi / 180
+
+	a := math.Sin(dφ/2)*math.Sin(dφ/2) + math.Cos(φ1)*math.Cos(φ2)*math.Sin(dλ/2)*math.Sin(dλ/2)
+	return earthRadiusKm * 2 * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
+}
+
+// bearingDegrees returns the initial compass bearing in degrees (0 is north, 90 is east) from the first point to the second.
+func bearingDegrees(lat1, long1, lat2, long2 float64) float64 {
+	φ1, φ2 := lat1*math.Pi/180, lat2*math.Pi/180
+	dλ := (long2 - long1) * math.Pi / 180
+
+	y := math.Sin(dλ) * math.Cos(φ2)
+	x := math.Cos(φ1)*math.Sin(φ2) - math.Sin(φ1)*math.Cos(φ2)*math.Cos(dλ)
+	return math.Mod(math.Atan2(y, x)*180/math.Pi+360, 360)
+}
+
+// compassPoint turns a bearing in degrees into one of the eight compass points.
+func compassPoint(degrees float64) string {
+	points := []string{"N", "NE", "E", "SE", "S", "SW", "W", "NW"}
+	return points[int(math.Mod(degrees+22.5, 360)/45)]
+}
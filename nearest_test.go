@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNearest(t *testing.T) {
+	f := newFakeEBird(t)
+	f.route("/ref/taxonomy/ebird", "taxonomy.json")
+	f.route("/data/nearest/geo/recent/snoowl1", "nearest_snoowl1.json")
+
+	sent := send(t, "!nearest snowy owl from rit 7")
+	want := "**Nearest Snowy Owl to Rochester Institute of Technology in the past 7 days:**\n" +
+		"1. 4.3 km N: Rochester Airport, present on 2022-10-09 16:10\n" +
+		"2. 25.2 km N: Braddock Bay Park, 1 on 2022-10-11 07:30\n" +
+		"3. 38.5 km NW: Hamlin Beach State Park, 2 on 2022-10-08 09:00\n"
+	if len(sent) != 1 || sent[0].Content != want {
+		t.Fatalf("got %+v, want %q", sent, want)
+	}
+	q := f.lastRequestTo("/data/nearest/geo/recent/snoowl1").URL.Query()
+	if q.Get("back") != "7" || q.Get("lat") != "43.08" || q.Get("dist") != "50" {
+		t.Errorf("unexpected query %v", q)
+	}
+
+	// Banding codes, scientific names and the default location work too
+	for _, msg := range []string{"!nearest snow", "!nearest bubo scandiacus", "!nearest snowy owl days:3"} {
+		sent := send(t, msg)
+		if len(sent) != 1 || !strings.HasPrefix(sent[0].Content, "**Nearest Snowy Owl to Rochester Institute of Technology") {
+			t.Errorf("%s: unexpected reply %+v", msg, sent)
+		}
+	}
+}
+
+func TestNearestErrors(t *testing.T) {
+	f := newFakeEBird(t)
+	f.route("/ref/taxonomy/ebird", "taxonomy.json")
+
+	for msg, want := range map[string]string{
+		"!nearest":                        "Error: use `!nearest",
+		"!nearest dodo":                   "Error: no species found matching 'dodo'",
+		"!nearest american":               "Error: 'american' matches several species: American Crow, American Robin",
+		"!nearest snowy owl from nowhere": "Error: 'nowhere' is not a valid option for !nearest",
+		"!nearest snowy owl 45":           "Error: 'days' must be a whole number from 1 to 30",
+	} {
+		sent := send(t, msg)
+		if len(sent) != 1 || !strings.HasPrefix(sent[0].Content, want) {
+			t.Errorf("%s: got %+v, want prefix %q", msg, sent, want)
+		}
+	}
+}
+
+func TestFindTaxonNormalizesNames(t *testing.T) {
+	f := newFakeEBird(t)
+	f.route("/ref/taxonomy/ebird", "taxonomy.json")
+	allTaxaMu.Lock()
+	allTaxa = make(map[string][]Taxon)
+	allTaxaMu.Unlock()
+
+	for _, name := range []string{"Downy Woodpecker", "downy-woodpecker", "DOWO", "dowwoo"} {
+		taxon, err := findTaxon(context.Background(), name, "en")
+		if err != nil || taxon.SpeciesCode != "dowwoo" {
+			t.Errorf("%s: got %+v, %v", name, taxon, err)
+		}
+	}
+}
+
+func TestTaxonomySnapshot(t *testing.T) {
+	f := newFakeEBird(t)
+	if snapshot, err := loadTaxonomySnapshot(""); snapshot != nil || err != nil {
+		t.Fatalf("got %d taxa, %v without a taxonomy file", len(snapshot), err)
+	}
+
+	snapshot, err := loadTaxonomySnapshot(filepath.Join("testdata", "ebird", "taxonomy.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	useTaxonomySnapshot(snapshot)
+
+	// Species are found by name without asking eBird for the taxonomy
+	taxon, err := findTaxon(context.Background(), "snowy owl", "en")
+	if err != nil || taxon.SpeciesCode != "snoowl1" {
+		t.Errorf("got %+v, %v", taxon, err)
+	}
+	if f.requestCount() != 0 {
+		t.Errorf("sent %d requests to eBird", f.requestCount())
+	}
+
+	if _, err := loadTaxonomySnapshot(filepath.Join("testdata", "ebird", "stats.json")); err == nil {
+		t.Error("loaded stats as a taxonomy")
+	}
+}
+
+func TestLoadAllTaxaConcurrent(t *testing.T) {
+	f := newFakeEBird(t)
+	data, err := os.ReadFile(filepath.Join("testdata", "ebird", "taxonomy.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	started, release := make(chan struct{}, 1), make(chan struct{})
+	f.handle("/ref/taxonomy/ebird", func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case started <- struct{}{}:
+		default:
+		}
+		<-release
+		w.Write(data)
+	})
+
+	// Several commands at once share one download
+	loaded := make(chan int)
+	for i := 0; i < 3; i++ {
+		go func() {
+			all, _ := loadAllTaxa(context.Background(), "en")
+			loaded <- len(all)
+		}()
+	}
+	<-started
+
+	// The download does not hold the lock, so a command that gives up returns right away
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	gaveUp := make(chan error)
+	go func() {
+		_, err := loadAllTaxa(ctx, "en")
+		gaveUp <- err
+	}()
+	select {
+	case err := <-gaveUp:
+		if err == nil {
+			t.Error("cancelled load succeeded")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("cancelled load waited for the download")
+	}
+
+	close(release)
+	for i := 0; i < 3; i++ {
+		if n := <-loaded; n == 0 {
+			t.Error("a load got no taxa")
+		}
+	}
+	if f.requestCount() != 1 {
+		t.Errorf("sent %d requests, want 1", f.requestCount())
+	}
+}
+
+func TestBearingAndDistance(t *testing.T) {
+	if d := haversineKm(0, 0, 0, 1); math.Abs(d-111.19) > 0.01 {
+		t.Errorf("one degree of longitude at the equator = %.2f km", d)
+	}
+	for _, tt := range []struct {
+		lat, long float64
+		want      string
+	}{
+		{1, 0, "N"}, {1, 1, "NE"}, {0, 1, "E"}, {-1, 0, "S"}, {0, -1, "W"}, {1, -1, "NW"},
+	} {
+		if got := compassPoint(bearingDegrees(0, 0, tt.lat, tt.long)); got != tt.want {
+			t.Errorf("bearing to %v,%v = %s, want %s", tt.lat, tt.long, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,328 @@
+// Ratelimit keeps users, channels and FlaminGo as a whole from sending too many commands and upstream requests
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Limits for upstream backoff.
+const (
+	// minBackoff and maxBackoff bound how long every command waits after an upstream answers 429 Too Many Requests
+	// without saying for how long. The wait doubles with every 429 in a row.
+	minBackoff = 30 * time.Second
+	maxBackoff = 10 * time.Minute
+	// maxUpstreamWait is the longest a request waits for its turn before the command gives up.
+	maxUpstreamWait = 30 * time.Second
+	// maxIdleBuckets is how many per-user and per-channel buckets are kept before full ones are forgotten.
+	maxIdleBuckets = 1000
+)
+
+// tokenBucket allows bursts of up to burst events, refilled at rate events per second.
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+	// warned is set once the user has been told about the cooldown, so FlaminGo does not answer every extra command.
+	warned bool
+}
+
+// take removes a token from the bucket at time t. If the bucket is empty, it returns how long until a token is
+// available instead and takes nothing.
+func (b *tokenBucket) take(t time.Time, rate, burst float64) time.Duration {
+	d := b.wait(t, rate, burst)
+	if d == 0 {
+		b.use()
+	}
+	return d
+}
+
+// wait refills the bucket up to time t, and returns how long until a token is available, or 0 if one is now.
+func (b *tokenBucket) wait(t time.Time, rate, burst float64) time.Duration {
+	if b.last.IsZero() {
+		b.tokens = burst
+	} else {
+		b.tokens = math.Min(burst, b.tokens+t.Sub(b.last).Seconds()*rate)
+	}
+	b.last = t
+
+	if b.tokens >= 1 {
+		return 0
+	}
+	return time.Duration((1 - b.tokens) / rate * float64(time.Second))
+}
+
+// use removes a token the last wait found available.
+func (b *tokenBucket) use() {
+	b.tokens--
+	b.warned = false
+}
+
+// warn reports whether the user has not been told about the cooldown yet, and marks them as told.
+func (b *tokenBucket) warn() bool {
+	first := !b.warned
+	b.warned = true
+	return first
+}
+
+// full reports whether the bucket would be back to burst tokens at time t, so forgetting it changes nothing.
+func (b *tokenBucket) full(t time.Time, rate, burst float64) bool {
+	return b.tokens+t.Sub(b.last).Seconds()*rate >= burst
+}
+
+// rateLimiter holds one token bucket per key, such as a user or channel ID.
+type rateLimiter struct {
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+}
+
+// newRateLimiter returns an empty rateLimiter.
+func newRateLimiter() *rateLimiter {
+	return &rateLimiter{buckets: make(map[string]*tokenBucket)}
+}
+
+// check refills key's bucket, which allows perMinute events a minute in bursts of the same size, up to time t. It
+// returns the bucket and how long until it has a token, without taking one. A perMinute of 0 or less turns the limit
+// off, and returns a nil bucket. l.mu must be held.
+func (l *rateLimiter) check(key string, perMinute int, t time.Time) (*tokenBucket, time.Duration) {
+	if perMinute <= 0 {
+		return nil, 0
+	}
+	rate, burst := float64(perMinute)/60, float64(perMinute)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		// Forgetting buckets that have refilled, so the map does not grow with every user ever seen
+		if len(l.buckets) >= maxIdleBuckets {
+			for k, old := range l.buckets {
+				if old.full(t, rate, burst) {
+					delete(l.buckets, k)
+				}
+			}
+		}
+		b = &tokenBucket{}
+		l.buckets[key] = b
+	}
+	return b, b.wait(t, rate, burst)
+}
+
+// reset forgets every bucket.
+func (l *rateLimiter) reset() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.buckets = make(map[string]*tokenBucket)
+}
+
+var (
+	// userLimits and channelLimits limit how many commands each user and each channel can send.
+	userLimits    = newRateLimiter()
+	channelLimits = newRateLimiter()
+)
+
+// allowCommand checks the invocation against the user and channel limits, and only takes a token from either if
+// both allow it.
+// If either is used up it returns the cooldown message to show, which is empty when the user was already told.
+func allowCommand(inv *Invocation) (string, bool) {
+	c := Conf()
+	userLimits.mu.Lock()
+	defer userLimits.mu.Unlock()
+	channelLimits.mu.Lock()
+	defer channelLimits.mu.Unlock()
+	t := now()
+
+	user, wait := userLimits.check(inv.UserID, c.UserRate, t)
+	if wait > 0 {
+		inv.Log.Info("user rate limited", slog.Duration("wait", wait))
+		if !user.warn() {
+			return "", false
+		}
+		return tr(inv.Locale, "Slow down! You can send FlaminGo another command in %s.", waitText(inv.Locale, wait)), false
+	}
+
+	channel, wait := channelLimits.check(inv.ChannelID, c.ChannelRate, t)
+	if wait > 0 {
+		inv.Log.Info("channel rate limited", slog.Duration("wait", wait))
+		if !channel.warn() {
+			return "", false
+		}
+		return tr(inv.Locale, "This channel is sending FlaminGo a lot of commands. Try again in %s.", waitText(inv.Locale, wait)), false
+	}
+
+	// Both limits allow the command, so it uses up a token of each
+	if user != nil {
+		user.use()
+	}
+	if channel != nil {
+		channel.use()
+	}
+	return "", true
+}
+
+// waitText rounds a wait up to whole seconds, or whole minutes for longer waits, for use in messages.
+func waitText(locale string, d time.Duration) string {
+	if d > 2*time.Minute {
+		return pluralizeIn(locale, int(math.Ceil(d.Minutes())), "minute")
+	}
+	return pluralizeIn(locale, int(math.Ceil(d.Seconds())), "second")
+}
+
+// BusyError is returned when an upstream asked FlaminGo to slow down, or FlaminGo's own limit for it is used up.
+type BusyError struct {
+	// Upstream is the name of the busy service, e.g. "eBird".
+	Upstream string
+	// Wait is how long until requests are sent again.
+	Wait time.Duration
+}
+
+func (e *BusyError) Error() string {
+	return fmt.Sprintf("%s is rate limited for another %s", e.Upstream, e.Wait.Round(time.Second))
+}
+
+// upstream limits the requests FlaminGo as a whole sends to a service, and backs off every command when the service
+// answers 429 Too Many Requests.
+type upstream struct {
+	// name is the service's name, shown to users.
+	name string
+	// perSecond returns how many requests a second the service may get. 0 or less turns the limit off.
+	perSecond func() int
+
+	mu     sync.Mutex
+	bucket tokenBucket
+	// until is when requests may be sent again after a 429, and backoff is the last wait, doubled on every 429 in a row.
+	until   time.Time
+	backoff time.Duration
+	// lastStatus and lastAt are the status code and time of the last response, for !admin status.
+	lastStatus int
+	lastAt     time.Time
+}
+
+var (
+	// ebirdUpstream limits requests to the eBird API, shared by every command.
+	ebirdUpstream = &upstream{name: "eBird", perSecond: func() int { return Conf().EBirdRate }}
+	// allAboutBirdsUpstream limits the pages scraped from AllAboutBirds.org for !bird.
+	allAboutBirdsUpstream = &upstream{name: "AllAboutBirds", perSecond: func() int { return 1 }}
+)
+
+// wait blocks until a request may be sent or ctx is done. It returns a BusyError without waiting if the service is
+// backing off, or if the wait would be longer than maxUpstreamWait.
+func (u *upstream) wait(ctx context.Context) error {
+	for {
+		err := ctx.Err()
+		// Error handling
+		if err != nil {
+			return fmt.Errorf("waiting for %s: %w", u.name, err)
+		}
+		d, err := u.reserve()
+		if d == 0 || err != nil {
+			return err
+		}
+
+		timer := time.NewTimer(d)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+		case <-timer.C:
+		}
+	}
+}
+
+// reserve takes a token if one is available, and otherwise returns how long to wait before trying again.
+func (u *upstream) reserve() (time.Duration, error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	t := now()
+	if t.Before(u.until) {
+		return 0, &BusyError{Upstream: u.name, Wait: u.until.Sub(t)}
+	}
+
+	rate := float64(u.perSecond())
+	if rate <= 0 {
+		return 0, nil
+	}
+	// Bursts of up to two seconds' worth of requests are allowed, for commands that send several at once
+	d := u.bucket.take(t, rate, 2*rate)
+	if d > maxUpstreamWait {
+		return 0, &BusyError{Upstream: u.name, Wait: d}
+	}
+	return d, nil
+}
+
+// throttled starts a backoff after the service answered 429. retryAfter is the response's Retry-After header, in
+// seconds, and may be empty. It returns the error for the request that was refused.
+func (u *upstream) throttled(retryAfter string) error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	u.backoff *= 2
+	if u.backoff < minBackoff {
+		u.backoff = minBackoff
+	}
+	if u.backoff > maxBackoff {
+		u.backoff = maxBackoff
+	}
+	wait := u.backoff
+	if secs, err := strconv.Atoi(retryAfter); err == nil && secs > 0 {
+		wait = time.Duration(secs) * time.Second
+	}
+
+	u.until = now().Add(wait)
+	logger.Warn("upstream rate limited FlaminGo, backing off", slog.String("upstream", u.name), slog.Duration("wait", wait))
+	return &BusyError{Upstream: u.name, Wait: wait}
+}
+
+// succeeded ends the run of 429s, so the next backoff starts from minBackoff again.
+func (u *upstream) succeeded() {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.backoff = 0
+}
+
+// checkResponse records a response's status with the upstream, returning a BusyError for 429 Too Many Requests.
+func (u *upstream) checkResponse(status int, header http.Header) error {
+	u.mu.Lock()
+	u.lastStatus, u.lastAt = status, now()
+	u.mu.Unlock()
+
+	if status == http.StatusTooManyRequests {
+		return u.throttled(header.Get("Retry-After"))
+	}
+	u.succeeded()
+	return nil
+}
+
+// health describes the upstream's last response and any backoff, e.g. "200 OK 12s ago".
+func (u *upstream) health() string {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	t := now()
+
+	if u.lastAt.IsZero() {
+		return "no requests yet"
+	}
+	health := fmt.Sprintf("%d %s %s ago", u.lastStatus, http.StatusText(u.lastStatus), t.Sub(u.lastAt).Round(time.Second))
+	if t.Before(u.until) {
+		health += fmt.Sprintf(", backing off for %s", u.until.Sub(t).Round(time.Second))
+	}
+	return health
+}
+
+// resetRateLimits forgets every bucket and backoff, e.g. when tests change the limits.
+func resetRateLimits() {
+	// The limiters are emptied rather than replaced, so commands checking them at the same time do not race
+	userLimits.reset()
+	channelLimits.reset()
+	for _, u := range []*upstream{ebirdUpstream, allAboutBirdsUpstream} {
+		u.mu.Lock()
+		u.bucket = tokenBucket{}
+		u.until = time.Time{}
+		u.backoff = 0
+		u.lastStatus, u.lastAt = 0, time.Time{}
+		u.mu.Unlock()
+	}
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// stepNow makes now return a fixed time for the duration of the test, and returns a function moving it forward.
+func stepNow(t *testing.T) func(d time.Duration) {
+	t.Helper()
+	current := time.Date(2022, time.October, 12, 15, 0, 0, 0, time.Local)
+	old := now
+	now = func() time.Time { return current }
+	t.Cleanup(func() {
+		now = old
+		resetRateLimits()
+	})
+	return func(d time.Duration) { current = current.Add(d) }
+}
+
+// sendFrom is send from the given user in the given channel.
+func sendFrom(t *testing.T, userID, channelID, content string) []sentMessage {
+	t.Helper()
+	m := newMessage(content)
+	m.Author = &discordgo.User{ID: userID}
+	m.ChannelID = channelID
+	s := &fakeSession{}
+	handleMessage(s, m)
+	commandPool.wait()
+	return s.messages()
+}
+
+func TestUserRateLimit(t *testing.T) {
+	newFakeEBird(t)
+	useTempStore(t)
+	advance := stepNow(t)
+	changeConf(t, func(c *Config) { c.UserRate = 2 })
+
+	for i := 0; i < 2; i++ {
+		if sent := send(t, "!flamingo"); len(sent) != 1 || sent[0].Embed == nil {
+			t.Fatalf("command %d: unexpected reply %+v", i+1, sent)
+		}
+	}
+
+	// Two commands a minute refill one token every 30 seconds
+	sent := send(t, "!flamingo")
+	if len(sent) != 1 || sent[0].Content != "Slow down! You can send FlaminGo another command in 30 seconds." {
+		t.Fatalf("unexpected cooldown %+v", sent)
+	}
+	// The cooldown message is only sent once
+	if sent := send(t, "!flamingo"); len(sent) != 0 {
+		t.Errorf("answered during the cooldown: %+v", sent)
+	}
+	// Other users are not affected
+	if sent := sendFrom(t, "u2", "c1", "!flamingo"); len(sent) != 1 || sent[0].Embed == nil {
+		t.Errorf("another user was limited: %+v", sent)
+	}
+
+	advance(30 * time.Second)
+	if sent := send(t, "!flamingo"); len(sent) != 1 || sent[0].Embed == nil {
+		t.Errorf("not allowed after the cooldown: %+v", sent)
+	}
+}
+
+func TestChannelRateLimit(t *testing.T) {
+	newFakeEBird(t)
+	useTempStore(t)
+	stepNow(t)
+	changeConf(t, func(c *Config) { c.ChannelRate = 3 })
+
+	for _, user := range []string{"u1", "u2", "u3"} {
+		if sent := sendFrom(t, user, "c1", "!flamingo"); len(sent) != 1 || sent[0].Embed == nil {
+			t.Fatalf("%s: unexpected reply %+v", user, sent)
+		}
+	}
+	sent := sendFrom(t, "u4", "c1", "!flamingo")
+	if len(sent) != 1 || sent[0].Content != "This channel is sending FlaminGo a lot of commands. Try again in 20 seconds." {
+		t.Fatalf("unexpected cooldown %+v", sent)
+	}
+	if sent := sendFrom(t, "u4", "c2", "!flamingo"); len(sent) != 1 || sent[0].Embed == nil {
+		t.Errorf("another channel was limited: %+v", sent)
+	}
+}
+
+func TestRateLimitsCheckedTogether(t *testing.T) {
+	newFakeEBird(t)
+	useTempStore(t)
+	stepNow(t)
+	changeConf(t, func(c *Config) { c.UserRate, c.ChannelRate = 2, 1 })
+
+	if sent := sendFrom(t, "u1", "c1", "!flamingo"); len(sent) != 1 || sent[0].Embed == nil {
+		t.Fatalf("unexpected reply %+v", sent)
+	}
+	if sent := sendFrom(t, "u1", "c1", "!flamingo"); len(sent) != 1 || !strings.HasPrefix(sent[0].Content, "This channel is sending") {
+		t.Fatalf("unexpected cooldown %+v", sent)
+	}
+
+	// The command the channel refused did not use up the user's second command
+	if sent := sendFrom(t, "u1", "c2", "!flamingo"); len(sent) != 1 || sent[0].Embed == nil {
+		t.Errorf("user limited by a refused command: %+v", sent)
+	}
+	if sent := sendFrom(t, "u1", "c3", "!flamingo"); len(sent) != 1 || !strings.HasPrefix(sent[0].Content, "Slow down!") {
+		t.Errorf("unexpected reply %+v", sent)
+	}
+}
+
+func TestResetRateLimitsDuringCommands(t *testing.T) {
+	newFakeEBird(t)
+	useTempStore(t)
+	changeConf(t, func(c *Config) { c.UserRate, c.ChannelRate = 1000, 1000 })
+
+	// Run with -race, commands checking the limits while they are reset show any data race
+	stop, done := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				resetRateLimits()
+			}
+		}
+	}()
+	for i := 0; i < 20; i++ {
+		if sent := send(t, "!flamingo"); len(sent) != 1 || sent[0].Embed == nil {
+			t.Errorf("command %d: unexpected reply %+v", i+1, sent)
+		}
+	}
+	close(stop)
+	<-done
+}
+
+func TestUpstreamBackoff(t *testing.T) {
+	f := newFakeEBird(t)
+	useTempStore(t)
+	advance := stepNow(t)
+	f.handle("/data/obs/geo/recent", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "90")
+		http.Error(w, "slow down", http.StatusTooManyRequests)
+	})
+	f.route("/data/obs/geo/recent/notable", "notable.json")
+
+	sent := send(t, "!get rit")
+	if len(sent) != 1 || sent[0].Content != "eBird is getting too many requests from FlaminGo right now. Try again in 90 seconds." {
+		t.Fatalf("unexpected reply %+v", sent)
+	}
+
+	// Every command waits out the backoff without asking eBird again
+	requests := f.requestCount()
+	advance(60 * time.Second)
+	sent = send(t, "!rare rit")
+	if len(sent) != 1 || !strings.Contains(sent[0].Content, "Try again in 30 seconds") {
+		t.Errorf("unexpected reply %+v", sent)
+	}
+	if f.requestCount() != requests {
+		t.Errorf("eBird was asked again during the backoff")
+	}
+
+	advance(30 * time.Second)
+	sent = send(t, "!rare rit")
+	if len(sent) != 1 || !strings.HasPrefix(sent[0].Content, "**Notable eBird sightings") {
+		t.Errorf("unexpected reply after the backoff %+v", sent)
+	}
+}
+
+func TestUpstreamBackoffDoubles(t *testing.T) {
+	stepNow(t)
+	u := &upstream{name: "test", perSecond: func() int { return 0 }}
+
+	for _, want := range []time.Duration{minBackoff, 2 * minBackoff, 4 * minBackoff} {
+		err := u.checkResponse(http.StatusTooManyRequests, http.Header{})
+		busyErr, ok := err.(*BusyError)
+		if !ok || busyErr.Wait != want {
+			t.Errorf("got %v, want a wait of %s", err, want)
+		}
+	}
+
+	// A successful response starts over
+	if err := u.checkResponse(http.StatusOK, http.Header{}); err != nil {
+		t.Fatal(err)
+	}
+	err := u.checkResponse(http.StatusTooManyRequests, http.Header{})
+	if busyErr, ok := err.(*BusyError); !ok || busyErr.Wait != minBackoff {
+		t.Errorf("got %v after a success, want %s", err, minBackoff)
+	}
+}
+
+func TestUpstreamRate(t *testing.T) {
+	advance := stepNow(t)
+	u := &upstream{name: "test", perSecond: func() int { return 2 }}
+
+	// Bursts of two seconds' worth are allowed, then requests wait their turn
+	for i := 0; i < 4; i++ {
+		if d, err := u.reserve(); d != 0 || err != nil {
+			t.Fatalf("request %d: wait %s, %v", i+1, d, err)
+		}
+	}
+	if d, err := u.reserve(); d != 500*time.Millisecond || err != nil {
+		t.Errorf("got wait %s, %v, want 500ms", d, err)
+	}
+	advance(500 * time.Millisecond)
+	if d, err := u.reserve(); d != 0 || err != nil {
+		t.Errorf("got wait %s, %v after waiting", d, err)
+	}
+}
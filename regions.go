@@ -0,0 +1,227 @@
+// Regions contains eBird region lookups and the !region command for discovering region codes
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Region is an entry from eBird's sub-region list endpoint.
+type Region struct {
+	Code string
+	Name string
+}
+
+// regionInfo holds the response of eBird's region info endpoint.
+type regionInfo struct {
+	Result string
+	Bounds struct {
+		MinX float64
+		MaxX float64
+		MinY float64
+		MaxY float64
+	}
+}
+
+// regionCodePattern matches eBird country, subnational1 and subnational2 codes, e.g. US, US-NY and US-NY-055.
+var regionCodePattern = regexp.MustCompile(`^[A-Z]{2}(-[A-Z0-9]{1,3}(-[A-Z0-9]{1,3})?)?$`)
+
+var (
+	// regionNamesMu guards regionNames.
+	regionNamesMu sync.Mutex
+	// regionNames caches the full names of regions by code, since they never change.
+	regionNames = make(map[string]string)
+)
+
+// regionUsage explains the !region subcommands.
+const regionUsage = "use `!region search <name> [in:<region code>]` or `!region info <region code>`"
+
+// maxRegionResults is the most matches !region search shows.
+const maxRegionResults = 10
+
+// regionConcurrency is the most region info requests !region search sends to eBird at once.
+const regionConcurrency = 4
+
+// parseRegionCode uppercases a region code typed by a user and checks that it looks like an eBird region code.
+func parseRegionCode(code string) (string, error) {
+	code = strings.ToUpper(code)
+	if !regionCodePattern.MatchString(code) {
+		return "", usageErrorf("'%s' is not an eBird region code like US, US-NY or US-NY-055, find one with `!region search`", code)
+	}
+	return code, nil
+}
+
+// regionType returns the eBird region type of a valid region code.
+func regionType(code string) string {
+	switch strings.Count(code, "-") {
+	case 0:
+		return "country"
+	case 1:
+		return "subnational1"
+	}
+	return "subnational2"
+}
+
+// GetRegionName returns the full name of a region, e.g. "Monroe, New York, United States" for US-NY-055.
+func GetRegionName(ctx context.Context, code string) (string, error) {
+	regionNamesMu.Lock()
+	name, ok := regionNames[code]
+	regionNamesMu.Unlock()
+	if ok {
+		return name, nil
+	}
+
+	var info regionInfo
+	err := ebirdGet(ctx, fmt.Sprintf("%s/ref/region/info/%s?regionNameFormat=full", Conf().EBirdURL, url.PathEscape(code)), &info)
+	// Error handling
+	if err != nil {
+		return "", fmt.Errorf("getting region info for %s: %w", code, err)
+	}
+
+	regionNamesMu.Lock()
+	regionNames[code] = info.Result
+	regionNamesMu.Unlock()
+	return info.Result, nil
+}
+
+// regionNameOrCode returns the full name of a region, or its code if the name cannot be looked up.
+// Observation headers use it so a failed name lookup does not fail the whole command.
+func regionNameOrCode(ctx context.Context, code string) string {
+	name, err := GetRegionName(ctx, code)
+	if err != nil {
+		logger.Debug("falling back to region code", slog.String("code", code), slog.Any("err", err))
+		return code
+	}
+	return name
+}
+
+// GetSubRegions returns the regions of the given type ("country", "subnational1" or "subnational2") within parent.
+// Countries are listed with the parent "world".
+func GetSubRegions(ctx context.Context, kind, parent string) ([]Region, error) {
+	var r []Region
+	err := ebirdGet(ctx, fmt.Sprintf("%s/ref/region/list/%s/%s?fmt=json", Conf().EBirdURL, kind, url.PathEscape(parent)), &r)
+	// Error handling
+	if err != nil {
+		return nil, fmt.Errorf("listing %s regions in %s: %w", kind, parent, err)
+	}
+	return r, nil
+}
+
+// SearchRegions returns the regions whose name contains query. Without a parent, countries are searched.
+// Within a country, its subnational1 and subnational2 regions are searched, and within a subnational1 region,
+// its subnational2 regions.
+func SearchRegions(ctx context.Context, query, parent string) ([]Region, error) {
+	var kinds []string
+	switch {
+	case parent == "":
+		parent = "world"
+		kinds = []string{"country"}
+	case regionType(parent) == "country":
+		kinds = []string{"subnational1", "subnational2"}
+	case regionType(parent) == "subnational1":
+		kinds = []string{"subnational2"}
+	default:
+		return nil, usageErrorf("'%s' has no smaller regions to search", parent)
+	}
+
+	query = strings.ToLower(query)
+	var matches []Region
+	for _, kind := range kinds {
+		r, err := GetSubRegions(ctx, kind, parent)
+		// Error handling
+		if err != nil {
+			return nil, err
+		}
+		for _, region := range r {
+			if strings.Contains(strings.ToLower(region.Name), query) {
+				matches = append(matches, region)
+			}
+		}
+	}
+	return matches, nil
+}
+
+// runRegion handles the !region search and !region info subcommands.
+func runRegion(s Sender, inv *Invocation) error {
+	ctx := inv.Context()
+	positional, options := splitOptions(inv.Args)
+	if len(positional) < 2 {
+		return usageErrorf("%s", regionUsage)
+	}
+
+	switch positional[0] {
+	case "search":
+		parent := ""
+		if v, ok := options["in"]; ok {
+			var err error
+			parent, err = parseRegionCode(v)
+			// Error handling
+			if err != nil {
+				return err
+			}
+		}
+		query := strings.Join(positional[1:], " ")
+
+		matches, err := SearchRegions(ctx, query, parent)
+		// Error handling
+		if err != nil {
+			return err
+		}
+		inv.send(s, formatRegionMatches(ctx, query, matches, inv.Locale))
+	case "info":
+		code, err := parseRegionCode(positional[1])
+		// Error handling
+		if err != nil {
+			return err
+		}
+		name, err := GetRegionName(ctx, code)
+		// Error handling
+		if err != nil {
+			return err
+		}
+		inv.send(s, tr(inv.Locale, "`%s` is **%s** (%s). Use it with `!get region:%s`.", code, name, regionType(code), code))
+	default:
+		return usageErrorf("'%s' is not a valid option for !region, %s", positional[0], regionUsage)
+	}
+	return nil
+}
+
+// formatRegionMatches lists region search results with their full names, in locale.
+func formatRegionMatches(ctx context.Context, query string, matches []Region, locale string) string {
+	if len(matches) == 0 {
+		return tr(locale, "**No eBird regions found matching '%s'.** Try searching inside a country or state with `in:`, e.g. `in:US` or `in:US-NY`.", query)
+	}
+
+	shown := matches
+	if len(shown) > maxRegionResults {
+		shown = shown[:maxRegionResults]
+	}
+
+	// The full name tells apart regions with the same name, e.g. the many Monroe counties
+	names := make([]string, len(shown))
+	// Every lookup returns nil, so runLimited cannot fail here
+	_ = runLimited(len(shown), regionConcurrency, func(i int) error {
+		name, err := GetRegionName(ctx, shown[i].Code)
+		if err != nil {
+			logger.Debug("falling back to short region name", slog.String("code", shown[i].Code), slog.Any("err", err))
+			name = shown[i].Name
+		}
+		names[i] = name
+		return nil
+	})
+
+	rString := tr(locale, "**eBird regions matching '%s':**\n", query)
+	for i, r := range shown {
+		rString += fmt.Sprintf("`%s`: %s\n", r.Code, names[i])
+	}
+	if len(matches) > maxRegionResults {
+		rString += tr(locale, "...and %d more, try a longer name\n", len(matches)-maxRegionResults)
+	}
+	return truncateText(rString, 1995)
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRegionQueries(t *testing.T) {
+	f := newFakeEBird(t)
+	f.route("/data/obs/US-NY-055/recent", "recent.json")
+	f.route("/data/obs/US-NY/recent/notable", "notable.json")
+	f.route("/ref/region/info/US-NY-055", "region_info_monroe.json")
+
+	sent := send(t, "!get region:us-ny-055 days:5")
+	if len(sent) != 1 || !strings.HasPrefix(sent[0].Content, "**Verified eBird sightings in Monroe, New York, United States in the past 5 days:**\nAmerican Crow: 40\n") {
+		t.Fatalf("unexpected reply %+v", sent)
+	}
+
+	// A failed name lookup falls back to the code
+	sent = send(t, "!rare region:US-NY")
+	if len(sent) != 1 || !strings.HasPrefix(sent[0].Content, "**Notable eBird sightings in US-NY in the past 14 days:**") {
+		t.Fatalf("unexpected reply %+v", sent)
+	}
+
+	for msg, want := range map[string]string{
+		"!get region:newyork":           "Error: 'NEWYORK' is not an eBird region code",
+		"!get region:US-NY radius:10":   "Error: 'radius' cannot be used with 'region'",
+		"!rare region:US-NY mode:geo":   "Error: 'mode' cannot be used with 'region'",
+		"!get region:US-NY-055 reverse": "Error: 'reverse' is not a valid option for !get",
+	} {
+		sent := send(t, msg)
+		if len(sent) != 1 || !strings.HasPrefix(sent[0].Content, want) {
+			t.Errorf("%s: got %+v, want prefix %q", msg, sent, want)
+		}
+	}
+}
+
+func TestRegionSearch(t *testing.T) {
+	f := newFakeEBird(t)
+	f.route("/ref/region/list/subnational2/US-NY", "regions_us_ny.json")
+	f.route("/ref/region/info/US-NY-055", "region_info_monroe.json")
+	f.route("/ref/region/info/US-NY", "region_info_ny.json")
+
+	sent := send(t, "!region search mon in:us-ny")
+	want := "**eBird regions matching 'mon':**\n`US-NY-055`: Monroe, New York, United States\n`US-NY-057`: Montgomery\n"
+	if len(sent) != 1 || sent[0].Content != want {
+		t.Errorf("got %+v, want %q", sent, want)
+	}
+
+	sent = send(t, "!region info us-ny")
+	if len(sent) != 1 || sent[0].Content != "`US-NY` is **New York, United States** (subnational1). Use it with `!get region:US-NY`." {
+		t.Errorf("unexpected reply %+v", sent)
+	}
+
+	sent = send(t, "!region search zzz in:us-ny")
+	if len(sent) != 1 || !strings.HasPrefix(sent[0].Content, "**No eBird regions found matching 'zzz'.**") {
+		t.Errorf("unexpected reply %+v", sent)
+	}
+
+	sent = send(t, "!region search x in:us-ny-055")
+	if len(sent) != 1 || sent[0].Content != "Error: 'US-NY-055' has no smaller regions to search" {
+		t.Errorf("unexpected reply %+v", sent)
+	}
+}
@@ -0,0 +1,346 @@
+// Season contains the !season command, which charts how often a species is reported at a place through the year
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Settings for !season.
+const (
+	// seasonWeeks is the number of weeks charted.
+	seasonWeeks = 52
+	// defaultSeasonYears and maxSeasonYears limit how many past years are sampled.
+	defaultSeasonYears = 3
+	maxSeasonYears     = 5
+	// seasonConcurrency is the most historic requests sent to eBird at once.
+	seasonConcurrency = 4
+	// maxSeasonFetches is the most days one !season fetches from eBird, a year's worth. Fetched days are cached, so
+	// running it again fills in the rest.
+	maxSeasonFetches = seasonWeeks
+)
+
+// seasonUsage explains the !season command.
+const seasonUsage = "use `!season <species> <location>` or `!season <species> region:<code>`, optionally with `years:1-5`"
+
+// Season holds a species' weekly frequency at a place.
+type Season struct {
+	Taxon Taxon
+	// Name is the place's name, for printing.
+	Name string
+	// FirstYear and LastYear are the years sampled.
+	FirstYear int
+	LastYear  int
+	// Frequency is the share of sampled days in each week the species was reported on, from 0 to 1.
+	// Weeks without any sightings of any species are -1.
+	Frequency [seasonWeeks]float64
+	// Missing is how many sampled days were left out, since only so many are fetched from eBird at once.
+	Missing int
+}
+
+// runSeason handles !season <species> <location>.
+func runSeason(s Sender, inv *Invocation) error {
+	ctx := inv.Context()
+	positional, options := splitOptions(inv.Args)
+
+	// The place is region: or the last word, and the rest is the species
+	var code, name string
+	if _, ok := options["region"]; ok {
+		var err error
+		code, name, _, err = codeArg(ctx, inv.GuildID, "season", nil, options)
+		// Error handling
+		if err != nil {
+			return err
+		}
+	} else {
+		if len(positional) < 2 {
+			return usageErrorf("%s", seasonUsage)
+		}
+		var err error
+		code, name, _, err = codeArg(ctx, inv.GuildID, "season", positional[len(positional)-1:], options)
+		// Error handling
+		if err != nil {
+			return err
+		}
+		positional = positional[:len(positional)-1]
+	}
+	if len(positional) == 0 {
+		return usageErrorf("%s", seasonUsage)
+	}
+	years, err := intOption(options, "years", 1, maxSeasonYears, defaultSeasonYears)
+	// Error handling
+	if err != nil {
+		return err
+	}
+
+	taxon, err := findTaxon(ctx, strings.Join(positional, " "), inv.Locale)
+	// Error handling
+	if err != nil {
+		return err
+	}
+
+	season, err := GetSeason(ctx, taxon, code, name, years)
+	// Error handling
+	if err != nil {
+		return err
+	}
+	png, err := RenderSeason(season)
+	// Error handling
+	if err != nil {
+		return err
+	}
+	inv.sendFile(s, FormatSeason(season, inv.Locale), "season-"+taxon.SpeciesCode+".png", "image/png", png)
+	return nil
+}
+
+// GetSeason samples one day in each week of the past years at an eBird location or region,
+// and works out how often the species was reported each week. Days that are not cached yet are fetched from eBird,
+// newest year first and at most seasonFetchBudget of them, and the rest are counted in Season.Missing.
+func GetSeason(ctx context.Context, taxon Taxon, code, name string, years int) (Season, error) {
+	season := Season{Taxon: taxon, Name: name, LastYear: today().Year() - 1}
+	season.FirstYear = season.LastYear - years + 1
+
+	// The middle day of every week in every sampled year, newest year first so a partial chart shows the latest
+	var dates []time.Time
+	for y := season.LastYear; y >= season.FirstYear; y-- {
+		for w := 0; w < seasonWeeks; w++ {
+			dates = append(dates, time.Date(y, time.January, 1+w*7+3, 0, 0, 0, 0, time.Local))
+		}
+	}
+
+	species := make([][]string, len(dates))
+	var fetch []int
+	for i, d := range dates {
+		if list, ok := cachedHistoricSpecies(code, d); ok {
+			species[i] = list
+		} else {
+			fetch = append(fetch, i)
+		}
+	}
+	if budget := seasonFetchBudget(); len(fetch) > budget {
+		season.Missing = len(fetch) - budget
+		fetch = fetch[:budget]
+	}
+	err := runLimited(len(fetch), seasonConcurrency, func(n int) error {
+		var err error
+		species[fetch[n]], err = historicSpecies(ctx, code, dates[fetch[n]])
+		return err
+	})
+	// Error handling
+	if err != nil {
+		return season, err
+	}
+
+	// Counting the days the species was reported, out of the days anything was reported
+	var reported, sampled [seasonWeeks]int
+	for i, list := range species {
+		w := i % seasonWeeks
+		if len(list) == 0 {
+			continue
+		}
+		sampled[w]++
+		for _, sp := range list {
+			if sp == taxon.SpeciesCode {
+				reported[w]++
+				break
+			}
+		}
+	}
+	for w := range season.Frequency {
+		if sampled[w] == 0 {
+			season.Frequency[w] = -1
+			continue
+		}
+		season.Frequency[w] = float64(reported[w]) / float64(sampled[w])
+	}
+	return season, nil
+}
+
+// seasonFetchBudget returns how many days one !season may fetch from eBird: at most maxSeasonFetches, and no more
+// than eBird's rate limit lets through in half the command timeout, leaving the rest for the other requests.
+func seasonFetchBudget() int {
+	c := Conf()
+	budget := maxSeasonFetches
+	if c.EBirdRate > 0 {
+		// The rate limit lets a burst of two seconds' worth of requests through at once
+		if n := c.EBirdRate*2 + c.EBirdRate*c.CommandTimeout/2; n < budget {
+			budget = n
+		}
+	}
+	return budget
+}
+
+// historicCachePath returns the file the species reported at an eBird location or region on a date are cached in.
+func historicCachePath(code string, date time.Time) string {
+	return filepath.Join(Conf().DataDir, "historic", code, date.Format(dateLayout)+".json")
+}
+
+// cachedHistoricSpecies returns the cached codes of the species reported at an eBird location or region on a date,
+// and whether they were cached.
+func cachedHistoricSpecies(code string, date time.Time) ([]string, bool) {
+	path := historicCachePath(code, date)
+	var species []string
+	data, err := os.ReadFile(path)
+	if err == nil {
+		err = json.Unmarshal(data, &species)
+		if err == nil {
+			return species, true
+		}
+		logger.Warn("ignoring unreadable historic cache file", slog.String("file", path), slog.Any("err", err))
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		logger.Warn("reading historic cache file", slog.String("file", path), slog.Any("err", err))
+	}
+	return nil, false
+}
+
+// historicSpecies returns the codes of the species reported at an eBird location or region on a date.
+// Past dates do not change much, so the list is cached in the data directory and eBird is only asked once per date.
+func historicSpecies(ctx context.Context, code string, date time.Time) ([]string, error) {
+	if species, ok := cachedHistoricSpecies(code, date); ok {
+		return species, nil
+	}
+
+	var b []BirdSighting
+	url := fmt.Sprintf("%s/data/obs/%s/historic/%d/%d/%d?rank=mrec&detail=simple", Conf().EBirdURL, code, date.Year(), date.Month(), date.Day())
+	err := ebirdGet(ctx, url, &b)
+	// Error handling
+	if err != nil {
+		return nil, fmt.Errorf("getting historic observations for %s on %s: %w", code, date.Format(dateLayout), err)
+	}
+	species := make([]string, 0, len(b))
+	for _, s := range b {
+		species = append(species, s.SpeciesCode)
+	}
+
+	// Today's list can still grow, so only finished days are cached
+	if date.Before(today()) {
+		path := historicCachePath(code, date)
+		err = writeFileAtomic(path, species)
+		if err != nil {
+			logger.Warn("caching historic observations", slog.String("file", path), slog.Any("err", err))
+		}
+	}
+	return species, nil
+}
+
+// writeFileAtomic writes v as JSON to path, creating its directory, through a temporary file that is renamed into place.
+func writeFileAtomic(path string, v interface{}) error {
+	data, err := json.Marshal(v)
+	// Error handling
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(path), 0o755)
+	// Error handling
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	// Error handling
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	// Error handling
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// FormatSeason returns the message sent with the chart in locale, naming the best weeks to look for the species.
+func FormatSeason(season Season, locale string) string {
+	years := fmt.Sprintf("%d-%d", season.FirstYear, season.LastYear)
+	if season.FirstYear == season.LastYear {
+		years = fmt.Sprint(season.FirstYear)
+	}
+	rString := tr(locale, "**Weekly frequency of %s at %s** (share of sampled days it was reported, %s)\n", season.Taxon.ComName, season.Name, years)
+
+	var weeks []int
+	for w, f := range season.Frequency {
+		if f > 0 {
+			weeks = append(weeks, w)
+		}
+	}
+	missing := ""
+	if season.Missing > 0 {
+		missing = "\n" + tr(locale, "%d sampled days are not loaded yet, run the same !season again to fill them in.", season.Missing)
+	}
+	if len(weeks) == 0 {
+		return rString + tr(locale, "It was not reported on any sampled day.") + missing
+	}
+	sort.SliceStable(weeks, func(i, j int) bool {
+		return season.Frequency[weeks[i]] > season.Frequency[weeks[j]]
+	})
+	if len(weeks) > 3 {
+		weeks = weeks[:3]
+	}
+
+	var best []string
+	for _, w := range weeks {
+		start := time.Date(season.LastYear, time.January, 1+w*7, 0, 0, 0, 0, time.Local)
+		best = append(best, tr(locale, "week of %s (%.0f%%)", monthDay(start, locale), season.Frequency[w]*100))
+	}
+	return rString + tr(locale, "Best time: %s", strings.Join(best, ", ")) + missing
+}
+
+// RenderSeason draws the weekly frequency as a PNG bar chart with month labels and gridlines every 25%.
+func RenderSeason(season Season) ([]byte, error) {
+	const (
+		left, right, top, bottom = 44, 10, 16, 24
+		barWidth, barGap         = 10, 2
+		plotHeight               = 200
+		scale                    = 2
+	)
+	width := left + seasonWeeks*(barWidth+barGap) + right
+	height := top + plotHeight + bottom
+	img := newCanvas(width, height)
+	baseline := top + plotHeight
+
+	// Gridlines and their labels
+	for pct := 25; pct <= 100; pct += 25 {
+		y := baseline - plotHeight*pct/100
+		fillRect(img, image.Rect(left, y, width-right, y+1), colorGrid)
+		label := fmt.Sprintf("%d%%", pct)
+		drawText(img, left-6-textWidth(label, scale), y-5*scale/2, label, scale, colorAxis)
+	}
+
+	// Bars, with weeks that had no data marked along the axis
+	for w, f := range season.Frequency {
+		x := left + w*(barWidth+barGap) + barGap/2
+		if f < 0 {
+			fillRect(img, image.Rect(x, baseline-2, x+barWidth, baseline), colorNoData)
+			continue
+		}
+		h := int(f*plotHeight + 0.5)
+		fillRect(img, image.Rect(x, baseline-h, x+barWidth, baseline), colorPink)
+	}
+
+	// Axis and month initials, placed at the week each month starts in
+	fillRect(img, image.Rect(left, baseline, width-right, baseline+1), colorAxis)
+	fillRect(img, image.Rect(left-1, top, left, baseline+1), colorAxis)
+	for m := time.January; m <= time.December; m++ {
+		w := time.Date(season.LastYear, m, 1, 0, 0, 0, 0, time.Local).YearDay() / 7
+		x := left + w*(barWidth+barGap)
+		fillRect(img, image.Rect(x, baseline, x+1, baseline+4), colorAxis)
+		drawText(img, x+2, baseline+7, m.String()[:1], scale, colorAxis)
+	}
+
+	return encodePNG(img)
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image/png"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSeason(t *testing.T) {
+	f := newFakeEBird(t)
+	fixNow(t, "2022-10-12")
+	f.route("/ref/taxonomy/ebird", "taxonomy.json")
+
+	// Snowy Owls are reported in the first ten weeks of 2021, Blue Jays all year except for two weeks with no reports at all
+	f.handlePrefix("/data/obs/L139800/historic/2021/", func(w http.ResponseWriter, r *http.Request) {
+		parts := strings.Split(r.URL.Path, "/")
+		month, _ := strconv.Atoi(parts[6])
+		day, _ := strconv.Atoi(parts[7])
+		week := (time.Date(2021, time.Month(month), day, 0, 0, 0, 0, time.Local).YearDay() - 1) / 7
+		switch {
+		case week < 10:
+			fmt.Fprint(w, `[{"speciesCode": "snoowl1", "comName": "Snowy Owl", "howMany": 1}, {"speciesCode": "blujay", "comName": "Blue Jay", "howMany": 2}]`)
+		case week == 30 || week == 31:
+			fmt.Fprint(w, `[]`)
+		default:
+			fmt.Fprint(w, `[{"speciesCode": "blujay", "comName": "Blue Jay", "howMany": 2}]`)
+		}
+	})
+
+	sent := send(t, "!season snowy owl mendon years:1")
+	if len(sent) != 1 {
+		t.Fatalf("sent %d messages, want 1", len(sent))
+	}
+	want := "**Weekly frequency of Snowy Owl at Mendon Ponds Park** (share of sampled days it was reported, 2021)\n" +
+		"Best time: week of January 1 (100%), week of January 8 (100%), week of January 15 (100%)"
+	if sent[0].Content != want {
+		t.Errorf("content = %q, want %q", sent[0].Content, want)
+	}
+	img, err := png.Decode(bytes.NewReader(sent[0].Files["season-snoowl1.png"]))
+	if err != nil {
+		t.Fatalf("attachment is not a PNG: %v", err)
+	}
+	if b := img.Bounds(); b.Dx() != 44+52*12+10 || b.Dy() != 240 {
+		t.Errorf("unexpected image size %v", b)
+	}
+
+	// The second chart for the same place only needs the cached days
+	before := f.requestCount()
+	sent = send(t, "!season blue jay mendon years:1")
+	if f.requestCount() != before {
+		t.Errorf("sent %d more requests, want all days cached", f.requestCount()-before)
+	}
+	if len(sent) != 1 || !strings.Contains(sent[0].Content, "Best time: week of January 1 (100%)") {
+		t.Errorf("unexpected reply %+v", sent)
+	}
+}
+
+func TestGetSeasonFrequency(t *testing.T) {
+	f := newFakeEBird(t)
+	fixNow(t, "2022-10-12")
+	f.handlePrefix("/data/obs/US-NY/historic/", func(w http.ResponseWriter, r *http.Request) {
+		// The owl is reported in 2021 only, so each week is 50%
+		if strings.Contains(r.URL.Path, "/2021/") {
+			fmt.Fprint(w, `[{"speciesCode": "snoowl1"}]`)
+			return
+		}
+		fmt.Fprint(w, `[{"speciesCode": "blujay"}]`)
+	})
+
+	// Only a year of days is fetched at once, starting with the latest
+	owl := Taxon{SpeciesCode: "snoowl1", ComName: "Snowy Owl"}
+	season, err := GetSeason(context.Background(), owl, "US-NY", "New York", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if season.FirstYear != 2020 || season.LastYear != 2021 || season.Missing != seasonWeeks || season.Frequency[0] != 1 {
+		t.Errorf("got %d-%d with %d days missing and %v in week 1, want 2021 loaded first", season.FirstYear, season.LastYear,
+			season.Missing, season.Frequency[0])
+	}
+
+	season, err = GetSeason(context.Background(), owl, "US-NY", "New York", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if season.Missing != 0 {
+		t.Errorf("%d days still missing", season.Missing)
+	}
+	for w, freq := range season.Frequency {
+		if freq != 0.5 {
+			t.Fatalf("week %d frequency = %v, want 0.5", w, freq)
+		}
+	}
+}
+
+func TestSeasonColdCache(t *testing.T) {
+	f := newFakeEBird(t)
+	fixNow(t, "2022-10-12")
+	f.route("/ref/taxonomy/ebird", "taxonomy.json")
+	f.handlePrefix("/data/obs/L139800/historic/", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[{"speciesCode": "snoowl1"}]`)
+	})
+
+	sent := send(t, "!season snowy owl mendon years:5")
+	if len(sent) != 1 || !strings.Contains(sent[0].Content, "208 sampled days are not loaded yet") {
+		t.Fatalf("unexpected reply %+v", sent)
+	}
+
+	// At the default eBird rate, every request the command sent must fit within the default timeout
+	changeConf(t, func(c *Config) { *c = defaultConfig() })
+	rate := float64(Conf().EBirdRate)
+	var b tokenBucket
+	start := now()
+	at := start
+	for i := 0; i < f.requestCount(); i++ {
+		for d := b.take(at, rate, 2*rate); d > 0; d = b.take(at, rate, 2*rate) {
+			at = at.Add(d)
+		}
+	}
+	if took, timeout := at.Sub(start), time.Duration(Conf().CommandTimeout)*time.Second; took >= timeout {
+		t.Errorf("%d requests take %s at %v a second, longer than the %s timeout", f.requestCount(), took, rate, timeout)
+	}
+}
+
+func TestSeasonErrors(t *testing.T) {
+	f := newFakeEBird(t)
+	f.route("/ref/taxonomy/ebird", "taxonomy.json")
+
+	for msg, want := range map[string]string{
+		"!season":                       "Error: use `!season",
+		"!season mendon":                "Error: use `!season",
+		"!season snowy owl nowhere":     "Error: 'nowhere' is not a valid option for !season",
+		"!season dodo mendon":           "Error: no species found matching 'dodo'",
+		"!season snowy owl rit years:9": "Error: 'years' must be a whole number from 1 to 5",
+	} {
+		sent := send(t, msg)
+		if len(sent) != 1 || !strings.HasPrefix(sent[0].Content, want) {
+			t.Errorf("%s: got %+v, want prefix %q", msg, sent, want)
+		}
+	}
+}
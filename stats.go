@@ -0,0 +1,287 @@
+// Stats contains the !top100 and !stats commands backed by eBird's product endpoints
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// TopBirder is an entry in eBird's top 100 list.
+type TopBirder struct {
+	UserDisplayName       string
+	NumSpecies            int
+	NumCompleteChecklists int
+	RowNum                int
+}
+
+// DayStats holds eBird's checklist and contributor totals for a region on one day.
+type DayStats struct {
+	Date            time.Time `json:"-"`
+	NumChecklists   int
+	NumContributors int
+	NumSpecies      int
+}
+
+// dateLayout is the date format users type, e.g. 2022-10-10.
+const dateLayout = "2006-01-02"
+
+// Limits for !stats date ranges.
+const (
+	// maxRecapDays is the longest range a recap covers, a little over a month.
+	maxRecapDays = 31
+	// recapConcurrency is the most daily stats requests a recap sends to eBird at once.
+	recapConcurrency = 4
+)
+
+// now returns the current time. Tests replace it to get fixed dates.
+var now = time.Now
+
+// statsUsage explains the !top100 and !stats commands.
+const (
+	top100Usage = "use `!top100 <region code> [YYYY-MM-DD] [by:species or by:checklists]`"
+	statsUsage  = "use `!stats <region code> [YYYY-MM-DD]`, `!stats <region code> <YYYY-MM-DD>..<YYYY-MM-DD>` or `!stats <region code> month:YYYY-MM`"
+)
+
+// runTop100 handles !top100 <region> [date].
+func runTop100(s Sender, inv *Invocation) error {
+	ctx := inv.Context()
+	positional, options := splitOptions(inv.Args)
+	region, positional, err := regionArg(positional, options, top100Usage)
+	// Error handling
+	if err != nil {
+		return err
+	}
+
+	date := today()
+	if len(positional) > 1 {
+		return usageErrorf("%s", top100Usage)
+	}
+	if len(positional) == 1 {
+		date, err = parseDate(positional[0])
+		// Error handling
+		if err != nil {
+			return err
+		}
+	}
+
+	// eBird ranks by species unless asked for checklists
+	rankedBy := "spp"
+	switch options["by"] {
+	case "", "species":
+	case "checklists":
+		rankedBy = "cl"
+	default:
+		return usageErrorf("'by' must be 'species' or 'checklists', got '%s'", options["by"])
+	}
+
+	top, err := GetTop100(ctx, region, date, rankedBy)
+	// Error handling
+	if err != nil {
+		return err
+	}
+	inv.send(s, FormatTop100(regionNameOrCode(ctx, region), date, rankedBy, top, inv.Locale))
+	return nil
+}
+
+// runStats handles !stats <region> [date or range].
+func runStats(s Sender, inv *Invocation) error {
+	ctx := inv.Context()
+	positional, options := splitOptions(inv.Args)
+	region, positional, err := regionArg(positional, options, statsUsage)
+	// Error handling
+	if err != nil {
+		return err
+	}
+
+	// Working out the date range, which is a single day unless a range or month is given
+	from, to := today(), today()
+	switch {
+	case options["month"] != "":
+		month, err := time.ParseInLocation("2006-01", options["month"], time.Local)
+		// Error handling
+		if err != nil {
+			return usageErrorf("'%s' is not a month like 2022-10", options["month"])
+		}
+		from, to = month, month.AddDate(0, 1, -1)
+		// The current month stops at today
+		if to.After(today()) {
+			to = today()
+		}
+	case len(positional) == 1:
+		first, last, isRange := strings.Cut(positional[0], "..")
+		from, err = parseDate(first)
+		// Error handling
+		if err != nil {
+			return err
+		}
+		to = from
+		if isRange {
+			to, err = parseDate(last)
+			// Error handling
+			if err != nil {
+				return err
+			}
+		}
+	case len(positional) > 1:
+		return usageErrorf("%s", statsUsage)
+	}
+	if to.Before(from) {
+		return usageErrorf("the range must not end before it starts")
+	}
+	if days := int(to.Sub(from).Hours()/24+0.5) + 1; days > maxRecapDays {
+		return usageErrorf("ranges can cover at most %d days, got %d", maxRecapDays, days)
+	}
+
+	name := regionNameOrCode(ctx, region)
+	if from.Equal(to) {
+		st, err := GetDayStats(ctx, region, from)
+		// Error handling
+		if err != nil {
+			return err
+		}
+		inv.send(s, FormatDayStats(name, st, inv.Locale))
+		return nil
+	}
+
+	days, err := GetStatsRange(ctx, region, from, to)
+	// Error handling
+	if err != nil {
+		return err
+	}
+	inv.send(s, FormatRecap(name, days, inv.Locale))
+	return nil
+}
+
+// regionArg takes the region code from region: or the first positional argument, returning the remaining arguments.
+func regionArg(positional []string, options map[string]string, usage string) (string, []string, error) {
+	if v, ok := options["region"]; ok {
+		code, err := parseRegionCode(v)
+		return code, positional, err
+	}
+	if len(positional) == 0 {
+		return "", nil, usageErrorf("%s", usage)
+	}
+	code, err := parseRegionCode(positional[0])
+	return code, positional[1:], err
+}
+
+// parseDate parses a date typed as YYYY-MM-DD, which must not be in the future.
+func parseDate(s string) (time.Time, error) {
+	d, err := time.ParseInLocation(dateLayout, s, time.Local)
+	// Error handling
+	if err != nil {
+		return d, usageErrorf("'%s' is not a date like 2022-10-10", s)
+	}
+	if d.After(today()) {
+		return d, usageErrorf("%s is in the future", s)
+	}
+	return d, nil
+}
+
+// today returns the start of the current day.
+func today() time.Time {
+	t := now()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.Local)
+}
+
+// GetTop100 returns eBird's top 100 contributors in a region on a date, ranked by "spp" (species) or "cl" (checklists).
+func GetTop100(ctx context.Context, region string, date time.Time, rankedBy string) ([]TopBirder, error) {
+	var top []TopBirder
+	url := fmt.Sprintf("%s/product/top100/%s/%d/%d/%d?rankedBy=%s&maxResults=100", Conf().EBirdURL, region, date.Year(), date.Month(), date.Day(), rankedBy)
+	err := ebirdGet(ctx, url, &top)
+	// Error handling
+	if err != nil {
+		return nil, fmt.Errorf("getting top 100 for %s on %s: %w", region, date.Format(dateLayout), err)
+	}
+	return top, nil
+}
+
+// GetDayStats returns the checklist, contributor and species totals for a region on a date.
+func GetDayStats(ctx context.Context, region string, date time.Time) (DayStats, error) {
+	var st DayStats
+	url := fmt.Sprintf("%s/product/stats/%s/%d/%d/%d", Conf().EBirdURL, region, date.Year(), date.Month(), date.Day())
+	err := ebirdGet(ctx, url, &st)
+	// Error handling
+	if err != nil {
+		return st, fmt.Errorf("getting stats for %s on %s: %w", region, date.Format(dateLayout), err)
+	}
+	st.Date = date
+	return st, nil
+}
+
+// GetStatsRange returns the daily stats for every day from from to to, inclusive, in order.
+// At most recapConcurrency requests are in flight at once, and the first error is returned.
+func GetStatsRange(ctx context.Context, region string, from, to time.Time) ([]DayStats, error) {
+	var dates []time.Time
+	for d := from; !d.After(to); d = d.AddDate(0, 0, 1) {
+		dates = append(dates, d)
+	}
+
+	days := make([]DayStats, len(dates))
+	err := runLimited(len(dates), recapConcurrency, func(i int) error {
+		var err error
+		days[i], err = GetDayStats(ctx, region, dates[i])
+		return err
+	})
+	// Error handling
+	if err != nil {
+		return nil, err
+	}
+	return days, nil
+}
+
+// FormatTop100 returns the top contributors for a Discord message in locale.
+func FormatTop100(name string, date time.Time, rankedBy string, top []TopBirder, locale string) string {
+	if len(top) == 0 {
+		return tr(locale, "**No eBird contributors found in %s on %s.**", name, date.Format(dateLayout))
+	}
+
+	rString := tr(locale, "**Top eBirders by species in %s on %s:**\n", name, date.Format(dateLayout))
+	if rankedBy == "cl" {
+		rString = tr(locale, "**Top eBirders by checklists in %s on %s:**\n", name, date.Format(dateLayout))
+	}
+	for i, b := range top {
+		rank := b.RowNum
+		if rank == 0 {
+			rank = i + 1
+		}
+		rString += tr(locale, "%d. %s: %d species, %s\n", rank, b.UserDisplayName, b.NumSpecies, pluralizeIn(locale, b.NumCompleteChecklists, "checklist"))
+	}
+
+	//Trimming return string to Discord max message length, minus 5 characters to show max length has been reached
+	return truncateText(rString, 1995)
+}
+
+// FormatDayStats returns a region's totals for one day for a Discord message in locale.
+func FormatDayStats(name string, st DayStats, locale string) string {
+	return tr(locale, "**eBird stats for %s on %s:**\n%s from %s, %d species\n", name, st.Date.Format(dateLayout),
+		pluralizeIn(locale, st.NumChecklists, "checklist"), pluralizeIn(locale, st.NumContributors, "contributor"), st.NumSpecies)
+}
+
+// FormatRecap returns a table of daily totals over a range in locale, with totals and the busiest day.
+// Contributors can take part on several days, so only the daily figures and the peak are shown for them.
+func FormatRecap(name string, days []DayStats, locale string) string {
+	first, last := days[0].Date, days[len(days)-1].Date
+	rString := tr(locale, "**eBird recap for %s, %s to %s:**\n", name, first.Format(dateLayout), last.Format(dateLayout))
+	rString += fmt.Sprintf("```\n%-10s %10s %12s %8s\n", tr(locale, "Date"), tr(locale, "Checklists"), tr(locale, "Contributors"), tr(locale, "Species"))
+
+	total := 0
+	busiest := days[0]
+	for _, d := range days {
+		rString += fmt.Sprintf("%-10s %10d %12d %8d\n", d.Date.Format(dateLayout), d.NumChecklists, d.NumContributors, d.NumSpecies)
+		total += d.NumChecklists
+		if d.NumChecklists > busiest.NumChecklists {
+			busiest = d
+		}
+	}
+	rString += "```\n"
+	rString += tr(locale, "%s in total, %.1f a day. Busiest day: %s with %s from %s.\n", pluralizeIn(locale, total, "checklist"),
+		float64(total)/float64(len(days)), busiest.Date.Format(dateLayout), pluralizeIn(locale, busiest.NumChecklists, "checklist"),
+		pluralizeIn(locale, busiest.NumContributors, "contributor"))
+
+	//Trimming return string to Discord max message length, minus 5 characters to show max length has been reached
+	return truncateText(rString, 1995)
+}
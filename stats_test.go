@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fixNow makes now return the given date for the duration of the test.
+func fixNow(t *testing.T, date string) {
+	t.Helper()
+	d, err := time.ParseInLocation(dateLayout, date, time.Local)
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := now
+	now = func() time.Time { return d.Add(15 * time.Hour) }
+	t.Cleanup(func() { now = old })
+}
+
+func TestTop100(t *testing.T) {
+	f := newFakeEBird(t)
+	fixNow(t, "2022-10-12")
+	f.route("/product/top100/US-NY-055/2022/10/10", "top100.json")
+	f.route("/product/top100/US-NY-055/2022/10/12", "top100.json")
+
+	sent := send(t, "!top100 us-ny-055 2022-10-10 by:checklists")
+	want := "**Top eBirders by checklists in US-NY-055 on 2022-10-10:**\n1. Alex Birder: 64 species, 5 checklists\n2. Sam Lister: 41 species, 1 checklist\n"
+	if len(sent) != 1 || sent[0].Content != want {
+		t.Fatalf("got %+v, want %q", sent, want)
+	}
+	if got := f.lastRequestTo("/product/top100/US-NY-055/2022/10/10").URL.Query().Get("rankedBy"); got != "cl" {
+		t.Errorf("rankedBy = %q, want cl", got)
+	}
+
+	// The date defaults to today
+	sent = send(t, "!top100 US-NY-055")
+	if len(sent) != 1 || !strings.Contains(sent[0].Content, "on 2022-10-12:") {
+		t.Errorf("unexpected reply %+v", sent)
+	}
+
+	for msg, want := range map[string]string{
+		"!top100":                      "Error: use `!top100",
+		"!top100 US-NY-055 2022-13-01": "Error: '2022-13-01' is not a date",
+		"!top100 US-NY-055 2022-10-13": "Error: 2022-10-13 is in the future",
+		"!top100 US-NY-055 by:owls":    "Error: 'by' must be",
+	} {
+		sent := send(t, msg)
+		if len(sent) != 1 || !strings.HasPrefix(sent[0].Content, want) {
+			t.Errorf("%s: got %+v, want prefix %q", msg, sent, want)
+		}
+	}
+}
+
+func TestStatsDay(t *testing.T) {
+	f := newFakeEBird(t)
+	fixNow(t, "2022-10-12")
+	f.route("/product/stats/US-NY-055/2022/10/10", "stats.json")
+
+	sent := send(t, "!stats US-NY-055 2022-10-10")
+	want := "**eBird stats for US-NY-055 on 2022-10-10:**\n112 checklists from 48 contributors, 97 species\n"
+	if len(sent) != 1 || sent[0].Content != want {
+		t.Fatalf("got %+v, want %q", sent, want)
+	}
+}
+
+func TestStatsRecap(t *testing.T) {
+	f := newFakeEBird(t)
+	fixNow(t, "2022-10-12")
+
+	// Each day reports its day of the month as its checklist count, and the handler tracks concurrent requests
+	var mu sync.Mutex
+	inFlight, maxInFlight := 0, 0
+	for day := 1; day <= 12; day++ {
+		day := day
+		f.handle(fmt.Sprintf("/product/stats/US-NY/2022/10/%d", day), func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			inFlight++
+			if inFlight > maxInFlight {
+				maxInFlight = inFlight
+			}
+			mu.Unlock()
+			time.Sleep(5 * time.Millisecond)
+			mu.Lock()
+			inFlight--
+			mu.Unlock()
+			fmt.Fprintf(w, `{"numChecklists": %d, "numContributors": 2, "numSpecies": 30}`, day)
+		})
+	}
+
+	// The current month stops at today
+	sent := send(t, "!stats US-NY month:2022-10")
+	if len(sent) != 1 {
+		t.Fatalf("sent %d messages, want 1", len(sent))
+	}
+	for _, want := range []string{
+		"**eBird recap for US-NY, 2022-10-01 to 2022-10-12:**",
+		"2022-10-01          1            2       30\n",
+		"2022-10-12         12            2       30\n",
+		"78 checklists in total, 6.5 a day. Busiest day: 2022-10-12 with 12 checklists from 2 contributors.",
+	} {
+		if !strings.Contains(sent[0].Content, want) {
+			t.Errorf("recap %q does not contain %q", sent[0].Content, want)
+		}
+	}
+	if maxInFlight > recapConcurrency {
+		t.Errorf("%d requests in flight, want at most %d", maxInFlight, recapConcurrency)
+	}
+
+	sent = send(t, "!stats US-NY 2022-10-02..2022-10-03")
+	if len(sent) != 1 || !strings.Contains(sent[0].Content, "5 checklists in total") {
+		t.Errorf("unexpected reply %+v", sent)
+	}
+
+	for msg, want := range map[string]string{
+		"!stats US-NY 2022-10-05..2022-10-01": "Error: the range must not end before it starts",
+		"!stats US-NY 2022-08-01..2022-10-01": "Error: ranges can cover at most 31 days, got 62",
+		"!stats US-NY month:october":          "Error: 'october' is not a month",
+	} {
+		sent := send(t, msg)
+		if len(sent) != 1 || !strings.HasPrefix(sent[0].Content, want) {
+			t.Errorf("%s: got %+v, want prefix %q", msg, sent, want)
+		}
+	}
+}
@@ -0,0 +1,279 @@
+// Store keeps FlaminGo's per-guild and per-user data in a JSON file in the data directory
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// GuildSettings holds everything FlaminGo remembers about a guild.
+type GuildSettings struct {
+	// Locations holds the guild's saved locations, keyed by the lowercased name used in commands.
+	Locations map[string]locationEntry `json:"locations,omitempty"`
+	// LocationModes overrides the query mode of any location, built-in or saved, keyed by its name.
+	LocationModes map[string]string `json:"location_modes,omitempty"`
+	// Locale is the language the guild gets replies and species names in. Empty means English.
+	Locale string `json:"locale,omitempty"`
+	// Prefix replaces "!" at the start of commands. Empty means "!".
+	Prefix string `json:"prefix,omitempty"`
+	// DefaultLocation is the name of the location used when a command is given none. Empty means RIT.
+	DefaultLocation string `json:"default_location,omitempty"`
+	// Radius is the default search radius in km. Zero means Conf.Radius.
+	Radius int `json:"radius,omitempty"`
+	// RareMultiplier is how many times Radius !rare searches. Zero means Conf.RareRadius / Conf.Radius.
+	RareMultiplier float64 `json:"rare_multiplier,omitempty"`
+	// EmbedColor is the color of embeds as 0xRRGGBB. Zero means FlaminGo's pink.
+	EmbedColor int `json:"embed_color,omitempty"`
+	// EnabledCommands lists the commands FlaminGo answers, without their prefix. Empty means all of them.
+	EnabledCommands []string `json:"enabled_commands,omitempty"`
+	// TimeZone is the IANA time zone the guild's trips are planned in, e.g. America/New_York. Empty means
+	// Conf().TimeZone.
+	TimeZone string `json:"time_zone,omitempty"`
+	// AnnouncementChannel is the ID of the channel that gets announcements from FlaminGo's owners. Empty means none.
+	AnnouncementChannel string `json:"announcement_channel,omitempty"`
+	// Words holds the word lists the guild uploaded for !generate. Nil means only the default lists are used.
+	Words *WordLists `json:"words,omitempty"`
+	// ReplaceWords makes !generate use only Words, instead of Words along with the default lists.
+	ReplaceWords bool `json:"replace_words,omitempty"`
+	// Trips holds the field trips planned with !trip create that have not had their recap yet.
+	Trips []Trip `json:"trips,omitempty"`
+}
+
+// UserSettings holds everything FlaminGo remembers about a user, across guilds.
+type UserSettings struct {
+	// Locale is the language the user gets replies and species names in, overriding the guild's.
+	Locale string `json:"locale,omitempty"`
+}
+
+// Store is a set of GuildSettings and UserSettings saved as a single JSON file. It is safe for concurrent use.
+type Store struct {
+	mu sync.Mutex
+	// path is the file the store is saved to. An empty path keeps the store in memory only.
+	path   string
+	guilds map[string]*GuildSettings
+	users  map[string]*UserSettings
+}
+
+// storeFile is the layout of the store's JSON file.
+type storeFile struct {
+	Guilds map[string]*GuildSettings `json:"guilds"`
+	Users  map[string]*UserSettings  `json:"users,omitempty"`
+}
+
+// store is the Store used by commands. It starts out in memory only, and is replaced by openStore at startup.
+var store = newStore("")
+
+// newStore returns an empty store that saves to path.
+func newStore(path string) *Store {
+	return &Store{path: path, guilds: make(map[string]*GuildSettings), users: make(map[string]*UserSettings)}
+}
+
+// openStore loads the store saved at path, or starts an empty one if the file does not exist yet.
+func openStore(path string) (*Store, error) {
+	st := newStore(path)
+
+	data, err := os.ReadFile(path)
+	// A missing file just means nothing has been saved yet
+	if errors.Is(err, fs.ErrNotExist) {
+		return st, nil
+	}
+	// Error handling
+	if err != nil {
+		return nil, fmt.Errorf("reading store: %w", err)
+	}
+
+	var f storeFile
+	err = json.Unmarshal(data, &f)
+	// Error handling
+	if err != nil {
+		return nil, fmt.Errorf("parsing store %s: %w", path, err)
+	}
+	if f.Guilds != nil {
+		st.guilds = f.Guilds
+	}
+	if f.Users != nil {
+		st.users = f.Users
+	}
+	return st, nil
+}
+
+// Guild returns a copy of the settings for guildID. Guilds with nothing saved get empty settings.
+func (st *Store) Guild(guildID string) GuildSettings {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	g, ok := st.guilds[guildID]
+	if !ok {
+		return GuildSettings{}
+	}
+	return g.clone()
+}
+
+// GuildIDs returns the IDs of every guild with saved settings.
+func (st *Store) GuildIDs() []string {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	ids := make([]string, 0, len(st.guilds))
+	for id := range st.guilds {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Update calls fn with the settings for guildID and saves the store. If fn returns an error, nothing is changed.
+func (st *Store) Update(guildID string, fn func(g *GuildSettings) error) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	// Working on a copy, so a failed update leaves the store untouched
+	g := GuildSettings{}
+	if old, ok := st.guilds[guildID]; ok {
+		g = old.clone()
+	}
+	err := fn(&g)
+	// Error handling
+	if err != nil {
+		return err
+	}
+
+	old := st.guilds[guildID]
+	st.guilds[guildID] = &g
+	err = st.save()
+	// Error handling
+	if err != nil {
+		// Restoring the previous settings so memory matches what is on disk
+		if old == nil {
+			delete(st.guilds, guildID)
+		} else {
+			st.guilds[guildID] = old
+		}
+		return err
+	}
+	return nil
+}
+
+// User returns a copy of the settings for userID. Users with nothing saved get empty settings.
+func (st *Store) User(userID string) UserSettings {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	u, ok := st.users[userID]
+	if !ok {
+		return UserSettings{}
+	}
+	return *u
+}
+
+// UpdateUser calls fn with the settings for userID and saves the store. If fn returns an error, nothing is changed.
+func (st *Store) UpdateUser(userID string, fn func(u *UserSettings) error) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	// Working on a copy, so a failed update leaves the store untouched
+	u := UserSettings{}
+	if old, ok := st.users[userID]; ok {
+		u = *old
+	}
+	err := fn(&u)
+	// Error handling
+	if err != nil {
+		return err
+	}
+
+	old := st.users[userID]
+	st.users[userID] = &u
+	err = st.save()
+	// Error handling
+	if err != nil {
+		// Restoring the previous settings so memory matches what is on disk
+		if old == nil {
+			delete(st.users, userID)
+		} else {
+			st.users[userID] = old
+		}
+		return err
+	}
+	return nil
+}
+
+// save writes the store to its file. The file is replaced atomically so a crash never leaves half a store behind.
+// st.mu must be held.
+func (st *Store) save() error {
+	if st.path == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(storeFile{Guilds: st.guilds, Users: st.users}, "", "  ")
+	// Error handling
+	if err != nil {
+		return fmt.Errorf("encoding store: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(st.path), ".guilds-*.json")
+	// Error handling
+	if err != nil {
+		return fmt.Errorf("saving store: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Close()
+	} else {
+		tmp.Close()
+	}
+	// Error handling
+	if err != nil {
+		return fmt.Errorf("saving store: %w", err)
+	}
+
+	err = os.Rename(tmp.Name(), st.path)
+	// Error handling
+	if err != nil {
+		return fmt.Errorf("saving store: %w", err)
+	}
+	return nil
+}
+
+// clone returns a deep copy of g.
+func (g *GuildSettings) clone() GuildSettings {
+	c := *g
+	if g.Locations != nil {
+		c.Locations = make(map[string]locationEntry, len(g.Locations))
+		for k, v := range g.Locations {
+			c.Locations[k] = v
+		}
+	}
+	if g.EnabledCommands != nil {
+		c.EnabledCommands = append([]string(nil), g.EnabledCommands...)
+	}
+	if g.LocationModes != nil {
+		c.LocationModes = make(map[string]string, len(g.LocationModes))
+		for k, v := range g.LocationModes {
+			c.LocationModes[k] = v
+		}
+	}
+	if g.Trips != nil {
+		c.Trips = make([]Trip, len(g.Trips))
+		for i, t := range g.Trips {
+			t.Going = append([]string(nil), t.Going...)
+			c.Trips[i] = t
+		}
+	}
+	if g.Words != nil {
+		c.Words = &WordLists{
+			Adjectives: append([]string(nil), g.Words.Adjectives...),
+			Nouns:      append([]string(nil), g.Words.Nouns...),
+		}
+	}
+	return c
+}
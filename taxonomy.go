@@ -0,0 +1,320 @@
+// Taxonomy looks up and caches species information from the eBird taxonomy
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Taxon holds information about a species from the eBird taxonomy.
+type Taxon struct {
+	SpeciesCode   string
+	ComName       string
+	SciName       string
+	Category      string
+	TaxonOrder    float64
+	Order         string
+	FamilyComName string
+	FamilySciName string
+	BandingCodes  []string
+}
+
+var (
+	// taxaMu guards taxa.
+	taxaMu sync.Mutex
+	// taxa caches every taxon fetched so far, keyed by locale and then by species code. The taxonomy rarely changes,
+	// so entries never expire.
+	taxa = make(map[string]map[string]Taxon)
+)
+
+// cacheTaxa adds taxa in a locale to the cache. taxaMu must be held.
+func cacheTaxa(locale string, fetched []Taxon) {
+	if taxa[locale] == nil {
+		taxa[locale] = make(map[string]Taxon)
+	}
+	for _, t := range fetched {
+		taxa[locale][t.SpeciesCode] = t
+	}
+}
+
+// taxonomyLocaleParam returns the query parameter asking the taxonomy endpoint for names in a locale. Unlike the
+// observation endpoints, it calls the parameter locale.
+func taxonomyLocaleParam(locale string) string {
+	if locale == "" || locale == defaultLocale {
+		return ""
+	}
+	return "&locale=" + locale
+}
+
+// lookupTaxa returns the taxa for the given species codes with names in locale, fetching any that are not cached yet
+// from eBird. Codes eBird does not know are missing from the returned map.
+func lookupTaxa(ctx context.Context, codes []string, locale string) (map[string]Taxon, error) {
+	if locale == "" {
+		locale = defaultLocale
+	}
+	found := make(map[string]Taxon)
+	var missing []string
+
+	taxaMu.Lock()
+	for _, code := range codes {
+		if t, ok := taxa[locale][code]; ok {
+			found[code] = t
+		} else if code != "" {
+			missing = append(missing, code)
+		}
+	}
+	taxaMu.Unlock()
+
+	if len(missing) == 0 {
+		return found, nil
+	}
+
+	// Only fetching the species we need, since the full taxonomy is several megabytes
+	sort.Strings(missing)
+	missing = dedupe(missing)
+	var fetched []Taxon
+	err := ebirdGet(ctx, fmt.Sprintf("%s/ref/taxonomy/ebird?fmt=json&species=%s%s", Conf().EBirdURL, url.QueryEscape(strings.Join(missing, ",")), taxonomyLocaleParam(locale)), &fetched)
+	// Error handling
+	if err != nil {
+		return found, fmt.Errorf("looking up taxonomy: %w", err)
+	}
+
+	taxaMu.Lock()
+	cacheTaxa(locale, fetched)
+	taxaMu.Unlock()
+
+	for _, t := range fetched {
+		found[t.SpeciesCode] = t
+	}
+	return found, nil
+}
+
+// dedupe removes consecutive duplicates from a sorted slice.
+func dedupe(s []string) []string {
+	out := s[:0]
+	for i, v := range s {
+		if i == 0 || v != s[i-1] {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+var (
+	// allTaxaMu guards allTaxa and allTaxaLoads.
+	allTaxaMu sync.Mutex
+	// allTaxa holds every species in the eBird taxonomy per locale once loadAllTaxa succeeds, for looking species up
+	// by name.
+	allTaxa = make(map[string][]Taxon)
+	// allTaxaLoads holds the taxonomy fetches in progress per locale, so commands wait for the one fetch instead of
+	// each downloading the taxonomy.
+	allTaxaLoads = make(map[string]*taxaLoad)
+)
+
+// taxaLoad is a fetch of the full taxonomy for a locale. done is closed once taxa and err are set.
+type taxaLoad struct {
+	done chan struct{}
+	taxa []Taxon
+	err  error
+}
+
+// loadAllTaxa fetches the species in the eBird taxonomy with names in locale the first time it is called for that
+// locale, and returns them. Calls while a fetch is in progress wait for it. A failed fetch is retried on the next
+// call.
+func loadAllTaxa(ctx context.Context, locale string) ([]Taxon, error) {
+	if locale == "" {
+		locale = defaultLocale
+	}
+
+	for {
+		allTaxaMu.Lock()
+		if all, ok := allTaxa[locale]; ok {
+			allTaxaMu.Unlock()
+			return all, nil
+		}
+		load, ok := allTaxaLoads[locale]
+		if !ok {
+			// Fetching without holding the lock, since the taxonomy is several megabytes
+			load = &taxaLoad{done: make(chan struct{})}
+			allTaxaLoads[locale] = load
+			allTaxaMu.Unlock()
+			return fetchAllTaxa(ctx, locale, load)
+		}
+		allTaxaMu.Unlock()
+
+		select {
+		case <-load.done:
+		case <-ctx.Done():
+			return nil, fmt.Errorf("loading taxonomy: %w", ctx.Err())
+		}
+		if load.err == nil {
+			return load.taxa, nil
+		}
+		// The other command's fetch failed, possibly only because it was cancelled, so this one tries again
+	}
+}
+
+// fetchAllTaxa fetches the taxonomy for loadAllTaxa, and finishes load with the result.
+func fetchAllTaxa(ctx context.Context, locale string, load *taxaLoad) ([]Taxon, error) {
+	var fetched []Taxon
+	err := ebirdGet(ctx, fmt.Sprintf("%s/ref/taxonomy/ebird?fmt=json&cat=species%s", Conf().EBirdURL, taxonomyLocaleParam(locale)), &fetched)
+	if err == nil {
+		// Filling the per-code cache too, since every species is known now
+		taxaMu.Lock()
+		cacheTaxa(locale, fetched)
+		taxaMu.Unlock()
+	}
+
+	allTaxaMu.Lock()
+	if err == nil {
+		allTaxa[locale] = fetched
+	}
+	delete(allTaxaLoads, locale)
+	allTaxaMu.Unlock()
+	load.taxa, load.err = fetched, err
+	close(load.done)
+
+	// Error handling
+	if err != nil {
+		return nil, fmt.Errorf("loading taxonomy: %w", err)
+	}
+	return fetched, nil
+}
+
+// loadTaxonomySnapshot reads a snapshot of the English species taxonomy from file, as returned by eBird's
+// /ref/taxonomy/ebird?fmt=json&cat=species. None is built into the binary, so without a file there is no snapshot,
+// and the taxonomy is fetched from eBird the first time it is needed.
+func loadTaxonomySnapshot(file string) ([]Taxon, error) {
+	if file == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(file)
+	// Error handling
+	if err != nil {
+		return nil, fmt.Errorf("reading taxonomy file: %w", err)
+	}
+
+	var snapshot []Taxon
+	err = json.Unmarshal(data, &snapshot)
+	// Error handling
+	if err != nil {
+		return nil, fmt.Errorf("parsing taxonomy file %s: %w", file, err)
+	}
+	for i, t := range snapshot {
+		if t.SpeciesCode == "" || t.ComName == "" {
+			return nil, fmt.Errorf("taxonomy file %s: entry %d has no species code or common name", file, i+1)
+		}
+	}
+	return snapshot, nil
+}
+
+// useTaxonomySnapshot fills the English taxonomy caches from a snapshot, so species can be looked up by name without
+// fetching the full taxonomy from eBird. An empty snapshot leaves the caches alone.
+func useTaxonomySnapshot(snapshot []Taxon) {
+	if len(snapshot) == 0 {
+		return
+	}
+
+	allTaxaMu.Lock()
+	allTaxa[defaultLocale] = snapshot
+	allTaxaMu.Unlock()
+	taxaMu.Lock()
+	cacheTaxa(defaultLocale, snapshot)
+	taxaMu.Unlock()
+}
+
+// findTaxon resolves a species name typed by a user, e.g. "red-headed woodpecker", to its taxon with names in locale.
+// An exact common name, scientific name, species code or four-letter banding code wins. Otherwise the name must
+// match part of exactly one common name. English names are tried too if nothing matches in locale, since many birders
+// know the English names.
+func findTaxon(ctx context.Context, name, locale string) (Taxon, error) {
+	t, err := findTaxonIn(ctx, name, locale)
+	var usageErr *UsageError
+	if locale == "" || locale == defaultLocale || !errors.As(err, &usageErr) {
+		return t, err
+	}
+
+	english, englishErr := findTaxonIn(ctx, name, defaultLocale)
+	// Error handling
+	if englishErr != nil {
+		return t, err
+	}
+	localized, lookupErr := lookupTaxa(ctx, []string{english.SpeciesCode}, locale)
+	if l, ok := localized[english.SpeciesCode]; ok && lookupErr == nil {
+		return l, nil
+	}
+	return english, nil
+}
+
+// findTaxonIn looks a species name up among the names in a single locale.
+func findTaxonIn(ctx context.Context, name, locale string) (Taxon, error) {
+	all, err := loadAllTaxa(ctx, locale)
+	// Error handling
+	if err != nil {
+		return Taxon{}, err
+	}
+
+	query := normalizeSpeciesName(name)
+	if query == "" {
+		return Taxon{}, usageErrorf("a species name is needed")
+	}
+
+	var partial []Taxon
+	for _, t := range all {
+		if normalizeSpeciesName(t.ComName) == query || normalizeSpeciesName(t.SciName) == query || strings.EqualFold(t.SpeciesCode, query) {
+			return t, nil
+		}
+		for _, b := range t.BandingCodes {
+			if strings.EqualFold(b, query) {
+				return t, nil
+			}
+		}
+		if strings.Contains(normalizeSpeciesName(t.ComName), query) {
+			partial = append(partial, t)
+		}
+	}
+
+	switch len(partial) {
+	case 0:
+		return Taxon{}, usageErrorf("no species found matching '%s'", name)
+	case 1:
+		return partial[0], nil
+	}
+
+	// Listing a few candidates so the user can be more specific
+	var names []string
+	for i, t := range partial {
+		if i == 5 {
+			names = append(names, "...")
+			break
+		}
+		names = append(names, t.ComName)
+	}
+	return Taxon{}, usageErrorf("'%s' matches several species: %s", name, strings.Join(names, ", "))
+}
+
+// normalizeSpeciesName lowercases a species name and drops accents, apostrophes and hyphens,
+// so "Swainson's Thrush", "swainsons thrush", "red headed woodpecker" and "buho nival" match eBird's names.
+func normalizeSpeciesName(name string) string {
+	// Transformers keep state, so each call gets its own
+	stripAccents := transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+	if stripped, _, err := transform.String(stripAccents, name); err == nil {
+		name = stripped
+	}
+	name = strings.ToLower(name)
+	name = strings.NewReplacer("'", "", "’", "", "-", " ").Replace(name)
+	return strings.Join(strings.Fields(name), " ")
+}
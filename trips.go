@@ -0,0 +1,588 @@
+// Trips contains the !trip command, which plans club field trips with RSVP buttons, reminders and a sightings recap
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Timing of trip reminders and recaps.
+const (
+	// tripPollInterval is how often FlaminGo checks for trips needing a reminder or a recap.
+	tripPollInterval = time.Minute
+	// tripReminder is how long before a trip starts the reminder is posted.
+	tripReminder = time.Hour
+	// tripRecapDelay is how long after a trip ends the recap is posted, giving attendees time to submit checklists.
+	tripRecapDelay = 2 * time.Hour
+	// maxTripRecapRetry is how long FlaminGo keeps retrying a recap eBird could not be asked for, before giving up.
+	maxTripRecapRetry = 24 * time.Hour
+	// defaultTripHours is how long a trip lasts unless hours: is given.
+	defaultTripHours = 3
+	// maxTrips is how many trips a guild can have planned at once.
+	maxTrips = 25
+	// tripChecklists is how many of the location's most recent checklists are searched for the trip's, the most eBird
+	// returns.
+	tripChecklists = 200
+	// tripConcurrency is the most checklists a recap fetches from eBird at once.
+	tripConcurrency = 4
+)
+
+// tripUsage explains the !trip command.
+const tripUsage = "use `!trip create <location> <YYYY-MM-DD HH:MM> [title] [hours:1-12]`, `!trip list` or `!trip cancel <id>`"
+
+// tripTimeLayouts are the date and time formats !trip create accepts, in the guild's time zone.
+var tripTimeLayouts = []string{"2006-01-02 15:04", "2006-01-02T15:04"}
+
+// errTripNotFound is returned from store updates when the trip was cancelled or is over.
+var errTripNotFound = errors.New("trip not found")
+
+// Trip is a field trip planned with !trip create.
+type Trip struct {
+	// ID identifies the trip in !trip cancel and in its buttons.
+	ID string `json:"id"`
+	// ChannelID is the channel the trip was planned in, which gets its reminder and recap.
+	ChannelID string `json:"channel_id"`
+	// Location is the lowercased name of the trip's location, as used in commands.
+	Location string `json:"location"`
+	// Title, Start and Hours describe the trip. Start is in FlaminGo's time zone.
+	Title string    `json:"title"`
+	Start time.Time `json:"start"`
+	Hours int       `json:"hours"`
+	// CreatedBy is the user ID of the organizer, who may cancel the trip.
+	CreatedBy string `json:"created_by"`
+	// Going lists the user IDs of everyone who RSVPed.
+	Going []string `json:"going,omitempty"`
+	// Reminded is set once the reminder was posted, or if the trip was planned too late for one.
+	Reminded bool `json:"reminded,omitempty"`
+}
+
+// end returns when the trip is over.
+func (t Trip) end() time.Time {
+	return t.Start.Add(time.Duration(t.Hours) * time.Hour)
+}
+
+// runTrip handles !trip create, !trip list and !trip cancel.
+func runTrip(s Sender, inv *Invocation) error {
+	if inv.GuildID == "" {
+		return usageErrorf("trips can only be planned in a server")
+	}
+	if len(inv.Args) == 0 {
+		return usageErrorf("%s", tripUsage)
+	}
+
+	switch inv.Args[0] {
+	case "create":
+		return createTrip(s, inv)
+	case "list":
+		inv.send(s, formatTrips(inv.GuildID, inv.Locale))
+	case "cancel":
+		if len(inv.Args) != 2 {
+			return usageErrorf("%s", tripUsage)
+		}
+		return cancelTrip(s, inv, inv.Args[1])
+	default:
+		return usageErrorf("%s", tripUsage)
+	}
+	return nil
+}
+
+// createTrip handles !trip create <location> <datetime> [title] [hours:1-12], posting the trip with its RSVP buttons.
+func createTrip(s Sender, inv *Invocation) error {
+	args := inv.Args[1:]
+	if len(args) < 2 {
+		return usageErrorf("%s", tripUsage)
+	}
+	loc, ok := namedLocation(inv.GuildID, args[0])
+	if !ok {
+		return usageErrorf("'%s' is not a valid option for !%s", args[0], "trip")
+	}
+	// The recap is made from the checklists submitted at the location
+	if loc.code == "" {
+		return usageErrorf("'%s' has no eBird location code, so there would be no checklists for the recap", args[0])
+	}
+
+	// The date and time are read before the options, since times contain a colon too
+	start, used, err := parseTripTime(args[1:], guildTimeZone(inv.GuildID))
+	// Error handling
+	if err != nil {
+		return err
+	}
+	if !start.After(now()) {
+		return usageErrorf("the trip must start in the future")
+	}
+
+	// The title keeps the case it was typed in, which the arguments lose
+	title, options := splitOptions(args[1+used:])
+	if typed := tokenize(inv.Content); len(typed) >= len(args)-1-used {
+		title, _ = splitOptions(typed[len(typed)-(len(args)-1-used):])
+	}
+	hours, err := intOption(options, "hours", 1, 12, defaultTripHours)
+	// Error handling
+	if err != nil {
+		return err
+	}
+	for k := range options {
+		if k != "hours" {
+			return usageErrorf("'%s' is not a valid option for !%s", k, "trip")
+		}
+	}
+
+	locale := localeFor(inv.GuildID, "")
+	trip := Trip{
+		ID:        newCorrelationID(),
+		ChannelID: inv.ChannelID,
+		Location:  strings.ToLower(args[0]),
+		Title:     strings.Join(title, " "),
+		Start:     start,
+		Hours:     hours,
+		CreatedBy: inv.UserID,
+		// Trips starting within the hour are reminded of by their own announcement
+		Reminded: start.Sub(now()) <= tripReminder,
+	}
+	if trip.Title == "" {
+		trip.Title = tr(locale, "Bird walk at %s", loc.name)
+	}
+
+	err = store.Update(inv.GuildID, func(g *GuildSettings) error {
+		if len(g.Trips) >= maxTrips {
+			return usageErrorf("this server already has %d trips planned, cancel one first", maxTrips)
+		}
+		g.Trips = append(g.Trips, trip)
+		return nil
+	})
+	// Error handling
+	if err != nil {
+		return err
+	}
+
+	_, err = s.ChannelMessageSendComplex(inv.ChannelID, &discordgo.MessageSend{
+		Embeds:     []*discordgo.MessageEmbed{tripEmbed(inv.GuildID, trip)},
+		Components: tripButtons(trip, locale),
+	})
+	// Error handling
+	if err != nil {
+		// Nobody can RSVP to a trip that was never posted
+		removeTrip(inv.GuildID, trip.ID)
+		return fmt.Errorf("posting trip: %w", err)
+	}
+	inv.Log.Info("planned trip", slog.String("trip", trip.ID), slog.Time("start", trip.Start))
+	return nil
+}
+
+// parseTripTime reads a trip's start from the start of args, either as one argument such as "2022-10-15 08:00" or
+// as a date and a time, in the time zone tz. It returns how many arguments were used.
+func parseTripTime(args []string, tz *time.Location) (time.Time, int, error) {
+	for _, used := range []int{1, 2} {
+		if len(args) < used {
+			break
+		}
+		// Commands are lowercased, so 2022-10-15t08:00 is put back the way the layouts write it
+		v := strings.ToUpper(strings.Join(args[:used], " "))
+		for _, layout := range tripTimeLayouts {
+			if t, err := time.ParseInLocation(layout, v, tz); err == nil {
+				return t, used, nil
+			}
+		}
+	}
+	return time.Time{}, 0, usageErrorf("'%s' is not a date and time, use the format YYYY-MM-DD HH:MM", args[0])
+}
+
+// cancelTrip handles !trip cancel <id>. Only the organizer and server managers may cancel a trip.
+func cancelTrip(s Sender, inv *Invocation, id string) error {
+	trip, ok := findTrip(store.Guild(inv.GuildID).Trips, id)
+	if !ok {
+		return usageErrorf("there is no trip '%s', see `!trip list`", id)
+	}
+	if trip.CreatedBy != inv.UserID {
+		err := requireManageServer(s, inv)
+		// Error handling
+		var usageErr *UsageError
+		if errors.As(err, &usageErr) {
+			return usageErrorf("only the trip's organizer or someone with the Manage Server permission can cancel it")
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	removeTrip(inv.GuildID, trip.ID)
+	inv.Log.Info("cancelled trip", slog.String("trip", trip.ID))
+	msg := tr(inv.Locale, "Cancelled **%s**.", trip.Title)
+	if len(trip.Going) > 0 {
+		msg += " " + mentions(trip.Going)
+	}
+	inv.send(s, msg)
+	return nil
+}
+
+// formatTrips lists a guild's planned trips, soonest first, for !trip list.
+func formatTrips(guildID, locale string) string {
+	trips := store.Guild(guildID).Trips
+	if len(trips) == 0 {
+		return tr(locale, "No trips are planned. Plan one with `!trip create`.")
+	}
+	sort.Slice(trips, func(i, j int) bool {
+		return trips[i].Start.Before(trips[j].Start)
+	})
+
+	rString := tr(locale, "**Planned trips:**\n")
+	for _, t := range trips {
+		rString += tr(locale, "`%s` **%s** %s, %s, %d going\n", t.ID, t.Title, tripPlace(guildID, t, locale),
+			discordTime(t.Start, "F"), len(t.Going))
+	}
+	return truncateText(rString, 1995)
+}
+
+// findTrip returns the trip with the given ID.
+func findTrip(trips []Trip, id string) (Trip, bool) {
+	for _, t := range trips {
+		if t.ID == id {
+			return t, true
+		}
+	}
+	return Trip{}, false
+}
+
+// removeTrip deletes a trip from the guild's settings, logging the error if the store cannot be saved.
+func removeTrip(guildID, id string) {
+	err := store.Update(guildID, func(g *GuildSettings) error {
+		for i, t := range g.Trips {
+			if t.ID == id {
+				g.Trips = append(g.Trips[:i], g.Trips[i+1:]...)
+				return nil
+			}
+		}
+		return errTripNotFound
+	})
+	// Error handling
+	if err != nil && !errors.Is(err, errTripNotFound) {
+		logger.Error("removing trip", slog.String("guild", guildID), slog.String("trip", id), slog.Any("err", err))
+	}
+}
+
+// tripPlace describes where a trip goes, e.g. "at Mendon Ponds Park", using the location's current name.
+func tripPlace(guildID string, t Trip, locale string) string {
+	if loc, ok := namedLocation(guildID, t.Location); ok {
+		return tr(locale, "at %s", loc.name)
+	}
+	return tr(locale, "at %s", t.Location)
+}
+
+// discordTime formats t as a Discord timestamp, which every user sees in their own time zone.
+// style is one of Discord's styles, e.g. "F" for the full date and time or "R" for "in 2 hours".
+func discordTime(t time.Time, style string) string {
+	return fmt.Sprintf("<t:%d:%s>", t.Unix(), style)
+}
+
+// mentions returns a mention of each user, separated by spaces.
+func mentions(userIDs []string) string {
+	tags := make([]string, len(userIDs))
+	for i, id := range userIDs {
+		tags[i] = "<@" + id + ">"
+	}
+	return strings.Join(tags, " ")
+}
+
+// tripEmbed returns the announcement of a trip, with its attendees, in the guild's language.
+func tripEmbed(guildID string, t Trip) *discordgo.MessageEmbed {
+	locale := localeFor(guildID, "")
+	going := tr(locale, "Nobody yet")
+	if len(t.Going) > 0 {
+		going = mentions(t.Going)
+	}
+
+	return &discordgo.MessageEmbed{
+		Title: t.Title,
+		Color: embedColor(guildID),
+		Fields: []*discordgo.MessageEmbedField{
+			{Name: tr(locale, "Where"), Value: tripPlace(guildID, t, locale), Inline: true},
+			{Name: tr(locale, "When"), Value: fmt.Sprintf("%s (%s), %s", discordTime(t.Start, "F"), discordTime(t.Start, "R"),
+				tr(locale, "for %s", pluralizeIn(locale, t.Hours, "hour"))), Inline: true},
+			{Name: tr(locale, "Going (%d)", len(t.Going)), Value: going, Inline: false},
+		},
+		Footer: &discordgo.MessageEmbedFooter{
+			Text: tr(locale, "Trip %s. Everyone going gets a reminder an hour before it starts, and a recap of the sightings afterwards.", t.ID),
+		},
+	}
+}
+
+// tripButtons returns the RSVP buttons for a trip. Their custom IDs are "trip:going:<id>" and "trip:notgoing:<id>".
+func tripButtons(t Trip, locale string) []discordgo.MessageComponent {
+	return []discordgo.MessageComponent{
+		discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+			discordgo.Button{Label: tr(locale, "Going"), Style: discordgo.SuccessButton, CustomID: "trip:going:" + t.ID},
+			discordgo.Button{Label: tr(locale, "Can't make it"), Style: discordgo.SecondaryButton, CustomID: "trip:notgoing:" + t.ID},
+		}},
+	}
+}
+
+// answerTripButton adds or removes the user clicking a trip's RSVP button, and updates the trip's announcement.
+func answerTripButton(s Sender, i *discordgo.InteractionCreate) {
+	customID := i.MessageComponentData().CustomID
+	userID := ""
+	if i.Member != nil && i.Member.User != nil {
+		userID = i.Member.User.ID
+	} else if i.User != nil {
+		userID = i.User.ID
+	}
+	log := logger.With(slog.String("guild", i.GuildID), slog.String("channel", i.ChannelID), slog.String("user", userID),
+		slog.String("button", customID))
+	// Without a user there is nobody to add to or remove from the trip
+	if userID == "" {
+		log.Warn("ignoring button click without a user")
+		return
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			log.Error("button panicked", slog.Any("panic", r))
+		}
+	}()
+
+	_, rest, _ := strings.Cut(customID, ":")
+	action, id, _ := strings.Cut(rest, ":")
+	var trip Trip
+	err := store.Update(i.GuildID, func(g *GuildSettings) error {
+		for n := range g.Trips {
+			t := &g.Trips[n]
+			if t.ID != id {
+				continue
+			}
+			t.Going = removeString(t.Going, userID)
+			if action == "going" {
+				t.Going = append(t.Going, userID)
+			}
+			trip = *t
+			return nil
+		}
+		return errTripNotFound
+	})
+
+	// Only the user who clicked sees why nothing changed
+	var resp *discordgo.InteractionResponse
+	locale := localeFor(i.GuildID, userID)
+	if errors.Is(err, errTripNotFound) {
+		resp = ephemeralResponse(tr(locale, "This trip is over or was cancelled."))
+	} else if err != nil {
+		log.Error("saving RSVP", slog.Any("err", err))
+		resp = ephemeralResponse(tr(locale, "Sorry, something went wrong. Try again in a little while."))
+	} else {
+		log.Info("trip RSVP", slog.String("trip", id), slog.String("action", action))
+		resp = &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseUpdateMessage,
+			Data: &discordgo.InteractionResponseData{
+				Embeds:     []*discordgo.MessageEmbed{tripEmbed(i.GuildID, trip)},
+				Components: tripButtons(trip, localeFor(i.GuildID, "")),
+			},
+		}
+	}
+
+	err = s.InteractionRespond(i.Interaction, resp)
+	// Error handling
+	if err != nil {
+		log.Warn("answering button failed", slog.Any("err", err))
+	}
+}
+
+// ephemeralResponse returns an interaction response only the user who clicked can see.
+func ephemeralResponse(msg string) *discordgo.InteractionResponse {
+	return &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{Content: msg, Flags: uint64(discordgo.MessageFlagsEphemeral)},
+	}
+}
+
+// removeString returns s without any element equal to v.
+func removeString(s []string, v string) []string {
+	var out []string
+	for _, e := range s {
+		if e != v {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+// watchTrips posts trip reminders and recaps as they come due. It never returns, so it is started with go.
+func watchTrips(s Sender) {
+	for range time.Tick(tripPollInterval) {
+		checkTrips(s)
+	}
+}
+
+// checkTrips posts the reminder of every trip starting within tripReminder, and the recap of every trip that ended
+// tripRecapDelay ago. Trips are forgotten once their recap is posted.
+func checkTrips(s Sender) {
+	t := now()
+	for _, guildID := range store.GuildIDs() {
+		for _, trip := range store.Guild(guildID).Trips {
+			log := logger.With(slog.String("guild", guildID), slog.String("trip", trip.ID))
+			switch {
+			case !trip.Reminded && !t.Before(trip.Start.Add(-tripReminder)):
+				// Reminders are not sent late, e.g. after FlaminGo was down
+				if t.Before(trip.Start) {
+					sendTripReminder(s, guildID, trip, log)
+				}
+				err := store.Update(guildID, func(g *GuildSettings) error {
+					for n := range g.Trips {
+						if g.Trips[n].ID == trip.ID {
+							g.Trips[n].Reminded = true
+						}
+					}
+					return nil
+				})
+				// Error handling
+				if err != nil {
+					log.Error("saving trip reminder", slog.Any("err", err))
+				}
+			case !t.Before(trip.end().Add(tripRecapDelay)):
+				sendTripRecap(s, guildID, trip, log)
+			}
+		}
+	}
+}
+
+// sendTripReminder posts a reminder of the trip, tagging everyone going.
+func sendTripReminder(s Sender, guildID string, trip Trip, log *slog.Logger) {
+	locale := localeFor(guildID, "")
+	msg := tr(locale, "Reminder: **%s** %s starts %s.", trip.Title, tripPlace(guildID, trip, locale), discordTime(trip.Start, "R"))
+	if len(trip.Going) > 0 {
+		msg += " " + mentions(trip.Going)
+	}
+	_, err := s.ChannelMessageSend(trip.ChannelID, msg)
+	// Error handling
+	if err != nil {
+		log.Warn("sending trip reminder failed", slog.Any("err", err))
+		return
+	}
+	log.Info("sent trip reminder")
+}
+
+// sendTripRecap posts the recap of the trip and forgets it. If eBird cannot be asked, the recap is tried again on the
+// next check, for up to maxTripRecapRetry.
+func sendTripRecap(s Sender, guildID string, trip Trip, log *slog.Logger) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(Conf().CommandTimeout)*time.Second)
+	defer cancel()
+
+	msg, err := tripRecap(ctx, guildID, trip)
+	// Error handling
+	if err != nil {
+		if now().Before(trip.end().Add(tripRecapDelay + maxTripRecapRetry)) {
+			log.Warn("getting trip recap, trying again later", slog.Any("err", err))
+			return
+		}
+		log.Error("getting trip recap, giving up", slog.Any("err", err))
+		removeTrip(guildID, trip.ID)
+		return
+	}
+
+	_, err = s.ChannelMessageSend(trip.ChannelID, msg)
+	// Error handling
+	if err != nil {
+		log.Warn("sending trip recap failed", slog.Any("err", err))
+	} else {
+		log.Info("sent trip recap")
+	}
+	removeTrip(guildID, trip.ID)
+}
+
+// tripRecap returns the species on the checklists submitted at the trip's location while the trip was on, thanking
+// everyone who went.
+func tripRecap(ctx context.Context, guildID string, trip Trip) (string, error) {
+	locale := localeFor(guildID, "")
+	loc, ok := namedLocation(guildID, trip.Location)
+	if !ok || loc.code == "" {
+		return tr(locale, "**%s** is over, but its location was removed, so there is no recap.", trip.Title), nil
+	}
+
+	// The recent sightings endpoints only keep each species' latest report, which is often from after the trip, so
+	// the trip's checklists are looked up instead. The feed is newest first, so they are among the most recent.
+	feed, err := checklistFeed(ctx, loc.code, tripChecklists)
+	// Error handling
+	if err != nil {
+		return "", err
+	}
+	tz := guildTimeZone(guildID)
+	var subIDs []string
+	for _, c := range feed {
+		if duringTrip(trip, c.IsoObsDate, tz) {
+			subIDs = append(subIDs, c.SubID)
+		}
+	}
+	checklists := make([]Checklist, len(subIDs))
+	err = runLimited(len(subIDs), tripConcurrency, func(i int) error {
+		var err error
+		checklists[i], err = GetChecklist(ctx, subIDs[i])
+		return err
+	})
+	// Error handling
+	if err != nil {
+		return "", err
+	}
+
+	// Attendees often report the same birds, so each species gets its highest count rather than the sum
+	counts := make(map[string]int)
+	var codes []string
+	for _, c := range checklists {
+		for _, o := range c.Obs {
+			if _, ok := counts[o.SpeciesCode]; !ok {
+				codes = append(codes, o.SpeciesCode)
+				counts[o.SpeciesCode] = 0
+			}
+			// "X" means present without a count
+			if n, err := strconv.Atoi(o.HowManyStr); err == nil && n > counts[o.SpeciesCode] {
+				counts[o.SpeciesCode] = n
+			}
+		}
+	}
+	tax, err := lookupTaxa(ctx, codes, locale)
+	// Error handling
+	if err != nil {
+		return "", err
+	}
+	names := make(map[string]string, len(codes))
+	for _, code := range codes {
+		names[code] = code
+		if t, ok := tax[code]; ok {
+			names[code] = t.ComName
+		}
+	}
+	sort.Slice(codes, func(i, j int) bool { return names[codes[i]] < names[codes[j]] })
+
+	rString := tr(locale, "**Recap of %s**: species on eBird checklists %s during the trip\n", trip.Title, tripPlace(guildID, trip, locale))
+	if len(trip.Going) > 0 {
+		rString += tr(locale, "Thanks for coming, %s!\n", mentions(trip.Going))
+	}
+	if len(codes) == 0 {
+		return rString + tr(locale, "No checklists from the trip have been submitted to eBird yet."), nil
+	}
+	for _, code := range codes {
+		count := tr(locale, "present")
+		if counts[code] > 0 {
+			count = strconv.Itoa(counts[code])
+		}
+		rString += fmt.Sprintf("%s: %s\n", names[code], count)
+	}
+
+	//Trimming return string to Discord max message length, minus 5 characters to show max length has been reached
+	return truncateText(rString, 1995), nil
+}
+
+// duringTrip reports whether an eBird observation date, e.g. "2022-10-15 08:30", falls within the trip.
+// eBird gives the location's local time without a time zone, so it is read in tz, the guild's time zone.
+// Observations without a time count if they are on the day the trip started.
+func duringTrip(trip Trip, obsDt string, tz *time.Location) bool {
+	t, err := time.ParseInLocation("2006-01-02 15:04", obsDt, tz)
+	if err == nil {
+		return !t.Before(trip.Start) && !t.After(trip.end())
+	}
+	return obsDt == trip.Start.In(tz).Format("2006-01-02")
+}
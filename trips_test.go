@@ -0,0 +1,308 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// click runs a button click from userID through handleInteraction, and returns the response.
+func click(t *testing.T, userID, customID string) *discordgo.InteractionResponse {
+	t.Helper()
+	s := &fakeSession{}
+	handleInteraction(s, &discordgo.InteractionCreate{Interaction: &discordgo.Interaction{
+		Type:      discordgo.InteractionMessageComponent,
+		GuildID:   "g1",
+		ChannelID: "c1",
+		Member:    &discordgo.Member{User: &discordgo.User{ID: userID}},
+		Data:      discordgo.MessageComponentInteractionData{CustomID: customID, ComponentType: discordgo.ButtonComponent},
+	}})
+	if len(s.responses) != 1 {
+		t.Fatalf("got %d responses, want 1", len(s.responses))
+	}
+	return s.responses[0]
+}
+
+// planTrip creates a trip with !trip create and returns it as saved.
+func planTrip(t *testing.T, content string) Trip {
+	t.Helper()
+	sent := send(t, content)
+	if len(sent) != 1 || sent[0].Embed == nil {
+		t.Fatalf("unexpected reply %+v", sent)
+	}
+	trips := store.Guild("g1").Trips
+	if len(trips) == 0 {
+		t.Fatal("trip not saved")
+	}
+	return trips[len(trips)-1]
+}
+
+func TestTripCreate(t *testing.T) {
+	newFakeEBird(t)
+	useTempStore(t)
+	stepNow(t)
+
+	sent := send(t, "!trip create mendon 2022-10-15 08:00 Saturday Sparrow Walk hours:2")
+	if len(sent) != 1 || sent[0].Embed == nil {
+		t.Fatalf("unexpected reply %+v", sent)
+	}
+	trips := store.Guild("g1").Trips
+	if len(trips) != 1 {
+		t.Fatalf("saved %d trips, want 1", len(trips))
+	}
+	trip := trips[0]
+	start := time.Date(2022, time.October, 15, 8, 0, 0, 0, time.Local)
+	unix := func(t time.Time) string { return strconv.FormatInt(t.Unix(), 10) }
+	if trip.Title != "Saturday Sparrow Walk" || !trip.Start.Equal(start) || trip.Hours != 2 || trip.Location != "mendon" ||
+		trip.CreatedBy != "u1" || trip.ChannelID != "c1" || trip.Reminded {
+		t.Errorf("unexpected trip %+v", trip)
+	}
+
+	embed := sent[0].Embed
+	if embed.Title != "Saturday Sparrow Walk" || embed.Fields[0].Value != "at Mendon Ponds Park" ||
+		!strings.Contains(embed.Fields[1].Value, "<t:"+unix(start)+":F>") || embed.Fields[2].Value != "Nobody yet" {
+		t.Errorf("unexpected embed %+v", embed)
+	}
+	row, ok := sent[0].Components[0].(discordgo.ActionsRow)
+	if !ok || len(row.Components) != 2 || row.Components[0].(discordgo.Button).CustomID != "trip:going:"+trip.ID {
+		t.Errorf("unexpected buttons %+v", sent[0].Components)
+	}
+
+	// A quoted date and time, and a title from the location
+	trip = planTrip(t, `!trip create braddock "2022-10-12T15:30"`)
+	if trip.Title != "Bird walk at Braddock Bay Park" || trip.Hours != defaultTripHours || !trip.Reminded {
+		t.Errorf("unexpected trip %+v", trip)
+	}
+}
+
+func TestTripTimeZone(t *testing.T) {
+	newFakeEBird(t)
+	useTempStore(t)
+	stepNow(t)
+
+	tests := map[string]string{
+		"!config set timezone america/new_york": "Updated: `timezone`: America/New_York",
+		"!config set timezone mars/olympus":     "Error: 'mars/olympus' is not a time zone, use a name such as America/New_York",
+	}
+	for content, want := range tests {
+		if sent := sendAsAdmin(t, content); len(sent) != 1 || sent[0].Content != want {
+			t.Errorf("%s: unexpected reply %+v", content, sent)
+		}
+	}
+
+	// Trip times and eBird's times are both in the guild's time zone
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	trip := planTrip(t, "!trip create mendon 2022-10-15 08:00")
+	if !trip.Start.Equal(time.Date(2022, time.October, 15, 8, 0, 0, 0, ny)) {
+		t.Errorf("trip starts at %s", trip.Start)
+	}
+	for obsDt, want := range map[string]bool{"2022-10-15 08:30": true, "2022-10-15 12:30": false, "2022-10-15": true, "2022-10-16": false} {
+		if got := duringTrip(trip, obsDt, guildTimeZone("g1")); got != want {
+			t.Errorf("duringTrip(%s) = %t", obsDt, got)
+		}
+	}
+}
+
+func TestTripCreateErrors(t *testing.T) {
+	newFakeEBird(t)
+	useTempStore(t)
+	stepNow(t)
+
+	tests := map[string]string{
+		"!trip create mendon 2022-10-11 08:00":           "Error: the trip must start in the future",
+		"!trip create mendon saturday":                   "Error: 'saturday' is not a date and time, use the format YYYY-MM-DD HH:MM",
+		"!trip create nowhere 2022-10-15 08:00":          "Error: 'nowhere' is not a valid option for !trip",
+		"!trip create mendon 2022-10-15 08:00 hours:20":  "Error: 'hours' must be a whole number from 1 to 12",
+		"!trip create mendon 2022-10-15 08:00 color:red": "Error: 'color' is not a valid option for !trip",
+		"!trip create mendon":                            "Error: use `!trip create",
+		"!trip":                                          "Error: use `!trip create",
+	}
+	for content, want := range tests {
+		sent := send(t, content)
+		if len(sent) != 1 || !strings.HasPrefix(sent[0].Content, want) {
+			t.Errorf("%s: unexpected reply %+v", content, sent)
+		}
+	}
+	if trips := store.Guild("g1").Trips; len(trips) != 0 {
+		t.Errorf("saved %d trips", len(trips))
+	}
+}
+
+func TestTripRSVP(t *testing.T) {
+	newFakeEBird(t)
+	useTempStore(t)
+	stepNow(t)
+	trip := planTrip(t, "!trip create mendon 2022-10-15 08:00")
+
+	for i := 0; i < 2; i++ {
+		resp := click(t, "u2", "trip:going:"+trip.ID)
+		if resp.Type != discordgo.InteractionResponseUpdateMessage || resp.Data.Embeds[0].Fields[2].Value != "<@u2>" {
+			t.Errorf("click %d: unexpected response %+v", i+1, resp.Data)
+		}
+	}
+	click(t, "u3", "trip:going:"+trip.ID)
+	if going := store.Guild("g1").Trips[0].Going; strings.Join(going, " ") != "u2 u3" {
+		t.Errorf("going %v, want u2 and u3 once each", going)
+	}
+
+	resp := click(t, "u2", "trip:notgoing:"+trip.ID)
+	if field := resp.Data.Embeds[0].Fields[2]; field.Name != "Going (1)" || field.Value != "<@u3>" {
+		t.Errorf("unexpected attendees %+v", field)
+	}
+
+	// A click without a user changes nothing
+	s := &fakeSession{}
+	handleInteraction(s, &discordgo.InteractionCreate{Interaction: &discordgo.Interaction{
+		Type:      discordgo.InteractionMessageComponent,
+		GuildID:   "g1",
+		ChannelID: "c1",
+		Data:      discordgo.MessageComponentInteractionData{CustomID: "trip:going:" + trip.ID, ComponentType: discordgo.ButtonComponent},
+	}})
+	if going := store.Guild("g1").Trips[0].Going; len(s.responses) != 0 || strings.Join(going, " ") != "u3" {
+		t.Errorf("click without a user answered %+v, going %v", s.responses, going)
+	}
+
+	resp = click(t, "u2", "trip:going:nosuchtrip")
+	if resp.Type != discordgo.InteractionResponseChannelMessageWithSource || resp.Data.Flags&uint64(discordgo.MessageFlagsEphemeral) == 0 ||
+		resp.Data.Content != "This trip is over or was cancelled." {
+		t.Errorf("unexpected response %+v", resp.Data)
+	}
+}
+
+func TestTripListAndCancel(t *testing.T) {
+	newFakeEBird(t)
+	useTempStore(t)
+	stepNow(t)
+	later := planTrip(t, "!trip create mendon 2022-10-20 08:00 Later walk")
+	sooner := planTrip(t, "!trip create braddock 2022-10-15 07:00 Hawk watch")
+	click(t, "u2", "trip:going:"+sooner.ID)
+
+	sent := send(t, "!trip list")
+	want := "**Planned trips:**\n`" + sooner.ID + "` **Hawk watch** at Braddock Bay Park, " + discordTime(sooner.Start, "F") + ", 1 going\n`" +
+		later.ID + "` **Later walk** at Mendon Ponds Park, " + discordTime(later.Start, "F") + ", 0 going\n"
+	if len(sent) != 1 || sent[0].Content != want {
+		t.Errorf("unexpected list %+v", sent)
+	}
+
+	// Only the organizer and server managers can cancel
+	if sent := sendFrom(t, "u2", "c1", "!trip cancel "+sooner.ID); len(sent) != 1 || !strings.Contains(sent[0].Content, "organizer") {
+		t.Errorf("unexpected reply %+v", sent)
+	}
+	if sent := send(t, "!trip cancel "+sooner.ID); len(sent) != 1 || sent[0].Content != "Cancelled **Hawk watch**. <@u2>" {
+		t.Errorf("unexpected reply %+v", sent)
+	}
+	if sent := sendAsAdmin(t, "!trip cancel "+later.ID); len(sent) != 1 || sent[0].Content != "Cancelled **Later walk**." {
+		t.Errorf("unexpected reply %+v", sent)
+	}
+	if sent := send(t, "!trip list"); len(sent) != 1 || sent[0].Content != "No trips are planned. Plan one with `!trip create`." {
+		t.Errorf("unexpected reply %+v", sent)
+	}
+}
+
+// serveTripChecklists makes the fake eBird serve a checklist feed for Mendon Ponds Park, with each checklist's
+// start time and the observations on it.
+func serveTripChecklists(f *fakeEBird, checklists map[string]string) {
+	f.route("/ref/taxonomy/ebird", "taxonomy.json")
+	var feed []string
+	for subID, started := range map[string]string{"S4": "2022-10-12 19:45", "S3": "2022-10-12 18:05", "S2": "2022-10-12 17:10",
+		"S1": "2022-10-12 12:00"} {
+		feed = append(feed, fmt.Sprintf(`{"locId": "L139800", "subId": %q, "isoObsDate": %q}`, subID, started))
+	}
+	f.handle("/product/lists/L139800", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("[" + strings.Join(feed, ",") + "]"))
+	})
+	for subID, obs := range checklists {
+		body := fmt.Sprintf(`{"subId": %q, "obs": [%s]}`, subID, obs)
+		f.handle("/product/checklist/view/"+subID, func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(body))
+		})
+	}
+}
+
+func TestTripReminderAndRecap(t *testing.T) {
+	f := newFakeEBird(t)
+	useTempStore(t)
+	advance := stepNow(t)
+	// American Robins were reported again after the trip, and S1 and S4 are outside it
+	serveTripChecklists(f, map[string]string{
+		"S1": `{"speciesCode": "dowwoo", "howManyStr": "1"}`,
+		"S2": `{"speciesCode": "amerob", "howManyStr": "3"}, {"speciesCode": "blujay", "howManyStr": "X"}`,
+		"S3": `{"speciesCode": "amerob", "howManyStr": "5"}, {"speciesCode": "amecro", "howManyStr": "2"}`,
+		"S4": `{"speciesCode": "amerob", "howManyStr": "9"}, {"speciesCode": "snoowl1", "howManyStr": "1"}`,
+	})
+	trip := planTrip(t, "!trip create mendon 2022-10-12 17:00 Evening walk hours:2")
+	click(t, "u2", "trip:going:"+trip.ID)
+	s := &fakeSession{}
+
+	// Nothing is due yet
+	advance(30 * time.Minute)
+	checkTrips(s)
+	if sent := s.messages(); len(sent) != 0 {
+		t.Fatalf("sent %+v before the reminder was due", sent)
+	}
+
+	advance(30 * time.Minute)
+	checkTrips(s)
+	checkTrips(s)
+	sent := s.messages()
+	if len(sent) != 1 || sent[0].ChannelID != "c1" || sent[0].Content != "Reminder: **Evening walk** at Mendon Ponds Park starts "+discordTime(trip.Start, "R")+". <@u2>" {
+		t.Fatalf("unexpected reminder %+v", sent)
+	}
+
+	// The recap waits for checklists to come in after the trip ends at 19:00
+	advance(5*time.Hour - time.Minute)
+	checkTrips(s)
+	if sent := s.messages(); len(sent) != 1 {
+		t.Fatalf("sent the recap early: %+v", sent)
+	}
+	advance(time.Minute)
+	checkTrips(s)
+	sent = s.messages()
+	want := "**Recap of Evening walk**: species on eBird checklists at Mendon Ponds Park during the trip\n" +
+		"Thanks for coming, <@u2>!\nAmerican Crow: 2\nAmerican Robin: 5\nBlue Jay: present\n"
+	if len(sent) != 2 || sent[1].Content != want {
+		t.Fatalf("unexpected recap %+v", sent)
+	}
+	if req := f.lastRequestTo("/product/lists/L139800"); req == nil || req.URL.Query().Get("maxResults") != "200" {
+		t.Errorf("unexpected request %+v", req)
+	}
+	for _, subID := range []string{"S1", "S4"} {
+		if f.lastRequestTo("/product/checklist/view/"+subID) != nil {
+			t.Errorf("fetched %s from outside the trip", subID)
+		}
+	}
+	if trips := store.Guild("g1").Trips; len(trips) != 0 {
+		t.Errorf("trip kept after its recap: %+v", trips)
+	}
+}
+
+func TestTripRecapRetries(t *testing.T) {
+	f := newFakeEBird(t)
+	useTempStore(t)
+	advance := stepNow(t)
+	f.handle("/product/lists/L139800", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "down", http.StatusInternalServerError)
+	})
+	planTrip(t, "!trip create mendon 2022-10-12 15:30")
+	s := &fakeSession{}
+
+	advance(6 * time.Hour)
+	checkTrips(s)
+	if trips := store.Guild("g1").Trips; len(trips) != 1 || len(s.messages()) != 0 {
+		t.Fatalf("trip dropped after one failed recap: %+v", trips)
+	}
+
+	advance(maxTripRecapRetry)
+	checkTrips(s)
+	if trips := store.Guild("g1").Trips; len(trips) != 0 {
+		t.Errorf("kept retrying the recap: %+v", trips)
+	}
+}